@@ -0,0 +1,187 @@
+// Package bolt stores the time sets of CRDTs in bbolt databases, for sets that do not fit in memory
+//
+// It lives apart from the crdt package so only the programs using it depend on bbolt
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	codec "github.com/bjornaer/crdt/internal/codec"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// batchSize is the number of entries Each reads per transaction
+const batchSize = 1024
+
+// countsBucket holds the number of entries of every time set of the database, keyed by the name of its bucket
+var countsBucket = []byte("crdt.counts")
+
+// TimeSet is a time set stored in a bucket of a bbolt database, it implements crdt.TimeSet
+//
+// Keys are the codec encodings of the elements and values their timestamps, in seconds and nanoseconds since the
+// Unix epoch. The number of entries is kept in a bucket of its own by the transactions writing them, so the sequence
+// of the bucket holding the entries stays free for callers.
+// Every Add is a transaction of its own, so the set is as crash-consistent as the database
+type TimeSet[T comparable] struct {
+	db     *bbolt.DB
+	bucket []byte
+	codec  codec.ElementCodec[T]
+}
+
+// NewTimeSet returns the time set stored in the given bucket of the database, creating the bucket if needed,
+// elements are stored in their codec encoding
+//
+// Several time sets can share a database as long as they use different buckets, other than crdt.counts
+func NewTimeSet[T comparable](db *bbolt.DB, bucket string, c codec.ElementCodec[T]) (*TimeSet[T], error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(countsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TimeSet[T]{db: db, bucket: []byte(bucket), codec: c}, nil
+}
+
+// Add an element in the set if it does not exist yet, or exists with a lesser timestamp than the given one
+func (s *TimeSet[T]) Add(value T, t time.Time) error {
+	key, err := s.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		current := b.Get(key)
+		if current != nil {
+			addedAt, err := codec.DecodeTime(current)
+			if err != nil {
+				return err
+			}
+			if !t.After(addedAt) {
+				return nil
+			}
+		}
+		if current == nil {
+			if err := s.count(tx, 1); err != nil {
+				return err
+			}
+		}
+		return b.Put(key, codec.EncodeTime(t))
+	})
+}
+
+// Delete drops the entry of an element, if any
+func (s *TimeSet[T]) Delete(value T) error {
+	key, err := s.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b.Get(key) == nil {
+			return nil
+		}
+		if err := s.count(tx, -1); err != nil {
+			return err
+		}
+		return b.Delete(key)
+	})
+}
+
+// AddedAt returns the timestamp of a given element if it exists
+func (s *TimeSet[T]) AddedAt(value T) (time.Time, bool) {
+	key, err := s.codec.Encode(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var addedAt time.Time
+	var ok bool
+	_ = s.db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(s.bucket).Get(key); v != nil {
+			t, err := codec.DecodeTime(v)
+			addedAt, ok = t, err == nil
+		}
+		return nil
+	})
+	return addedAt, ok
+}
+
+// Each traverses the items in the set in the order of their encoding, calling the provided function
+// for each element/timestamp association
+//
+// Entries are read in batches, each in a transaction of its own that is closed before the function is called,
+// so the function may write to the database. Entries added meanwhile may or may not be traversed
+func (s *TimeSet[T]) Each(f func(element T, addedAt time.Time) error) error {
+	var after []byte // last key of the previous batch
+	for {
+		var keys [][]byte
+		var timestamps []time.Time
+		err := s.db.View(func(tx *bbolt.Tx) error {
+			c := tx.Bucket(s.bucket).Cursor()
+			k, v := c.First()
+			if after != nil {
+				k, v = c.Seek(after)
+				if k != nil && bytes.Equal(k, after) {
+					k, v = c.Next()
+				}
+			}
+			for ; k != nil && len(keys) < batchSize; k, v = c.Next() {
+				// keys and values are only valid within the transaction
+				t, err := codec.DecodeTime(v)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, append([]byte{}, k...))
+				timestamps = append(timestamps, t)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for i, key := range keys {
+			element, err := s.codec.Decode(key)
+			if err != nil {
+				return err
+			}
+			if err := f(element, timestamps[i]); err != nil {
+				return err
+			}
+		}
+		if len(keys) < batchSize {
+			return nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// Size returns the number of entries, read from the count kept along with them
+func (s *TimeSet[T]) Size() int {
+	size := 0
+	_ = s.db.View(func(tx *bbolt.Tx) error {
+		size = int(s.size(tx))
+		return nil
+	})
+	return size
+}
+
+// size reads the number of entries within a transaction
+func (s *TimeSet[T]) size(tx *bbolt.Tx) uint64 {
+	v := tx.Bucket(countsBucket).Get(s.bucket)
+	if len(v) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+// count changes the number of entries within the transaction writing them
+func (s *TimeSet[T]) count(tx *bbolt.Tx, delta int64) error {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, uint64(int64(s.size(tx))+delta))
+	return tx.Bucket(countsBucket).Put(s.bucket, v)
+}
@@ -0,0 +1,219 @@
+package bolt_test
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt/bolt"
+	codec "github.com/bjornaer/crdt/internal/codec"
+	bbolt "go.etcd.io/bbolt"
+)
+
+func openTestDB(t *testing.T, path string) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func boltTestSet(t *testing.T, db *bbolt.DB, bucket string) *bolt.TimeSet[int] {
+	t.Helper()
+	s, err := bolt.NewTimeSet[int](db, bucket, codec.Int[int]())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return s
+}
+
+func TestTimeSet_Add(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.db")
+	db := openTestDB(t, path)
+	s := boltTestSet(t, db, "additions")
+	now := time.Now()
+	s.Add(1, now)
+	s.Add(1, now.Add(-time.Second)) // older, not kept
+	s.Add(2, now)
+	s.Add(2, now.Add(time.Second))
+	db.Close()
+
+	s = boltTestSet(t, openTestDB(t, path), "additions")
+	if size := s.Size(); size != 2 {
+		t.Errorf("Unexpected size, got: %d, expected: %d.", size, 2)
+	}
+	for element, expected := range map[int]time.Time{1: now, 2: now.Add(time.Second)} {
+		addedAt, ok := s.AddedAt(element)
+		if !ok || !addedAt.Equal(expected) {
+			t.Errorf("Unexpected timestamp for %d, got: %v, expected: %v.", element, addedAt, expected)
+		}
+	}
+	if _, ok := s.AddedAt(3); ok {
+		t.Errorf("Unexpected timestamp for an element never added")
+	}
+}
+
+// checks timestamps nanoseconds since the epoch cannot represent are stored as they are
+func TestTimeSet_Timestamps(t *testing.T) {
+	s := boltTestSet(t, openTestDB(t, filepath.Join(t.TempDir(), "set.db")), "additions")
+	for element, expected := range map[int]time.Time{
+		1: {},
+		2: time.Date(1500, 3, 1, 12, 0, 0, 7, time.UTC),
+		3: time.Date(3000, 1, 1, 0, 0, 0, 999999999, time.UTC),
+	} {
+		if err := s.Add(element, expected); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		addedAt, ok := s.AddedAt(element)
+		if !ok || !addedAt.Equal(expected) {
+			t.Errorf("Unexpected timestamp for %d, got: %v, expected: %v.", element, addedAt, expected)
+		}
+	}
+}
+
+// checks the count of entries follows additions and deletions, and leaves the sequence of the bucket to its callers
+func TestTimeSet_Size(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.db")
+	db := openTestDB(t, path)
+	s := boltTestSet(t, db, "additions")
+	now := time.Now()
+	s.Add(1, now)
+	s.Add(1, now.Add(time.Second))
+	s.Add(2, now)
+	s.Delete(2)
+	s.Delete(3)
+	if size := s.Size(); size != 1 {
+		t.Errorf("Unexpected size, got: %d, expected: %d.", size, 1)
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("additions"))
+		if _, err := b.NextSequence(); err != nil {
+			return err
+		}
+		return b.SetSequence(42)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Add(3, now)
+	if size := s.Size(); size != 2 {
+		t.Errorf("Unexpected size once the bucket sequence is used, got: %d, expected: %d.", size, 2)
+	}
+	if size := boltTestSet(t, db, "other").Size(); size != 0 {
+		t.Errorf("Unexpected size of a new set, got: %d, expected: %d.", size, 0)
+	}
+}
+
+// checks Each walks every entry in order across batches, and lets the callback write to the same database
+func TestTimeSet_Each(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "set.db"))
+	s := boltTestSet(t, db, "additions")
+	copied := boltTestSet(t, db, "copy")
+	size := 3000
+	now := time.Now()
+	// the test does not need durable commits, syncing each of them would only slow it down
+	db.NoSync = true
+	for i := 0; i < size; i++ {
+		if err := s.Add(i, now); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	next := 0
+	err := s.Each(func(element int, addedAt time.Time) error {
+		if element != next {
+			return fmt.Errorf("got element %d, expected %d", element, next)
+		}
+		next++
+		return copied.Add(element, addedAt)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if next != size {
+		t.Errorf("Unexpected number of entries traversed, got: %d, expected: %d.", next, size)
+	}
+	if copied.Size() != size {
+		t.Errorf("Unexpected number of entries copied, got: %d, expected: %d.", copied.Size(), size)
+	}
+}
+
+// crashEnv makes the test binary run as the writer killed by TestTimeSet_Crash
+const crashEnv = "CRDT_BOLT_CRASH_DB"
+
+// checks a process killed in the middle of writes leaves a consistent database holding every acknowledged entry
+func TestTimeSet_Crash(t *testing.T) {
+	if path := os.Getenv(crashEnv); path != "" {
+		crashWriter(path)
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "set.db")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestTimeSet_Crash$")
+	cmd.Env = append(os.Environ(), crashEnv+"="+path)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	acked := -1
+	scanner := bufio.NewScanner(stdout)
+	for acked < 200 && scanner.Scan() {
+		if n, err := strconv.Atoi(scanner.Text()); err == nil {
+			acked = n
+		}
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+	if acked < 200 {
+		t.Fatalf("Writer stopped after %d entries", acked)
+	}
+
+	db := openTestDB(t, path)
+	err = db.View(func(tx *bbolt.Tx) error {
+		var first error
+		for err := range tx.Check() {
+			if first == nil {
+				first = err
+			}
+		}
+		return first
+	})
+	if err != nil {
+		t.Fatalf("Inconsistent database after the crash: %v", err)
+	}
+	s := boltTestSet(t, db, "additions")
+	for i := 0; i <= acked; i++ {
+		if _, ok := s.AddedAt(i); !ok {
+			t.Errorf("Acknowledged entry %d lost in the crash", i)
+		}
+	}
+}
+
+// crashWriter adds entries until it is killed, printing each one once Add returned
+func crashWriter(path string) {
+	db, err := bbolt.Open(path, 0o600, nil)
+	if err != nil {
+		os.Exit(1)
+	}
+	s, err := bolt.NewTimeSet[int](db, "additions", codec.Int[int]())
+	if err != nil {
+		os.Exit(1)
+	}
+	for i := 0; ; i++ {
+		if err := s.Add(i, time.Now()); err != nil {
+			os.Exit(1)
+		}
+		fmt.Println(i)
+	}
+}
@@ -0,0 +1,126 @@
+// Package broadcast delivers operations to CRDT replicas in causal order
+package broadcast
+
+import (
+	"sync"
+
+	"github.com/bjornaer/crdt/causal"
+	"github.com/bjornaer/crdt/oplog"
+)
+
+// Applier receives the operations delivered by a Broadcast, sets and graphs implement it
+type Applier[T any] interface {
+	Apply(oplog.Op[T]) error
+}
+
+// Message is an operation stamped with the vector clock of its sender at the time it was sent
+type Message[T any] struct {
+	Sender string               `json:"sender"`
+	Clock  causal.VersionVector `json:"clock"`
+	Op     oplog.Op[T]          `json:"op"`
+}
+
+// Broadcast stamps local operations with vector clocks, and delivers remote operations to an Applier
+// only once every operation they causally depend on was delivered
+//
+// Messages can be received in any order and any number of times: early ones are buffered until their
+// dependencies arrive, and duplicates are dropped
+type Broadcast[T any] struct {
+	self      string
+	target    Applier[T]
+	delivered causal.VersionVector // operations delivered so far, local ones included
+	pending   []Message[T]         // received messages waiting for their dependencies
+	mutex     sync.Mutex
+}
+
+// New returns a Broadcast for the replica identified by self, delivering remote operations to target
+func New[T any](self string, target Applier[T]) *Broadcast[T] {
+	return &Broadcast[T]{
+		self:      self,
+		target:    target,
+		delivered: make(causal.VersionVector),
+	}
+}
+
+// Send stamps an operation the local replica already applied, the returned message is to be sent to every other replica
+func (b *Broadcast[T]) Send(op oplog.Op[T]) Message[T] {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.delivered.Increment(b.self)
+	return Message[T]{Sender: b.self, Clock: b.delivered.Copy(), Op: op}
+}
+
+// Receive delivers a message, along with every buffered message it unblocks, or buffers it if some of
+// its dependencies are missing
+func (b *Broadcast[T]) Receive(m Message[T]) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.isDuplicate(m) {
+		return nil
+	}
+	for _, p := range b.pending {
+		if p.Sender == m.Sender && p.Clock[p.Sender] == m.Clock[m.Sender] {
+			return nil
+		}
+	}
+	b.pending = append(b.pending, m)
+
+	// every delivery may unblock other buffered messages, so we keep going until nothing moves
+	for delivered := true; delivered; {
+		delivered = false
+		for i := 0; i < len(b.pending); i++ {
+			p := b.pending[i]
+			if b.isDuplicate(p) {
+				b.pending = append(b.pending[:i], b.pending[i+1:]...)
+				i--
+				continue
+			}
+			if !b.isDeliverable(p) {
+				continue
+			}
+			err := b.target.Apply(p.Op)
+			if err != nil {
+				return err
+			}
+			b.delivered.Increment(p.Sender)
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			i--
+			delivered = true
+		}
+	}
+	return nil
+}
+
+// isDuplicate checks if a message was already delivered
+func (b *Broadcast[T]) isDuplicate(m Message[T]) bool {
+	return b.delivered.Contains(causal.Dot{Replica: m.Sender, Counter: m.Clock.Get(m.Sender)})
+}
+
+// isDeliverable checks if a message is the next one from its sender, and if everything its sender knew about
+// when sending it was delivered
+func (b *Broadcast[T]) isDeliverable(m Message[T]) bool {
+	if m.Clock[m.Sender] != b.delivered[m.Sender]+1 {
+		return false
+	}
+	for replica, counter := range m.Clock {
+		if replica != m.Sender && counter > b.delivered[replica] {
+			return false
+		}
+	}
+	return true
+}
+
+// Clock returns the vector clock of the operations delivered so far
+func (b *Broadcast[T]) Clock() causal.VersionVector {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.delivered.Copy()
+}
+
+// Pending returns the number of received messages waiting for their dependencies
+func (b *Broadcast[T]) Pending() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.pending)
+}
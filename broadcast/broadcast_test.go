@@ -0,0 +1,190 @@
+package broadcast_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/broadcast"
+	"github.com/bjornaer/crdt/oplog"
+)
+
+// recorder is an Applier keeping track of the order operations were delivered in
+type recorder struct {
+	delivered []string
+}
+
+func (r *recorder) Apply(op oplog.Op[string]) error {
+	r.delivered = append(r.delivered, op.Element)
+	return nil
+}
+
+func op(element string) oplog.Op[string] {
+	return oplog.Op[string]{Type: oplog.AddOp, Element: element, Timestamp: time.Now()}
+}
+
+func TestBroadcast_CausalOrder(t *testing.T) {
+	r3 := &recorder{}
+	b1 := broadcast.New[string]("r1", &recorder{})
+	b2 := broadcast.New[string]("r2", &recorder{})
+	b3 := broadcast.New[string]("r3", r3)
+
+	// r2 sends m2 after delivering m1, so m2 causally depends on m1
+	m1 := b1.Send(op("m1"))
+	b2.Receive(m1)
+	m2 := b2.Send(op("m2"))
+
+	// r3 receives m2 first, it must wait for m1
+	err := b3.Receive(m2)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(r3.delivered) != 0 || b3.Pending() != 1 {
+		t.Errorf("Message delivered before its dependency, delivered: %v", r3.delivered)
+	}
+	err = b3.Receive(m1)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !pathsAreEqual(r3.delivered, []string{"m1", "m2"}) || b3.Pending() != 0 {
+		t.Errorf("Causal order violated, delivered: %v, expected: %v.", r3.delivered, []string{"m1", "m2"})
+	}
+}
+
+func TestBroadcast_FIFOOrder(t *testing.T) {
+	r2 := &recorder{}
+	b1 := broadcast.New[string]("r1", &recorder{})
+	b2 := broadcast.New[string]("r2", r2)
+	m1 := b1.Send(op("m1"))
+	m2 := b1.Send(op("m2"))
+	m3 := b1.Send(op("m3"))
+	b2.Receive(m3)
+	b2.Receive(m2)
+	b2.Receive(m1)
+	if !pathsAreEqual(r2.delivered, []string{"m1", "m2", "m3"}) {
+		t.Errorf("FIFO order violated, delivered: %v", r2.delivered)
+	}
+}
+
+func TestBroadcast_Duplicates(t *testing.T) {
+	r2 := &recorder{}
+	b1 := broadcast.New[string]("r1", &recorder{})
+	b2 := broadcast.New[string]("r2", r2)
+	m1 := b1.Send(op("m1"))
+	m2 := b1.Send(op("m2"))
+	b2.Receive(m2)
+	b2.Receive(m2)
+	b2.Receive(m1)
+	b2.Receive(m1)
+	b2.Receive(m2)
+	if !pathsAreEqual(r2.delivered, []string{"m1", "m2"}) || b2.Pending() != 0 {
+		t.Errorf("Duplicates delivered, delivered: %v", r2.delivered)
+	}
+	clock := b2.Clock()
+	if clock["r1"] != 2 {
+		t.Errorf("Clock mismatch, got: %v", clock)
+	}
+}
+
+// every replica mutates its graph and broadcasts the operations, which reach the other replicas
+// shuffled and duplicated: edges must never be lost because they arrived before their vertices
+func TestBroadcast_ReorderedGraphs(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	names := []string{"r1", "r2", "r3"}
+	logs := make(map[string]*oplog.Log[string])
+	graphs := make(map[string]crdt.LastWriterWinsGraph[string])
+	broadcasts := make(map[string]*broadcast.Broadcast[string])
+	for _, name := range names {
+		logs[name] = oplog.NewLog[string](name)
+		graphs[name] = crdt.NewLWWGraph(crdt.WithLog(logs[name]))
+		broadcasts[name] = broadcast.New[string](name, graphs[name])
+	}
+	var inFlight []broadcast.Message[string]
+	// send stamps every operation recorded by a replica since its last call
+	sent := make(map[string]uint64)
+	send := func(name string) {
+		for _, o := range logs[name].Read(sent[name]) {
+			inFlight = append(inFlight, broadcasts[name].Send(o))
+			sent[name]++
+		}
+	}
+	deliverAll := func(to string) {
+		messages := append([]broadcast.Message[string]{}, inFlight...)
+		messages = append(messages, inFlight[:len(inFlight)/2]...)
+		random.Shuffle(len(messages), func(i, j int) { messages[i], messages[j] = messages[j], messages[i] })
+		for _, m := range messages {
+			if m.Sender == to {
+				continue
+			}
+			err := broadcasts[to].Receive(m)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+
+	graphs["r1"].AddVertex("vertex1")
+	graphs["r1"].AddVertex("vertex2")
+	send("r1")
+	deliverAll("r2")
+	graphs["r2"].AddVertex("vertex3")
+	graphs["r2"].AddEdge("vertex1", "vertex3")
+	graphs["r2"].AddEdge("vertex2", "vertex3")
+	send("r2")
+	deliverAll("r3")
+	graphs["r3"].RemoveEdge("vertex2", "vertex3")
+	send("r3")
+	for _, name := range names {
+		deliverAll(name)
+	}
+
+	for _, name := range names {
+		if broadcasts[name].Pending() != 0 {
+			t.Errorf("Replica %s still waits for %v messages", name, broadcasts[name].Pending())
+		}
+		vertices, _ := graphs[name].GetAllVertices()
+		if !setsAreEqual(vertices, []string{"vertex1", "vertex2", "vertex3"}) {
+			t.Errorf("Replica %s vertices mismatch, got: %v", name, vertices)
+		}
+		if !graphs[name].EdgeExists("vertex1", "vertex3") || graphs[name].EdgeExists("vertex2", "vertex3") {
+			t.Errorf("Replica %s edges mismatch", name)
+		}
+	}
+}
+
+// compares two slices, order included
+func pathsAreEqual[T comparable](s1, s2 []T) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for i, v := range s1 {
+		if v != s2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checks element is contained within set
+func contains[T comparable](s []T, e T) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// checks for set equality -- independent of order
+func setsAreEqual[T comparable](s1, s2 []T) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for _, v := range s1 {
+		if !contains(s2, v) {
+			return false
+		}
+	}
+	return true
+}
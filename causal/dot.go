@@ -0,0 +1,153 @@
+package causal
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Dot identifies a single event: the counter-th event of a replica
+type Dot struct {
+	Replica string `json:"replica"`
+	Counter uint64 `json:"counter"`
+}
+
+func (d Dot) String() string {
+	return fmt.Sprintf("%s:%d", d.Replica, d.Counter)
+}
+
+// DotSet is a set of dots, typically the events that produced the values a dot-based CRDT currently holds
+type DotSet map[Dot]struct{}
+
+// NewDotSet returns a set holding the given dots
+func NewDotSet(dots ...Dot) DotSet {
+	s := make(DotSet, len(dots))
+	for _, d := range dots {
+		s.Add(d)
+	}
+	return s
+}
+
+// Add inserts a dot in the set
+func (s DotSet) Add(d Dot) {
+	s[d] = struct{}{}
+}
+
+// Remove deletes a dot from the set
+func (s DotSet) Remove(d Dot) {
+	delete(s, d)
+}
+
+// Contains checks if a dot is in the set
+func (s DotSet) Contains(d Dot) bool {
+	_, ok := s[d]
+	return ok
+}
+
+// Union inserts every dot of the other set in the set
+func (s DotSet) Union(other DotSet) {
+	for d := range other {
+		s.Add(d)
+	}
+}
+
+// Dots returns the dots of the set, sorted by replica then counter
+func (s DotSet) Dots() []Dot {
+	dots := make([]Dot, 0, len(s))
+	for d := range s {
+		dots = append(dots, d)
+	}
+	sort.Slice(dots, func(i, j int) bool {
+		if dots[i].Replica != dots[j].Replica {
+			return dots[i].Replica < dots[j].Replica
+		}
+		return dots[i].Counter < dots[j].Counter
+	})
+	return dots
+}
+
+// Copy returns an independent copy of the set
+func (s DotSet) Copy() DotSet {
+	copied := make(DotSet, len(s))
+	copied.Union(s)
+	return copied
+}
+
+// DotContext is the causal context of a dot-based CRDT: every event it has seen, whether or not
+// the values it produced are still around
+//
+// Contiguous events are summarized by a version vector, the dot cloud holds the events received out of order
+type DotContext struct {
+	vector VersionVector
+	cloud  DotSet
+}
+
+// NewDotContext returns an empty causal context
+func NewDotContext() *DotContext {
+	return &DotContext{
+		vector: make(VersionVector),
+		cloud:  make(DotSet),
+	}
+}
+
+// Contains checks if the event identified by a dot was seen
+func (c *DotContext) Contains(d Dot) bool {
+	return c.vector.Contains(d) || c.cloud.Contains(d)
+}
+
+// Next records a new event of a replica and returns its dot
+//
+// It is meant for local events, whose replica has seen every one of its previous events
+func (c *DotContext) Next(replica string) Dot {
+	return Dot{Replica: replica, Counter: c.vector.Increment(replica)}
+}
+
+// Insert records an event seen by the context
+func (c *DotContext) Insert(d Dot) {
+	if c.Contains(d) {
+		return
+	}
+	c.cloud.Add(d)
+	c.compact()
+}
+
+// Merge makes the context see every event the other context has seen
+func (c *DotContext) Merge(other *DotContext) {
+	c.vector.Merge(other.vector)
+	c.cloud.Union(other.cloud)
+	c.compact()
+}
+
+// VersionVector returns a copy of the contiguous part of the context
+func (c *DotContext) VersionVector() VersionVector {
+	return c.vector.Copy()
+}
+
+// Cloud returns a copy of the dots seen out of order
+func (c *DotContext) Cloud() DotSet {
+	return c.cloud.Copy()
+}
+
+// Copy returns an independent copy of the context
+func (c *DotContext) Copy() *DotContext {
+	return &DotContext{
+		vector: c.vector.Copy(),
+		cloud:  c.cloud.Copy(),
+	}
+}
+
+// compact moves the dots of the cloud that extend the version vector into it, and drops the ones it already covers
+func (c *DotContext) compact() {
+	for moved := true; moved; {
+		moved = false
+		for d := range c.cloud {
+			switch {
+			case d.Counter == c.vector[d.Replica]+1:
+				c.vector[d.Replica] = d.Counter
+				moved = true
+				fallthrough
+			case d.Counter <= c.vector[d.Replica]:
+				c.cloud.Remove(d)
+			}
+		}
+	}
+}
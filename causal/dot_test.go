@@ -0,0 +1,191 @@
+package causal_test
+
+import (
+	"testing"
+
+	"github.com/bjornaer/crdt/causal"
+)
+
+func dot(replica string, counter uint64) causal.Dot {
+	return causal.Dot{Replica: replica, Counter: counter}
+}
+
+func TestDotSet(t *testing.T) {
+	s := causal.NewDotSet(dot("b", 1), dot("a", 2))
+	s.Add(dot("a", 1))
+	s.Add(dot("a", 1))
+	if len(s) != 3 || !s.Contains(dot("a", 1)) || s.Contains(dot("c", 1)) {
+		t.Errorf("DotSet mismatch, got: %v", s.Dots())
+	}
+	s.Remove(dot("b", 1))
+	if s.Contains(dot("b", 1)) {
+		t.Errorf("Removed dot still in set")
+	}
+
+	other := causal.NewDotSet(dot("c", 1), dot("a", 2))
+	s.Union(other)
+	expected := []causal.Dot{dot("a", 1), dot("a", 2), dot("c", 1)}
+	got := s.Dots()
+	if len(got) != len(expected) {
+		t.Fatalf("Union mismatch, got: %v, expected: %v.", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Union mismatch, got: %v, expected: %v.", got, expected)
+		}
+	}
+
+	c := s.Copy()
+	c.Add(dot("d", 1))
+	if s.Contains(dot("d", 1)) {
+		t.Errorf("Copy shares state with the original set")
+	}
+}
+
+func TestDot_String(t *testing.T) {
+	if dot("a", 3).String() != "a:3" {
+		t.Errorf("Dot name mismatch, got: %v", dot("a", 3))
+	}
+}
+
+func TestDotContext_Next(t *testing.T) {
+	c := causal.NewDotContext()
+	d1 := c.Next("a")
+	d2 := c.Next("a")
+	d3 := c.Next("b")
+	if d1 != dot("a", 1) || d2 != dot("a", 2) || d3 != dot("b", 1) {
+		t.Errorf("Dots mismatch, got: %v, %v, %v", d1, d2, d3)
+	}
+	for _, d := range []causal.Dot{d1, d2, d3} {
+		if !c.Contains(d) {
+			t.Errorf("Missing dot in context: %v", d)
+		}
+	}
+	if c.Contains(dot("a", 3)) {
+		t.Errorf("Unexpected dot in context: %v", dot("a", 3))
+	}
+}
+
+func TestDotContext_Insert(t *testing.T) {
+	tests := []struct {
+		name           string
+		inserted       []causal.Dot
+		expectedVector causal.VersionVector
+		expectedCloud  []causal.Dot
+	}{
+		{"in order", []causal.Dot{dot("a", 1), dot("a", 2)}, causal.VersionVector{"a": 2}, nil},
+		{"gap", []causal.Dot{dot("a", 1), dot("a", 3)}, causal.VersionVector{"a": 1}, []causal.Dot{dot("a", 3)}},
+		{"gap filled", []causal.Dot{dot("a", 3), dot("a", 2), dot("a", 1)}, causal.VersionVector{"a": 3}, nil},
+		{"duplicates", []causal.Dot{dot("a", 1), dot("a", 1), dot("a", 3), dot("a", 3)}, causal.VersionVector{"a": 1}, []causal.Dot{dot("a", 3)}},
+		{"several replicas", []causal.Dot{dot("b", 2), dot("a", 1), dot("b", 1)}, causal.VersionVector{"a": 1, "b": 2}, nil},
+		{"only cloud", []causal.Dot{dot("a", 2), dot("b", 5)}, causal.VersionVector{}, []causal.Dot{dot("a", 2), dot("b", 5)}},
+	}
+	for _, tt := range tests {
+		c := causal.NewDotContext()
+		for _, d := range tt.inserted {
+			c.Insert(d)
+		}
+		if c.VersionVector().Compare(tt.expectedVector) != causal.Equal {
+			t.Errorf("Insert %s vector mismatch, got: %v, expected: %v.", tt.name, c.VersionVector(), tt.expectedVector)
+		}
+		cloud := c.Cloud().Dots()
+		if len(cloud) != len(tt.expectedCloud) {
+			t.Errorf("Insert %s cloud mismatch, got: %v, expected: %v.", tt.name, cloud, tt.expectedCloud)
+			continue
+		}
+		for i := range cloud {
+			if cloud[i] != tt.expectedCloud[i] {
+				t.Errorf("Insert %s cloud mismatch, got: %v, expected: %v.", tt.name, cloud, tt.expectedCloud)
+			}
+		}
+		for _, d := range tt.inserted {
+			if !c.Contains(d) {
+				t.Errorf("Insert %s lost dot %v", tt.name, d)
+			}
+		}
+	}
+}
+
+func TestDotContext_Merge(t *testing.T) {
+	c1 := causal.NewDotContext()
+	c1.Insert(dot("a", 1))
+	c1.Insert(dot("a", 3))
+	c1.Insert(dot("b", 1))
+	c2 := causal.NewDotContext()
+	c2.Insert(dot("a", 1))
+	c2.Insert(dot("a", 2))
+	c2.Insert(dot("c", 2))
+
+	c1.Merge(c2)
+	expected := causal.VersionVector{"a": 3, "b": 1}
+	if c1.VersionVector().Compare(expected) != causal.Equal {
+		t.Errorf("Merge vector mismatch, got: %v, expected: %v.", c1.VersionVector(), expected)
+	}
+	cloud := c1.Cloud()
+	if len(cloud) != 1 || !cloud.Contains(dot("c", 2)) {
+		t.Errorf("Merge cloud mismatch, got: %v", cloud.Dots())
+	}
+	if c2.Contains(dot("b", 1)) {
+		t.Errorf("Merge modified the other context")
+	}
+}
+
+// merging contexts must be a join: commutative, associative and idempotent
+func TestDotContext_MergeLaws(t *testing.T) {
+	build := func(dots ...causal.Dot) *causal.DotContext {
+		c := causal.NewDotContext()
+		for _, d := range dots {
+			c.Insert(d)
+		}
+		return c
+	}
+	contexts := []*causal.DotContext{
+		build(),
+		build(dot("a", 1)),
+		build(dot("a", 2)),
+		build(dot("a", 1), dot("b", 3)),
+		build(dot("b", 1), dot("b", 2), dot("c", 4)),
+	}
+	merge := func(c1, c2 *causal.DotContext) *causal.DotContext {
+		merged := c1.Copy()
+		merged.Merge(c2)
+		return merged
+	}
+	equal := func(c1, c2 *causal.DotContext) bool {
+		if c1.VersionVector().Compare(c2.VersionVector()) != causal.Equal || len(c1.Cloud()) != len(c2.Cloud()) {
+			return false
+		}
+		for d := range c1.Cloud() {
+			if !c2.Cloud().Contains(d) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, c1 := range contexts {
+		if !equal(merge(c1, c1), c1) {
+			t.Errorf("Merge not idempotent")
+		}
+		for _, c2 := range contexts {
+			if !equal(merge(c1, c2), merge(c2, c1)) {
+				t.Errorf("Merge not commutative")
+			}
+			for _, c3 := range contexts {
+				if !equal(merge(merge(c1, c2), c3), merge(c1, merge(c2, c3))) {
+					t.Errorf("Merge not associative")
+				}
+			}
+		}
+	}
+}
+
+func TestDotContext_Copy(t *testing.T) {
+	c := causal.NewDotContext()
+	c.Next("a")
+	copied := c.Copy()
+	copied.Next("a")
+	copied.Insert(dot("b", 5))
+	if c.Contains(dot("a", 2)) || c.Contains(dot("b", 5)) {
+		t.Errorf("Copy shares state with the original context")
+	}
+}
@@ -0,0 +1,97 @@
+// Package causal provides the causality metadata dot-based CRDTs are built on
+package causal
+
+import "fmt"
+
+// Ordering is the causal relation between two version vectors
+type Ordering int
+
+const (
+	// Equal vectors describe the same history
+	Equal Ordering = iota
+	// Before means the history of the first vector is strictly included in the second one
+	Before
+	// After means the history of the second vector is strictly included in the first one
+	After
+	// Concurrent vectors each know about events the other one does not
+	Concurrent
+)
+
+func (o Ordering) String() string {
+	switch o {
+	case Equal:
+		return "equal"
+	case Before:
+		return "before"
+	case After:
+		return "after"
+	case Concurrent:
+		return "concurrent"
+	}
+	return fmt.Sprintf("unknown(%d)", int(o))
+}
+
+// VersionVector counts, for each replica, how many of its events are known
+//
+// Missing replicas count as zero, so the zero value of a replica entry never needs to be stored
+type VersionVector map[string]uint64
+
+// Get returns the number of known events of a replica
+func (v VersionVector) Get(replica string) uint64 {
+	return v[replica]
+}
+
+// Increment records a new event of a replica and returns its counter
+func (v VersionVector) Increment(replica string) uint64 {
+	v[replica]++
+	return v[replica]
+}
+
+// Merge makes the vector know about every event the other vector knows about, by taking the pointwise maximum
+func (v VersionVector) Merge(other VersionVector) {
+	for replica, counter := range other {
+		if counter > v[replica] {
+			v[replica] = counter
+		}
+	}
+}
+
+// Compare returns the causal relation between the vector and the other one
+func (v VersionVector) Compare(other VersionVector) Ordering {
+	less, greater := false, false
+	for replica, counter := range v {
+		if counter > other[replica] {
+			greater = true
+		} else if counter < other[replica] {
+			less = true
+		}
+	}
+	for replica, counter := range other {
+		if _, ok := v[replica]; !ok && counter > 0 {
+			less = true
+		}
+	}
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	}
+	return Equal
+}
+
+// Contains checks if the event identified by a dot is known to the vector
+func (v VersionVector) Contains(d Dot) bool {
+	return d.Counter <= v[d.Replica]
+}
+
+// Copy returns an independent copy of the vector
+func (v VersionVector) Copy() VersionVector {
+	copied := make(VersionVector, len(v))
+	for replica, counter := range v {
+		copied[replica] = counter
+	}
+	return copied
+}
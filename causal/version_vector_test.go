@@ -0,0 +1,166 @@
+package causal_test
+
+import (
+	"testing"
+
+	"github.com/bjornaer/crdt/causal"
+)
+
+func TestVersionVector_Increment(t *testing.T) {
+	v := causal.VersionVector{}
+	if got := v.Increment("a"); got != 1 {
+		t.Errorf("Counter mismatch, got: %v, expected: %v.", got, 1)
+	}
+	if got := v.Increment("a"); got != 2 {
+		t.Errorf("Counter mismatch, got: %v, expected: %v.", got, 2)
+	}
+	v.Increment("b")
+	if v.Get("a") != 2 || v.Get("b") != 1 || v.Get("c") != 0 {
+		t.Errorf("Vector mismatch, got: %v", v)
+	}
+}
+
+func TestVersionVector_Merge(t *testing.T) {
+	tests := []struct {
+		name     string
+		v1, v2   causal.VersionVector
+		expected causal.VersionVector
+	}{
+		{"both empty", causal.VersionVector{}, causal.VersionVector{}, causal.VersionVector{}},
+		{"into empty", causal.VersionVector{}, causal.VersionVector{"a": 1}, causal.VersionVector{"a": 1}},
+		{"from empty", causal.VersionVector{"a": 1}, causal.VersionVector{}, causal.VersionVector{"a": 1}},
+		{"pointwise max", causal.VersionVector{"a": 3, "b": 1}, causal.VersionVector{"a": 1, "b": 2}, causal.VersionVector{"a": 3, "b": 2}},
+		{"disjoint replicas", causal.VersionVector{"a": 1}, causal.VersionVector{"b": 1}, causal.VersionVector{"a": 1, "b": 1}},
+		{"equal", causal.VersionVector{"a": 2}, causal.VersionVector{"a": 2}, causal.VersionVector{"a": 2}},
+	}
+	for _, tt := range tests {
+		got := tt.v1.Copy()
+		got.Merge(tt.v2)
+		if got.Compare(tt.expected) != causal.Equal {
+			t.Errorf("Merge %s failed, got: %v, expected: %v.", tt.name, got, tt.expected)
+		}
+	}
+}
+
+// merging must be a join: commutative, associative and idempotent
+func TestVersionVector_MergeLaws(t *testing.T) {
+	vectors := []causal.VersionVector{
+		{},
+		{"a": 1},
+		{"a": 2, "b": 1},
+		{"b": 3, "c": 1},
+		{"a": 1, "b": 1, "c": 1},
+	}
+	merge := func(v1, v2 causal.VersionVector) causal.VersionVector {
+		merged := v1.Copy()
+		merged.Merge(v2)
+		return merged
+	}
+	for _, v1 := range vectors {
+		if merge(v1, v1).Compare(v1) != causal.Equal {
+			t.Errorf("Merge not idempotent for %v", v1)
+		}
+		for _, v2 := range vectors {
+			if merge(v1, v2).Compare(merge(v2, v1)) != causal.Equal {
+				t.Errorf("Merge not commutative for %v and %v", v1, v2)
+			}
+			// the merge is an upper bound of both vectors
+			if o := v1.Compare(merge(v1, v2)); o != causal.Before && o != causal.Equal {
+				t.Errorf("Merge of %v and %v does not dominate %v", v1, v2, v1)
+			}
+			for _, v3 := range vectors {
+				if merge(merge(v1, v2), v3).Compare(merge(v1, merge(v2, v3))) != causal.Equal {
+					t.Errorf("Merge not associative for %v, %v and %v", v1, v2, v3)
+				}
+			}
+		}
+	}
+}
+
+func TestVersionVector_Compare(t *testing.T) {
+	tests := []struct {
+		name     string
+		v1, v2   causal.VersionVector
+		expected causal.Ordering
+	}{
+		{"both empty", causal.VersionVector{}, causal.VersionVector{}, causal.Equal},
+		{"explicit zero equals missing", causal.VersionVector{"a": 0}, causal.VersionVector{}, causal.Equal},
+		{"missing equals explicit zero", causal.VersionVector{}, causal.VersionVector{"a": 0}, causal.Equal},
+		{"same entries", causal.VersionVector{"a": 1, "b": 2}, causal.VersionVector{"a": 1, "b": 2}, causal.Equal},
+		{"empty before", causal.VersionVector{}, causal.VersionVector{"a": 1}, causal.Before},
+		{"empty after", causal.VersionVector{"a": 1}, causal.VersionVector{}, causal.After},
+		{"lower counter", causal.VersionVector{"a": 1}, causal.VersionVector{"a": 2}, causal.Before},
+		{"higher counter", causal.VersionVector{"a": 2}, causal.VersionVector{"a": 1}, causal.After},
+		{"missing replica", causal.VersionVector{"a": 1}, causal.VersionVector{"a": 1, "b": 1}, causal.Before},
+		{"extra replica", causal.VersionVector{"a": 1, "b": 1}, causal.VersionVector{"a": 1}, causal.After},
+		{"lower and equal", causal.VersionVector{"a": 1, "b": 2}, causal.VersionVector{"a": 2, "b": 2}, causal.Before},
+		{"crossed counters", causal.VersionVector{"a": 2, "b": 1}, causal.VersionVector{"a": 1, "b": 2}, causal.Concurrent},
+		{"disjoint replicas", causal.VersionVector{"a": 1}, causal.VersionVector{"b": 1}, causal.Concurrent},
+		{"higher and missing", causal.VersionVector{"a": 2}, causal.VersionVector{"a": 1, "b": 1}, causal.Concurrent},
+	}
+	for _, tt := range tests {
+		if got := tt.v1.Compare(tt.v2); got != tt.expected {
+			t.Errorf("Compare %s failed, got: %v, expected: %v.", tt.name, got, tt.expected)
+		}
+	}
+}
+
+// comparing the other way around must mirror the result
+func TestVersionVector_CompareSymmetry(t *testing.T) {
+	mirror := map[causal.Ordering]causal.Ordering{
+		causal.Equal:      causal.Equal,
+		causal.Before:     causal.After,
+		causal.After:      causal.Before,
+		causal.Concurrent: causal.Concurrent,
+	}
+	vectors := []causal.VersionVector{{}, {"a": 1}, {"a": 2}, {"b": 1}, {"a": 1, "b": 1}, {"a": 2, "b": 0}}
+	for _, v1 := range vectors {
+		for _, v2 := range vectors {
+			if v2.Compare(v1) != mirror[v1.Compare(v2)] {
+				t.Errorf("Compare not symmetric for %v and %v: %v then %v", v1, v2, v1.Compare(v2), v2.Compare(v1))
+			}
+		}
+	}
+}
+
+func TestVersionVector_Contains(t *testing.T) {
+	v := causal.VersionVector{"a": 2}
+	tests := []struct {
+		dot      causal.Dot
+		expected bool
+	}{
+		{causal.Dot{Replica: "a", Counter: 1}, true},
+		{causal.Dot{Replica: "a", Counter: 2}, true},
+		{causal.Dot{Replica: "a", Counter: 3}, false},
+		{causal.Dot{Replica: "b", Counter: 1}, false},
+	}
+	for _, tt := range tests {
+		if got := v.Contains(tt.dot); got != tt.expected {
+			t.Errorf("Contains %v failed, got: %v, expected: %v.", tt.dot, got, tt.expected)
+		}
+	}
+}
+
+func TestVersionVector_Copy(t *testing.T) {
+	v := causal.VersionVector{"a": 1}
+	c := v.Copy()
+	c.Increment("a")
+	if v.Get("a") != 1 {
+		t.Errorf("Copy shares state with the original vector")
+	}
+}
+
+func TestOrdering_String(t *testing.T) {
+	expected := map[causal.Ordering]string{
+		causal.Equal:       "equal",
+		causal.Before:      "before",
+		causal.After:       "after",
+		causal.Concurrent:  "concurrent",
+		causal.Ordering(9): "unknown(9)",
+	}
+	for o, name := range expected {
+		if o.String() != name {
+			t.Errorf("Ordering name mismatch, got: %v, expected: %v.", o.String(), name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package compaction periodically drops the tombstones of CRDT replicas that are older than a retention period
+package compaction
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Compactable is a CRDT dropping its tombstones stamped before a given time, sets and graphs implement it
+type Compactable interface {
+	// Compact drops the tombstones stamped before the given time, and returns the number of dropped entries
+	Compact(before time.Time) (int, error)
+}
+
+// Config holds the settings of a Compactor
+type Config struct {
+	// Interval is the time between two compactions of Run
+	Interval time.Duration
+	// OnError is called for every failed compaction, if set
+	OnError func(name string, err error)
+}
+
+// Option configures a Compactor
+type Option func(*Config)
+
+// WithInterval sets the time between two compactions
+func WithInterval(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Interval = d
+	}
+}
+
+// WithErrorHandler sets the function called for every failed compaction
+func WithErrorHandler(f func(name string, err error)) Option {
+	return func(cfg *Config) {
+		cfg.OnError = f
+	}
+}
+
+// Metrics describes the compactions of a target, or of every target for totals
+type Metrics struct {
+	// Runs counts the compactions, Failures the ones that returned an error
+	Runs     uint64
+	Failures uint64
+	// Reclaimed counts the dropped entries, LastReclaimed the ones dropped by the latest compaction
+	Reclaimed     uint64
+	LastReclaimed uint64
+	// LastRun is when the latest compaction started, and LastDuration how long it took
+	LastRun      time.Time
+	LastDuration time.Duration
+}
+
+// add accumulates the metrics of another target, the latest run being the most recent of both
+func (m *Metrics) add(other Metrics) {
+	m.Runs += other.Runs
+	m.Failures += other.Failures
+	m.Reclaimed += other.Reclaimed
+	m.LastReclaimed += other.LastReclaimed
+	if other.LastRun.After(m.LastRun) {
+		m.LastRun = other.LastRun
+		m.LastDuration = other.LastDuration
+	}
+}
+
+// Compactor compacts named CRDT replicas, dropping the tombstones older than the retention period
+//
+// Compacting relies on nothing older than the retention reaching the replicas anymore, peers that were away
+// for longer would bring elements back, see the Compact method of sets. Replicas synchronizing together should
+// all be compacted with the same retention
+type Compactor struct {
+	retention time.Duration
+	cfg       Config
+	targets   map[string]Compactable
+	metrics   map[string]*Metrics
+	mutex     sync.Mutex
+}
+
+// New returns a Compactor dropping the tombstones older than the retention period, every minute unless set otherwise
+func New(retention time.Duration, opts ...Option) *Compactor {
+	cfg := Config{Interval: time.Minute}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Compactor{
+		retention: retention,
+		cfg:       cfg,
+		targets:   make(map[string]Compactable),
+		metrics:   make(map[string]*Metrics),
+	}
+}
+
+// Register adds a replica to compact under the given name, metrics are reported under the same name
+func (c *Compactor) Register(name string, target Compactable) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.targets[name] = target
+	if _, ok := c.metrics[name]; !ok {
+		c.metrics[name] = &Metrics{}
+	}
+}
+
+// Run compacts every replica every interval until the context is done
+func (c *Compactor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			// failures are reported to the error handler and counted in the metrics, they do not stop the loop
+			_ = c.CompactOnce()
+		}
+	}
+}
+
+// CompactOnce compacts every replica once, and returns the first failure after trying all of them
+func (c *Compactor) CompactOnce() error {
+	c.mutex.Lock()
+	names := make([]string, 0, len(c.targets))
+	for name := range c.targets {
+		names = append(names, name)
+	}
+	c.mutex.Unlock()
+	sort.Strings(names)
+
+	failed := 0
+	var first error
+	for _, name := range names {
+		if err := c.compact(name); err != nil {
+			if c.cfg.OnError != nil {
+				c.cfg.OnError(name, err)
+			}
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("compaction failed for %d of %d replicas: %w", failed, len(names), first)
+	}
+	return nil
+}
+
+func (c *Compactor) compact(name string) error {
+	c.mutex.Lock()
+	target := c.targets[name]
+	c.mutex.Unlock()
+
+	start := time.Now()
+	dropped, err := target.Compact(start.Add(-c.retention))
+	duration := time.Since(start)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	m := c.metrics[name]
+	m.Runs++
+	m.Reclaimed += uint64(dropped)
+	m.LastReclaimed = uint64(dropped)
+	m.LastRun = start
+	m.LastDuration = duration
+	if err != nil {
+		m.Failures++
+		return fmt.Errorf("cannot compact %s: %w", name, err)
+	}
+	return nil
+}
+
+// Metrics returns the metrics of the replica registered under the given name
+func (c *Compactor) Metrics(name string) (Metrics, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	m, ok := c.metrics[name]
+	if !ok {
+		return Metrics{}, false
+	}
+	return *m, true
+}
+
+// Total returns the metrics of every replica added together
+func (c *Compactor) Total() Metrics {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var total Metrics
+	for _, m := range c.metrics {
+		total.add(*m)
+	}
+	return total
+}
@@ -0,0 +1,103 @@
+package compaction_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/compaction"
+)
+
+// failing is a replica whose compaction always fails
+type failing struct{}
+
+func (failing) Compact(time.Time) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// checks tombstones older than the retention are dropped, and reported in the metrics of their replica
+func TestCompactor_CompactOnce(t *testing.T) {
+	s := crdt.NewLWWSet[string]()
+	old := time.Now().Add(-time.Hour)
+	s.Add("item1", old)
+	s.Remove("item1", old.Add(time.Second))
+	s.Add("item2", old)
+	s.Remove("item2", time.Now())
+	g := crdt.NewLWWGraph[string]()
+	g.AddVertex("vertex1")
+	g.RemoveVertex("vertex1")
+
+	c := compaction.New(time.Minute)
+	c.Register("items", s)
+	c.Register("graph", g)
+	if err := c.CompactOnce(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if s.GetRemovals().Size() != 1 || s.Exists("item2") {
+		t.Errorf("Expected only the removal older than the retention to be dropped")
+	}
+	m, ok := c.Metrics("items")
+	if !ok || m.Runs != 1 || m.Reclaimed != 2 || m.LastReclaimed != 2 {
+		t.Errorf("Unexpected metrics, got: %+v, expected 1 run reclaiming 2 entries.", m)
+	}
+	if m, _ := c.Metrics("graph"); m.Reclaimed != 0 {
+		t.Errorf("Unexpected entries reclaimed within the retention, got: %d, expected: %d.", m.Reclaimed, 0)
+	}
+
+	if err := c.CompactOnce(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	total := c.Total()
+	if total.Runs != 4 || total.Reclaimed != 2 || total.LastReclaimed != 0 {
+		t.Errorf("Unexpected totals, got: %+v, expected 4 runs reclaiming 2 entries, none in the latest ones.", total)
+	}
+}
+
+// checks a failing replica is reported without preventing the others from being compacted
+func TestCompactor_Failure(t *testing.T) {
+	s := crdt.NewLWWSet[string]()
+	s.Add("item1", time.Now())
+	s.Remove("item1", time.Now())
+
+	var failures []string
+	c := compaction.New(0, compaction.WithErrorHandler(func(name string, err error) {
+		failures = append(failures, name)
+	}))
+	c.Register("broken", failing{})
+	c.Register("items", s)
+	if err := c.CompactOnce(); err == nil {
+		t.Errorf("Expected an error for the failing replica")
+	}
+	if len(failures) != 1 || failures[0] != "broken" {
+		t.Errorf("Unexpected failures, got: %v, expected: %v.", failures, []string{"broken"})
+	}
+	if m, _ := c.Metrics("broken"); m.Failures != 1 {
+		t.Errorf("Unexpected number of failures, got: %d, expected: %d.", m.Failures, 1)
+	}
+	if m, _ := c.Metrics("items"); m.Reclaimed != 2 {
+		t.Errorf("Unexpected number of reclaimed entries, got: %d, expected: %d.", m.Reclaimed, 2)
+	}
+}
+
+func TestCompactor_Run(t *testing.T) {
+	s := crdt.NewLWWSet[string]()
+	s.Add("item1", time.Now())
+	s.Remove("item1", time.Now())
+	c := compaction.New(0, compaction.WithInterval(10*time.Millisecond))
+	c.Register("items", s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, context.DeadlineExceeded)
+	}
+	if s.GetRemovals().Size() != 0 {
+		t.Errorf("Expected the tombstone to be dropped by the background compactor")
+	}
+	if m, _ := c.Metrics("items"); m.Runs < 2 {
+		t.Errorf("Unexpected number of runs, got: %d, expected several.", m.Runs)
+	}
+}
@@ -1,8 +1,13 @@
 package crdt
 
 import (
+	"github.com/bjornaer/crdt/crdtpb"
+	backends "github.com/bjornaer/crdt/internal/backends"
+	"github.com/bjornaer/crdt/internal/codec"
 	"github.com/bjornaer/crdt/internal/graph"
 	"github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/oplog"
+	"github.com/bjornaer/crdt/report"
 )
 
 type LastWriterWinsSet[T comparable] interface {
@@ -13,10 +18,170 @@ type LastWriterWinsGraph[T comparable] interface {
 	graph.LastWriterWinsGraph[T]
 }
 
-func NewLWWSet[T comparable]() LastWriterWinsSet[T] {
-	return set.NewLWWSet[T]()
+// ElementCodec translates elements to and from bytes, it is used by every serializer in the library
+type ElementCodec[T any] interface {
+	codec.ElementCodec[T]
 }
 
-func NewLWWGraph[T comparable]() LastWriterWinsGraph[T] {
-	return graph.NewLWWGraph[T]()
+// TimeSet holds element/timestamp associations, sets keep their additions and their removals in one each
+type TimeSet[T comparable] interface {
+	backends.TimeSet[T]
+}
+
+// FileTimeSet is a TimeSet persisted to disk, see OpenFileTimeSet
+type FileTimeSet[T comparable] interface {
+	TimeSet[T]
+	// Snapshot folds the write-ahead log into a new snapshot of every entry
+	Snapshot() error
+	Close() error
+}
+
+// ErrInvalidSnapshot is returned when restoring from data that is not a snapshot of the expected kind, or is damaged
+var ErrInvalidSnapshot = set.ErrInvalidSnapshot
+
+// ErrNoHistory is returned for queries about the past of sets and graphs that were not built WithHistory
+var ErrNoHistory = set.ErrNoHistory
+
+// ErrStaleState is returned for entries stamped before the garbage collection horizon of a set that could bring
+// a purged element back
+var ErrStaleState = set.ErrStaleState
+
+// Side designates one of the two replicas compared by DiffSets or DiffGraphs
+type Side = report.Side
+
+// SideA and SideB designate the first and the second replica compared by DiffSets or DiffGraphs
+const (
+	SideA = report.SideA
+	SideB = report.SideB
+)
+
+func NewLWWSet[T comparable](opts ...set.Option[T]) LastWriterWinsSet[T] {
+	return set.NewLWWSet(opts...)
+}
+
+func NewLWWGraph[T comparable](opts ...set.Option[T]) LastWriterWinsGraph[T] {
+	return graph.NewLWWGraph(opts...)
+}
+
+// JoinSetDeltas returns a new delta-state holding the union of the given set deltas
+func JoinSetDeltas[T comparable](deltas ...LastWriterWinsSet[T]) (LastWriterWinsSet[T], error) {
+	joined := make([]set.LastWriterWinsSet[T], 0, len(deltas))
+	for _, delta := range deltas {
+		joined = append(joined, delta)
+	}
+	return set.Join(joined...)
+}
+
+// JoinGraphDeltas returns a new delta-state holding the union of the given graph deltas
+func JoinGraphDeltas[T comparable](deltas ...LastWriterWinsGraph[T]) (LastWriterWinsGraph[T], error) {
+	joined := make([]graph.LastWriterWinsGraph[T], 0, len(deltas))
+	for _, delta := range deltas {
+		joined = append(joined, delta)
+	}
+	return graph.Join(joined...)
+}
+
+// DiffSets compares two set replicas without modifying either: the elements present in one only, the entries
+// whose timestamps differ, and which replica wins each of them on merge, see package report
+func DiffSets[T comparable](a, b LastWriterWinsSet[T]) (report.Difference[T], error) {
+	return set.Diff[T](a, b)
+}
+
+// DiffGraphs compares two graph replicas without modifying either, vertex by vertex and edge by edge
+func DiffGraphs[T comparable](a, b LastWriterWinsGraph[T]) (report.GraphDifference[T], error) {
+	return graph.Diff[T](a, b)
+}
+
+// WithCodec sets the codec used to serialize elements, for sets as well as for graph vertices
+func WithCodec[T comparable](c ElementCodec[T]) set.Option[T] {
+	return set.WithCodec[T](c)
+}
+
+// WithLog records every local mutation in the given operation log, for sets as well as for graphs
+func WithLog[T comparable](log *oplog.Log[T]) set.Option[T] {
+	return set.WithLog(log)
+}
+
+// WithDigest maintains a Merkle tree over the entries of sets, used to find where two replicas differ
+func WithDigest[T comparable]() set.Option[T] {
+	return set.WithDigest[T]()
+}
+
+// WithTimeSets stores the additions and removals of a set in the given time sets, such as the ones of OpenFileTimeSet
+func WithTimeSets[T comparable](additions, removals TimeSet[T]) set.Option[T] {
+	return set.WithTimeSets[T](additions, removals)
+}
+
+// OpenFileTimeSet opens the time set persisted at path (in path.wal and path.snapshot), creating it if needed
+//
+// Every Add is appended to a write-ahead log, which is folded into a snapshot every 10000 entries unless
+// WithSnapshotEvery says otherwise. Opening the set replays the log over the snapshot
+func OpenFileTimeSet[T comparable](path string, c ElementCodec[T], opts ...backends.FileOption) (FileTimeSet[T], error) {
+	s, err := backends.OpenFileTimeSet[T](path, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WithShards splits sets in the given number of shards, each with a lock, a delta and a time map of its own, which lets
+// concurrent writers scale. Graphs only shard their vertices set. A number that is not positive picks one according to the CPUs
+func WithShards[T comparable](shards int) set.Option[T] {
+	return set.WithShards[T](shards)
+}
+
+// WithHistory keeps every timestamp of the elements in memory rather than the latest one only, for sets as well as
+// for graphs, so they can be queried as of any past instant with GetAt, ExistsAt, VertexExistsAt and EdgeExistsAt
+func WithHistory[T comparable]() set.Option[T] {
+	return set.WithHistory[T]()
+}
+
+// WithSnapshotEvery sets the number of entries a FileTimeSet appends to its write-ahead log between two snapshots
+func WithSnapshotEvery(n int) backends.FileOption {
+	return backends.WithSnapshotEvery(n)
+}
+
+// WithFileSync makes a FileTimeSet flush every entry to stable storage before Add returns
+func WithFileSync() backends.FileOption {
+	return backends.WithSync()
+}
+
+// StringCodec stores strings as their raw bytes
+func StringCodec() ElementCodec[string] {
+	return codec.String()
+}
+
+// IntCodec stores integers as 8 big-endian bytes
+func IntCodec[T codec.Integer]() ElementCodec[T] {
+	return codec.Int[T]()
+}
+
+// UUIDCodec stores UUID-like 16 byte arrays as-is
+func UUIDCodec() ElementCodec[[16]byte] {
+	return codec.UUID()
+}
+
+// GobCodec serializes elements with encoding/gob, it is the default for element types without a dedicated codec
+func GobCodec[T any]() ElementCodec[T] {
+	return codec.Gob[T]()
+}
+
+// SetToProto converts a set to its protobuf wire message
+func SetToProto[T comparable](s LastWriterWinsSet[T]) (*crdtpb.LWWSet, error) {
+	return set.ToProto[T](s)
+}
+
+// SetFromProto builds a set out of its protobuf wire message
+func SetFromProto[T comparable](m *crdtpb.LWWSet, opts ...set.Option[T]) (LastWriterWinsSet[T], error) {
+	return set.FromProto(m, opts...)
+}
+
+// GraphToProto converts a graph to its protobuf wire message
+func GraphToProto[T comparable](g LastWriterWinsGraph[T]) (*crdtpb.LWWGraph, error) {
+	return graph.ToProto[T](g)
+}
+
+// GraphFromProto builds a graph out of its protobuf wire message
+func GraphFromProto[T comparable](m *crdtpb.LWWGraph, opts ...set.Option[T]) (LastWriterWinsGraph[T], error) {
+	return graph.FromProto(m, opts...)
 }
@@ -0,0 +1,514 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: crdt.proto
+
+package crdtpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Entry associates an element with the timestamp at which it was added to a time set.
+type Entry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// element is the element as produced by the ElementCodec of the replica.
+	Element []byte `protobuf:"bytes,1,opt,name=element,proto3" json:"element,omitempty"`
+	// timestamp is expressed in nanoseconds since the Unix epoch.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crdt_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_crdt_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_crdt_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Entry) GetElement() []byte {
+	if x != nil {
+		return x.Element
+	}
+	return nil
+}
+
+func (x *Entry) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+// LWWSet is the full state of a Last-Writer-Wins Element Set.
+type LWWSet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Additions []*Entry `protobuf:"bytes,1,rep,name=additions,proto3" json:"additions,omitempty"`
+	Removals  []*Entry `protobuf:"bytes,2,rep,name=removals,proto3" json:"removals,omitempty"`
+}
+
+func (x *LWWSet) Reset() {
+	*x = LWWSet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crdt_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LWWSet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LWWSet) ProtoMessage() {}
+
+func (x *LWWSet) ProtoReflect() protoreflect.Message {
+	mi := &file_crdt_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LWWSet.ProtoReflect.Descriptor instead.
+func (*LWWSet) Descriptor() ([]byte, []int) {
+	return file_crdt_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LWWSet) GetAdditions() []*Entry {
+	if x != nil {
+		return x.Additions
+	}
+	return nil
+}
+
+func (x *LWWSet) GetRemovals() []*Entry {
+	if x != nil {
+		return x.Removals
+	}
+	return nil
+}
+
+// Adjacency holds the edges set of a single vertex.
+type Adjacency struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// vertex is the vertex as produced by the ElementCodec of the replica.
+	Vertex []byte  `protobuf:"bytes,1,opt,name=vertex,proto3" json:"vertex,omitempty"`
+	Edges  *LWWSet `protobuf:"bytes,2,opt,name=edges,proto3" json:"edges,omitempty"`
+}
+
+func (x *Adjacency) Reset() {
+	*x = Adjacency{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crdt_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Adjacency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Adjacency) ProtoMessage() {}
+
+func (x *Adjacency) ProtoReflect() protoreflect.Message {
+	mi := &file_crdt_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Adjacency.ProtoReflect.Descriptor instead.
+func (*Adjacency) Descriptor() ([]byte, []int) {
+	return file_crdt_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Adjacency) GetVertex() []byte {
+	if x != nil {
+		return x.Vertex
+	}
+	return nil
+}
+
+func (x *Adjacency) GetEdges() *LWWSet {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+// LWWGraph is the full state of a graph built on Last-Writer-Wins Element Sets.
+type LWWGraph struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Vertices *LWWSet      `protobuf:"bytes,1,opt,name=vertices,proto3" json:"vertices,omitempty"`
+	Edges    []*Adjacency `protobuf:"bytes,2,rep,name=edges,proto3" json:"edges,omitempty"`
+}
+
+func (x *LWWGraph) Reset() {
+	*x = LWWGraph{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crdt_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LWWGraph) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LWWGraph) ProtoMessage() {}
+
+func (x *LWWGraph) ProtoReflect() protoreflect.Message {
+	mi := &file_crdt_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LWWGraph.ProtoReflect.Descriptor instead.
+func (*LWWGraph) Descriptor() ([]byte, []int) {
+	return file_crdt_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LWWGraph) GetVertices() *LWWSet {
+	if x != nil {
+		return x.Vertices
+	}
+	return nil
+}
+
+func (x *LWWGraph) GetEdges() []*Adjacency {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+// Delta carries a delta-state: it has the shape of the full state of the CRDT it applies to,
+// but only holds the entries that changed since the previous delta of the same origin.
+type Delta struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// origin identifies the replica that produced the delta.
+	Origin string `protobuf:"bytes,1,opt,name=origin,proto3" json:"origin,omitempty"`
+	// sequence orders the deltas produced by the same origin.
+	Sequence uint64 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// Types that are assignable to State:
+	//	*Delta_Set
+	//	*Delta_Graph
+	State isDelta_State `protobuf_oneof:"state"`
+}
+
+func (x *Delta) Reset() {
+	*x = Delta{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crdt_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Delta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Delta) ProtoMessage() {}
+
+func (x *Delta) ProtoReflect() protoreflect.Message {
+	mi := &file_crdt_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Delta.ProtoReflect.Descriptor instead.
+func (*Delta) Descriptor() ([]byte, []int) {
+	return file_crdt_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Delta) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *Delta) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (m *Delta) GetState() isDelta_State {
+	if m != nil {
+		return m.State
+	}
+	return nil
+}
+
+func (x *Delta) GetSet() *LWWSet {
+	if x, ok := x.GetState().(*Delta_Set); ok {
+		return x.Set
+	}
+	return nil
+}
+
+func (x *Delta) GetGraph() *LWWGraph {
+	if x, ok := x.GetState().(*Delta_Graph); ok {
+		return x.Graph
+	}
+	return nil
+}
+
+type isDelta_State interface {
+	isDelta_State()
+}
+
+type Delta_Set struct {
+	Set *LWWSet `protobuf:"bytes,3,opt,name=set,proto3,oneof"`
+}
+
+type Delta_Graph struct {
+	Graph *LWWGraph `protobuf:"bytes,4,opt,name=graph,proto3,oneof"`
+}
+
+func (*Delta_Set) isDelta_State() {}
+
+func (*Delta_Graph) isDelta_State() {}
+
+var File_crdt_proto protoreflect.FileDescriptor
+
+var file_crdt_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x63, 0x72, 0x64, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x63, 0x72,
+	0x64, 0x74, 0x2e, 0x76, 0x31, 0x22, 0x3f, 0x0a, 0x05, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x18,
+	0x0a, 0x07, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x07, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x22, 0x62, 0x0a, 0x06, 0x4c, 0x57, 0x57, 0x53, 0x65, 0x74,
+	0x12, 0x2c, 0x0a, 0x09, 0x61, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x63, 0x72, 0x64, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x52, 0x09, 0x61, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x2a,
+	0x0a, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x0e, 0x2e, 0x63, 0x72, 0x64, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x08, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x22, 0x4a, 0x0a, 0x09, 0x41, 0x64,
+	0x6a, 0x61, 0x63, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x65, 0x72, 0x74, 0x65,
+	0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x76, 0x65, 0x72, 0x74, 0x65, 0x78, 0x12,
+	0x25, 0x0a, 0x05, 0x65, 0x64, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f,
+	0x2e, 0x63, 0x72, 0x64, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x57, 0x57, 0x53, 0x65, 0x74, 0x52,
+	0x05, 0x65, 0x64, 0x67, 0x65, 0x73, 0x22, 0x61, 0x0a, 0x08, 0x4c, 0x57, 0x57, 0x47, 0x72, 0x61,
+	0x70, 0x68, 0x12, 0x2b, 0x0a, 0x08, 0x76, 0x65, 0x72, 0x74, 0x69, 0x63, 0x65, 0x73, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x63, 0x72, 0x64, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
+	0x57, 0x57, 0x53, 0x65, 0x74, 0x52, 0x08, 0x76, 0x65, 0x72, 0x74, 0x69, 0x63, 0x65, 0x73, 0x12,
+	0x28, 0x0a, 0x05, 0x65, 0x64, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12,
+	0x2e, 0x63, 0x72, 0x64, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x64, 0x6a, 0x61, 0x63, 0x65, 0x6e,
+	0x63, 0x79, 0x52, 0x05, 0x65, 0x64, 0x67, 0x65, 0x73, 0x22, 0x94, 0x01, 0x0a, 0x05, 0x44, 0x65,
+	0x6c, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x73,
+	0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73,
+	0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x23, 0x0a, 0x03, 0x73, 0x65, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x63, 0x72, 0x64, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
+	0x57, 0x57, 0x53, 0x65, 0x74, 0x48, 0x00, 0x52, 0x03, 0x73, 0x65, 0x74, 0x12, 0x29, 0x0a, 0x05,
+	0x67, 0x72, 0x61, 0x70, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x63, 0x72,
+	0x64, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x57, 0x57, 0x47, 0x72, 0x61, 0x70, 0x68, 0x48, 0x00,
+	0x52, 0x05, 0x67, 0x72, 0x61, 0x70, 0x68, 0x42, 0x07, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x42, 0x21, 0x5a, 0x1f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62,
+	0x6a, 0x6f, 0x72, 0x6e, 0x61, 0x65, 0x72, 0x2f, 0x63, 0x72, 0x64, 0x74, 0x2f, 0x63, 0x72, 0x64,
+	0x74, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_crdt_proto_rawDescOnce sync.Once
+	file_crdt_proto_rawDescData = file_crdt_proto_rawDesc
+)
+
+func file_crdt_proto_rawDescGZIP() []byte {
+	file_crdt_proto_rawDescOnce.Do(func() {
+		file_crdt_proto_rawDescData = protoimpl.X.CompressGZIP(file_crdt_proto_rawDescData)
+	})
+	return file_crdt_proto_rawDescData
+}
+
+var file_crdt_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_crdt_proto_goTypes = []interface{}{
+	(*Entry)(nil),     // 0: crdt.v1.Entry
+	(*LWWSet)(nil),    // 1: crdt.v1.LWWSet
+	(*Adjacency)(nil), // 2: crdt.v1.Adjacency
+	(*LWWGraph)(nil),  // 3: crdt.v1.LWWGraph
+	(*Delta)(nil),     // 4: crdt.v1.Delta
+}
+var file_crdt_proto_depIdxs = []int32{
+	0, // 0: crdt.v1.LWWSet.additions:type_name -> crdt.v1.Entry
+	0, // 1: crdt.v1.LWWSet.removals:type_name -> crdt.v1.Entry
+	1, // 2: crdt.v1.Adjacency.edges:type_name -> crdt.v1.LWWSet
+	1, // 3: crdt.v1.LWWGraph.vertices:type_name -> crdt.v1.LWWSet
+	2, // 4: crdt.v1.LWWGraph.edges:type_name -> crdt.v1.Adjacency
+	1, // 5: crdt.v1.Delta.set:type_name -> crdt.v1.LWWSet
+	3, // 6: crdt.v1.Delta.graph:type_name -> crdt.v1.LWWGraph
+	7, // [7:7] is the sub-list for method output_type
+	7, // [7:7] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_crdt_proto_init() }
+func file_crdt_proto_init() {
+	if File_crdt_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_crdt_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Entry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crdt_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LWWSet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crdt_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Adjacency); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crdt_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LWWGraph); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crdt_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Delta); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_crdt_proto_msgTypes[4].OneofWrappers = []interface{}{
+		(*Delta_Set)(nil),
+		(*Delta_Graph)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_crdt_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_crdt_proto_goTypes,
+		DependencyIndexes: file_crdt_proto_depIdxs,
+		MessageInfos:      file_crdt_proto_msgTypes,
+	}.Build()
+	File_crdt_proto = out.File
+	file_crdt_proto_rawDesc = nil
+	file_crdt_proto_goTypes = nil
+	file_crdt_proto_depIdxs = nil
+}
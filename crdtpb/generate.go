@@ -0,0 +1,4 @@
+// Package crdtpb holds the protobuf wire schema of the library CRDTs, for replicas written in other languages
+package crdtpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative crdt.proto
@@ -0,0 +1,248 @@
+package delta
+
+import (
+	"sync"
+	"time"
+)
+
+// Payload is what a Buffer ships to a peer
+type Payload[S any] struct {
+	// Full is true when State holds the full state of the replica rather than a join of deltas
+	Full bool `json:"full"`
+	// Sequence is the sequence of the latest delta included in State, the peer acknowledges it once merged
+	Sequence uint64 `json:"sequence"`
+	State    S      `json:"state"`
+}
+
+type entry[S any] struct {
+	sequence uint64
+	takenAt  time.Time
+	delta    S
+}
+
+// Buffer accumulates the deltas of a replica and tracks what each peer acknowledged,
+// so every peer only receives the deltas it has not seen yet
+//
+// Deltas are retained for the retention duration, a peer that has not acknowledged anything since
+// then (or never did) receives the full state instead
+type Buffer[S any] struct {
+	replica    Replica[S]
+	retention  time.Duration
+	entries    []entry[S]        // retained deltas, in sequence order
+	sequence   uint64            // sequence of the latest delta taken from the replica
+	horizon    uint64            // sequence of the latest delta dropped from the buffer
+	acks       map[string]uint64 // latest sequence acknowledged by each peer
+	collecting bool              // tombstones are kept until purged once Collect was called
+	tombstones []entry[S]        // tombstones of the retained deltas, or of every delta since Collect was called
+	purged     []entry[S]        // tombstones purged within the retention duration, filtered from what is received
+	collected  uint64            // sequence of the latest delta whose tombstones were purged
+	mutex      sync.Mutex
+}
+
+// NewBuffer returns an empty Buffer for the given replica
+func NewBuffer[S any](replica Replica[S], retention time.Duration) *Buffer[S] {
+	return &Buffer[S]{
+		replica:   replica,
+		retention: retention,
+		acks:      make(map[string]uint64),
+	}
+}
+
+// Flush moves the pending delta of the replica into the buffer, and drops the deltas that are no longer needed
+func (b *Buffer[S]) Flush() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.flush()
+}
+
+// flush moves the pending delta into the buffer, an empty one is dropped so rounds without mutations do not add entries
+func (b *Buffer[S]) flush() {
+	now := time.Now()
+	if delta := b.replica.TakeDelta(); !b.replica.Empty(delta) {
+		b.sequence++
+		b.entries = append(b.entries, entry[S]{sequence: b.sequence, takenAt: now, delta: delta})
+		// the sequence a tombstone is received in, unlike its stamp, tells which peers merged it
+		if c, ok := b.replica.(Collectable[S]); ok {
+			if tombstones, ok := c.Tombstones(delta); ok {
+				b.tombstones = append(b.tombstones, entry[S]{sequence: b.sequence, takenAt: now, delta: tombstones})
+			}
+		}
+	}
+
+	// deltas every known peer acknowledged are not needed anymore, peers showing up later get the full state
+	acked := b.sequence
+	for _, sequence := range b.acks {
+		if sequence < acked {
+			acked = sequence
+		}
+	}
+	drop := 0
+	for drop < len(b.entries) {
+		e := b.entries[drop]
+		if e.sequence > acked && now.Sub(e.takenAt) <= b.retention {
+			break
+		}
+		b.horizon = e.sequence
+		drop++
+	}
+	b.entries = b.entries[drop:]
+	if !b.collecting {
+		b.tombstones = expire(b.tombstones, now, b.retention)
+	}
+	b.purged = expire(b.purged, now, b.retention)
+}
+
+// expire drops the entries taken more than the retention duration ago
+func expire[S any](entries []entry[S], now time.Time, retention time.Duration) []entry[S] {
+	drop := 0
+	for drop < len(entries) && now.Sub(entries[drop].takenAt) > retention {
+		drop++
+	}
+	return entries[drop:]
+}
+
+// State returns a copy of the full state of the replica, stamped with the sequence of the latest delta it includes
+func (b *Buffer[S]) State() (Payload[S], error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.flush()
+	return b.full()
+}
+
+// full returns a payload holding a copy of the full state, the buffer lock has to be held
+func (b *Buffer[S]) full() (Payload[S], error) {
+	state, err := b.replica.State()
+	if err != nil {
+		return Payload[S]{}, err
+	}
+	return Payload[S]{Full: true, Sequence: b.sequence, State: state}, nil
+}
+
+// Prepare returns the payload to ship to a peer: the join of the deltas it did not acknowledge yet,
+// or the full state if some of them were already dropped from the buffer
+func (b *Buffer[S]) Prepare(peer string) (Payload[S], error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.flush()
+
+	acked, known := b.acks[peer]
+	if !known || acked < b.horizon {
+		return b.full()
+	}
+
+	var deltas []S
+	for _, e := range b.entries {
+		if e.sequence > acked {
+			deltas = append(deltas, e.delta)
+		}
+	}
+	joined, err := b.replica.Join(deltas...)
+	if err != nil {
+		return Payload[S]{}, err
+	}
+	return Payload[S]{Sequence: b.sequence, State: joined}, nil
+}
+
+// Ack records that a peer merged every delta up to the given sequence
+//
+// A sequence the buffer never reached was acknowledged to a previous incarnation of the replica,
+// the peer is then forgotten so it receives the full state next
+func (b *Buffer[S]) Ack(peer string, sequence uint64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if sequence > b.sequence {
+		b.forget(peer)
+		return
+	}
+	if current, known := b.acks[peer]; !known || sequence > current {
+		b.acks[peer] = sequence
+	}
+}
+
+// Forget stops tracking a peer, the next payload prepared for it will hold the full state
+func (b *Buffer[S]) Forget(peer string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.forget(peer)
+}
+
+func (b *Buffer[S]) forget(peer string) {
+	delete(b.acks, peer)
+}
+
+// Collect purges from the replica the tombstones every given peer merged, the ones received in deltas up to
+// the latest sequence all of them acknowledged, and returns the number of dropped entries
+//
+// Until the first call, tombstones are only recorded for the retention duration, the ones dropped by then and the
+// ones the replica held before the buffer are kept. Nothing is purged when no peer is given, when one of them
+// did not acknowledge anything, or when the replica is not Collectable
+//
+// Purged tombstones are filtered from what the buffer receives for the retention duration, so peers that did not
+// purge them yet do not bring them back
+func (b *Buffer[S]) Collect(peers ...string) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c, ok := b.replica.(Collectable[S])
+	if !ok {
+		return 0, nil
+	}
+	b.collecting = true
+	b.flush()
+	if len(peers) == 0 {
+		return 0, nil
+	}
+	stable := b.sequence
+	for _, peer := range peers {
+		acked, known := b.acks[peer]
+		if !known {
+			return 0, nil
+		}
+		if acked < stable {
+			stable = acked
+		}
+	}
+
+	purged := 0
+	for len(b.tombstones) > 0 && b.tombstones[0].sequence <= stable {
+		n, err := c.Purge(b.tombstones[0].delta)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		b.collected = b.tombstones[0].sequence
+		b.purged = append(b.purged, entry[S]{sequence: b.collected, takenAt: time.Now(), delta: b.tombstones[0].delta})
+		b.tombstones = b.tombstones[1:]
+	}
+	return purged, nil
+}
+
+// Behind tells whether a peer may miss tombstones the buffer purged, as it did not acknowledge the deltas they were
+// received in, the states it ships could then bring purged elements back
+func (b *Buffer[S]) Behind(peer string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	acked, known := b.acks[peer]
+	return b.collected > 0 && (!known || acked < b.collected)
+}
+
+// Receive merges a payload shipped by a peer into the replica, without the tombstones purged lately
+func (b *Buffer[S]) Receive(p Payload[S]) error {
+	b.mutex.Lock()
+	purged := make([]S, 0, len(b.purged))
+	for _, e := range b.purged {
+		purged = append(purged, e.delta)
+	}
+	b.mutex.Unlock()
+
+	state := p.State
+	if c, ok := b.replica.(Collectable[S]); ok && len(purged) > 0 {
+		joined, err := b.replica.Join(purged...)
+		if err != nil {
+			return err
+		}
+		if state, err = c.Without(state, joined); err != nil {
+			return err
+		}
+	}
+	return b.replica.Merge(state)
+}
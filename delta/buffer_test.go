@@ -0,0 +1,273 @@
+package delta_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/delta"
+)
+
+// node is a replica reachable through an in-memory transport
+type node[S any] struct {
+	name   string
+	buffer *delta.Buffer[S]
+}
+
+// ship sends from one node to another what the receiver has not acknowledged yet,
+// the receiver merges the payload and acknowledges it back
+func ship[S any](t *testing.T, from, to *node[S]) delta.Payload[S] {
+	payload, err := from.buffer.Prepare(to.name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = to.buffer.Receive(payload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	from.buffer.Ack(to.name, payload.Sequence)
+	return payload
+}
+
+// checks element is contained within set
+func contains[T comparable](s []T, e T) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// checks for set equality -- independent of order
+func setsAreEqual[T comparable](s1, s2 []T) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for _, v := range s1 {
+		if !contains(s2, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func setNode(name string, s crdt.LastWriterWinsSet[string], retention time.Duration) *node[crdt.LastWriterWinsSet[string]] {
+	return &node[crdt.LastWriterWinsSet[string]]{name: name, buffer: delta.NewBuffer(delta.Set(s), retention)}
+}
+
+func TestBuffer_Set(t *testing.T) {
+	s1 := crdt.NewLWWSet[string]()
+	s2 := crdt.NewLWWSet[string]()
+	n1 := setNode("n1", s1, time.Minute)
+	n2 := setNode("n2", s2, time.Minute)
+
+	s1.Add("item1", time.Now())
+	first := ship(t, n1, n2)
+	if !first.Full {
+		t.Errorf("Expected full state for a peer that never acknowledged anything")
+	}
+
+	s1.Add("item2", time.Now())
+	n1.buffer.Flush()
+	s1.Remove("item1", time.Now())
+	s1.Add("item3", time.Now())
+	second := ship(t, n1, n2)
+	if second.Full {
+		t.Errorf("Expected deltas for a peer that acknowledged the previous payload")
+	}
+	// the payload coalesces both flushed deltas, and only them
+	if second.State.GetAdditions().Size() != 2 || second.State.GetRemovals().Size() != 1 {
+		t.Errorf("Payload mismatch, got: %v additions and %v removals, expected: 2 and 1.",
+			second.State.GetAdditions().Size(), second.State.GetRemovals().Size())
+	}
+
+	items1, _ := s1.Get()
+	items2, _ := s2.Get()
+	if !setsAreEqual(items1, items2) {
+		t.Errorf("Replicas diverged, n1: %v, n2: %v.", items1, items2)
+	}
+
+	third := ship(t, n1, n2)
+	if third.State.GetAdditions().Size() != 0 || third.State.GetRemovals().Size() != 0 {
+		t.Errorf("Expected an empty payload once everything was acknowledged")
+	}
+}
+
+func TestBuffer_Bidirectional(t *testing.T) {
+	s1 := crdt.NewLWWSet[string]()
+	s2 := crdt.NewLWWSet[string]()
+	n1 := setNode("n1", s1, time.Minute)
+	n2 := setNode("n2", s2, time.Minute)
+
+	s1.Add("item1", time.Now())
+	s2.Add("item2", time.Now())
+	ship(t, n1, n2)
+	ship(t, n2, n1)
+	s2.Remove("item1", time.Now())
+	ship(t, n2, n1)
+
+	items1, _ := s1.Get()
+	items2, _ := s2.Get()
+	expected := []string{"item2"}
+	if !setsAreEqual(items1, expected) || !setsAreEqual(items2, expected) {
+		t.Errorf("Replicas diverged, n1: %v, n2: %v, expected: %v.", items1, items2, expected)
+	}
+}
+
+func TestBuffer_RetentionFallback(t *testing.T) {
+	s1 := crdt.NewLWWSet[string]()
+	s2 := crdt.NewLWWSet[string]()
+	s3 := crdt.NewLWWSet[string]()
+	retention := 10 * time.Millisecond
+	n1 := setNode("n1", s1, retention)
+	n2 := setNode("n2", s2, retention)
+	n3 := setNode("n3", s3, retention)
+	ship(t, n1, n2)
+	ship(t, n1, n3)
+
+	// n3 goes away for longer than the retention while n2 keeps up
+	s1.Add("item1", time.Now())
+	ship(t, n1, n2)
+	time.Sleep(2 * retention)
+	s1.Add("item2", time.Now())
+	n1.buffer.Flush()
+
+	if payload := ship(t, n1, n2); payload.Full {
+		t.Errorf("Expected deltas for a peer that kept up")
+	}
+	if payload := ship(t, n1, n3); !payload.Full {
+		t.Errorf("Expected full state for a peer away longer than the retention")
+	}
+	items1, _ := s1.Get()
+	items3, _ := s3.Get()
+	if !setsAreEqual(items1, items3) {
+		t.Errorf("Replicas diverged, n1: %v, n3: %v.", items1, items3)
+	}
+}
+
+func TestBuffer_Forget(t *testing.T) {
+	n1 := setNode("n1", crdt.NewLWWSet[string](), time.Minute)
+	n2 := setNode("n2", crdt.NewLWWSet[string](), time.Minute)
+	ship(t, n1, n2)
+	n1.buffer.Forget("n2")
+	if payload := ship(t, n1, n2); !payload.Full {
+		t.Errorf("Expected full state for a forgotten peer")
+	}
+}
+
+// checks a peer acknowledging a sequence from a previous incarnation of the replica gets the full state
+func TestBuffer_AckFromFuture(t *testing.T) {
+	n1 := setNode("n1", crdt.NewLWWSet[string](), time.Minute)
+	n2 := setNode("n2", crdt.NewLWWSet[string](), time.Minute)
+	ship(t, n1, n2)
+	n1.buffer.Ack("n2", 42)
+	if payload := ship(t, n1, n2); !payload.Full {
+		t.Errorf("Expected full state for a peer acknowledging an unknown sequence")
+	}
+}
+
+// checks rounds without mutations neither take a sequence nor retain an entry
+func TestBuffer_EmptyFlush(t *testing.T) {
+	s1 := crdt.NewLWWSet[string]()
+	n1 := setNode("n1", s1, time.Minute)
+	n2 := setNode("n2", crdt.NewLWWSet[string](), time.Minute)
+	s1.Add("item1", time.Now())
+	first := ship(t, n1, n2)
+	for i := 0; i < 3; i++ {
+		n1.buffer.Flush()
+	}
+	if payload := ship(t, n1, n2); payload.Sequence != first.Sequence {
+		t.Errorf("Unexpected sequence, got: %v, expected: %v.", payload.Sequence, first.Sequence)
+	}
+	s1.Add("item2", time.Now())
+	if payload := ship(t, n1, n2); payload.Sequence != first.Sequence+1 {
+		t.Errorf("Unexpected sequence, got: %v, expected: %v.", payload.Sequence, first.Sequence+1)
+	}
+}
+
+// checks full state payloads are copies, which later mutations of the replica leave untouched
+func TestBuffer_StateIsCopy(t *testing.T) {
+	s1 := crdt.NewLWWSet[string]()
+	n1 := setNode("n1", s1, time.Minute)
+	s1.Add("item1", time.Now())
+	payload, err := n1.buffer.State()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s1.Add("item2", time.Now())
+	if payload.State.Exists("item2") || !payload.State.Exists("item1") {
+		t.Errorf("Full state payload follows the replica, got: %v additions, expected: 1.", payload.State.GetAdditions().Size())
+	}
+}
+
+// checks tombstones are only purged once every peer acknowledged the delta they were received in, however old
+// their timestamps, and are not brought back by peers that did not purge them yet
+func TestBuffer_Collect(t *testing.T) {
+	sa := crdt.NewLWWSet[string]()
+	sb := crdt.NewLWWSet[string]()
+	sc := crdt.NewLWWSet[string]()
+	a := setNode("a", sa, time.Minute)
+	b := setNode("b", sb, time.Minute)
+	c := setNode("c", sc, time.Minute)
+	if n, err := a.buffer.Collect(); n != 0 || err != nil {
+		t.Errorf("Unexpected collection without peers, got: %d, %v, expected: 0, <nil>.", n, err)
+	}
+
+	addedAt := time.Now().Add(-time.Hour)
+	sa.Add("x", addedAt)
+	ship(t, a, b)
+	// a third replica ships a tombstone stamped long before what b acknowledged
+	sc.Remove("x", addedAt.Add(time.Minute))
+	ship(t, c, a)
+	if n, err := a.buffer.Collect("b"); n != 0 || err != nil {
+		t.Errorf("Unexpected collection before the peer merged the tombstone, got: %d, %v, expected: 0, <nil>.", n, err)
+	}
+	if n, err := a.buffer.Collect("b", "c"); n != 0 || err != nil {
+		t.Errorf("Unexpected collection while a peer never acknowledged anything, got: %d, %v, expected: 0, <nil>.", n, err)
+	}
+
+	ship(t, a, b)
+	if n, err := a.buffer.Collect("b"); n != 2 || err != nil {
+		t.Errorf("Unexpected collection, got: %d, %v, expected: 2, <nil>.", n, err)
+	}
+	if sa.GetAdditions().Size() != 0 || sa.GetRemovals().Size() != 0 {
+		t.Errorf("Unexpected entries after the collection, got: %v additions and %v removals, expected: 0 and 0.",
+			sa.GetAdditions().Size(), sa.GetRemovals().Size())
+	}
+	if a.buffer.Behind("b") || !a.buffer.Behind("c") {
+		t.Errorf("Unexpected peers behind, got: %v and %v for b and c, expected: false and true.",
+			a.buffer.Behind("b"), a.buffer.Behind("c"))
+	}
+
+	// b still holds the tombstone and ships it back
+	sb.Add("y", time.Now())
+	ship(t, b, a)
+	if sa.GetRemovals().Size() != 0 || sa.Exists("x") || !sa.Exists("y") {
+		t.Errorf("Unexpected entries once the peer shipped the purged tombstone, got: %v removals, expected: 0.",
+			sa.GetRemovals().Size())
+	}
+}
+
+func TestBuffer_Graph(t *testing.T) {
+	g1 := crdt.NewLWWGraph[string]()
+	g2 := crdt.NewLWWGraph[string]()
+	n1 := &node[crdt.LastWriterWinsGraph[string]]{name: "n1", buffer: delta.NewBuffer(delta.Graph(g1), time.Minute)}
+	n2 := &node[crdt.LastWriterWinsGraph[string]]{name: "n2", buffer: delta.NewBuffer(delta.Graph(g2), time.Minute)}
+
+	g1.AddVertex("vertex1")
+	g1.AddVertex("vertex2")
+	ship(t, n1, n2)
+	g1.AddVertex("vertex3")
+	g1.AddEdge("vertex1", "vertex3")
+	payload := ship(t, n1, n2)
+	if payload.Full {
+		t.Errorf("Expected deltas for a peer that acknowledged the previous payload")
+	}
+
+	vertices, _ := g2.GetAllVertices()
+	expected := []string{"vertex1", "vertex2", "vertex3"}
+	if !setsAreEqual(vertices, expected) || !g2.EdgeExists("vertex1", "vertex3") {
+		t.Errorf("Graph replicas diverged, got vertices: %v, expected: %v.", vertices, expected)
+	}
+}
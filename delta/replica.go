@@ -0,0 +1,136 @@
+// Package delta ships delta-states of CRDT replicas to their peers, falling back to full states when needed
+package delta
+
+import (
+	"time"
+
+	"github.com/bjornaer/crdt"
+)
+
+// Replica adapts a CRDT holding states of type S to the delta buffer
+type Replica[S any] interface {
+	// State returns a copy of the full state of the replica, which later mutations of the replica leave untouched
+	State() (S, error)
+	// TakeDelta returns the local mutations since the previous call
+	TakeDelta() S
+	// Empty tells whether a delta holds no entry
+	Empty(S) bool
+	// Merge merges a full state or a delta into the replica
+	Merge(S) error
+	// Join returns the union of the given deltas
+	Join(...S) (S, error)
+}
+
+// Collectable is implemented by replicas whose tombstones the buffer can purge once every peer merged them,
+// see Buffer.Collect
+type Collectable[S any] interface {
+	// Tombstones returns the removals a delta holds, and false when it holds none
+	Tombstones(delta S) (S, bool)
+	// Purge drops the given tombstones from the replica, unless they were superseded since,
+	// and returns the number of dropped entries
+	Purge(tombstones S) (int, error)
+	// Without returns a state without the entries the given purged tombstones supersede
+	Without(state S, purged S) (S, error)
+}
+
+type setReplica[T comparable] struct {
+	set crdt.LastWriterWinsSet[T]
+}
+
+// Set adapts a LastWriterWinsSet to the delta buffer
+func Set[T comparable](s crdt.LastWriterWinsSet[T]) Replica[crdt.LastWriterWinsSet[T]] {
+	return &setReplica[T]{set: s}
+}
+
+func (r *setReplica[T]) State() (crdt.LastWriterWinsSet[T], error) {
+	return crdt.JoinSetDeltas(r.set)
+}
+
+func (r *setReplica[T]) TakeDelta() crdt.LastWriterWinsSet[T] {
+	return r.set.TakeDelta()
+}
+
+func (r *setReplica[T]) Empty(delta crdt.LastWriterWinsSet[T]) bool {
+	return delta.GetAdditions().Size() == 0 && delta.GetRemovals().Size() == 0
+}
+
+func (r *setReplica[T]) Merge(other crdt.LastWriterWinsSet[T]) error {
+	return r.set.Merge(other)
+}
+
+func (r *setReplica[T]) Tombstones(delta crdt.LastWriterWinsSet[T]) (crdt.LastWriterWinsSet[T], bool) {
+	if delta.GetRemovals().Size() == 0 {
+		return nil, false
+	}
+	// the additions of the delta are not needed to purge, and would be retained until every peer merged them
+	tombstones := crdt.NewLWWSet[T]()
+	err := delta.GetRemovals().Each(func(element T, removedAt time.Time) error {
+		return tombstones.GetRemovals().Add(element, removedAt)
+	})
+	return tombstones, err == nil
+}
+
+func (r *setReplica[T]) Purge(tombstones crdt.LastWriterWinsSet[T]) (int, error) {
+	return r.set.Purge(tombstones)
+}
+
+func (r *setReplica[T]) Without(state, purged crdt.LastWriterWinsSet[T]) (crdt.LastWriterWinsSet[T], error) {
+	// purged elements were removed after their latest addition, additions stamped as their removal would win
+	purgedAt := func(element T) (time.Time, bool) {
+		return purged.GetRemovals().AddedAt(element)
+	}
+	filtered := crdt.NewLWWSet(crdt.WithCodec[T](state.GetCodec()))
+	err := state.GetAdditions().Each(func(element T, addedAt time.Time) error {
+		if removedAt, ok := purgedAt(element); ok && addedAt.Before(removedAt) {
+			return nil
+		}
+		return filtered.GetAdditions().Add(element, addedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = state.GetRemovals().Each(func(element T, removedAt time.Time) error {
+		if purgedRemoval, ok := purgedAt(element); ok && !removedAt.After(purgedRemoval) {
+			return nil
+		}
+		return filtered.GetRemovals().Add(element, removedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filtered, nil
+}
+
+func (r *setReplica[T]) Join(deltas ...crdt.LastWriterWinsSet[T]) (crdt.LastWriterWinsSet[T], error) {
+	return crdt.JoinSetDeltas(deltas...)
+}
+
+type graphReplica[T comparable] struct {
+	graph crdt.LastWriterWinsGraph[T]
+}
+
+// Graph adapts a LastWriterWinsGraph to the delta buffer
+func Graph[T comparable](g crdt.LastWriterWinsGraph[T]) Replica[crdt.LastWriterWinsGraph[T]] {
+	return &graphReplica[T]{graph: g}
+}
+
+func (r *graphReplica[T]) State() (crdt.LastWriterWinsGraph[T], error) {
+	return crdt.JoinGraphDeltas(r.graph)
+}
+
+func (r *graphReplica[T]) TakeDelta() crdt.LastWriterWinsGraph[T] {
+	return r.graph.TakeDelta()
+}
+
+func (r *graphReplica[T]) Empty(delta crdt.LastWriterWinsGraph[T]) bool {
+	d, err := crdt.DiffGraphs(delta, crdt.NewLWWGraph[T]())
+	return err == nil && d.Equal()
+}
+
+func (r *graphReplica[T]) Merge(other crdt.LastWriterWinsGraph[T]) error {
+	return r.graph.Merge(other)
+}
+
+func (r *graphReplica[T]) Join(deltas ...crdt.LastWriterWinsGraph[T]) (crdt.LastWriterWinsGraph[T], error) {
+	return crdt.JoinGraphDeltas(deltas...)
+}
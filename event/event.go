@@ -0,0 +1,49 @@
+// Package event describes the changes CRDT replicas report to their observers
+package event
+
+import (
+	"fmt"
+	"time"
+)
+
+// Type tells what changed
+type Type uint8
+
+const (
+	// Added means an element of a set, or a vertex of a graph, became present
+	Added Type = iota + 1
+	// Removed means an element of a set, or a vertex of a graph, stopped being present
+	Removed
+	// EdgeAdded means two vertices of a graph became connected
+	EdgeAdded
+	// EdgeRemoved means two vertices of a graph stopped being connected
+	EdgeRemoved
+)
+
+func (t Type) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case EdgeAdded:
+		return "edge_added"
+	case EdgeRemoved:
+		return "edge_removed"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// Event is an effective change of a replica: an element that appeared or disappeared, rather than a mutation
+// that may change nothing, such as adding an element that is already present
+type Event[T any] struct {
+	Type Type `json:"type"`
+	// Element is the element or vertex that changed, or the first vertex of an edge
+	Element T `json:"element"`
+	// Target is the second vertex of an edge
+	Target T `json:"target"`
+	// Timestamp is the timestamp of the entry that made the change
+	Timestamp time.Time `json:"timestamp"`
+	// Remote is true for changes introduced by a merge or an applied operation, rather than by a local mutation
+	Remote bool `json:"remote"`
+}
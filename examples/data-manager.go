@@ -4,94 +4,40 @@
 package main
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
 	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/replication"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
 type DataManager struct {
-	Store crdt.LastWriterWinsSet[string]
-	Peers []string
-	Self  string
+	Store      crdt.LastWriterWinsSet[string]
+	Replicator *replication.Replicator
+	Self       string
 }
 
 type RequestBody struct {
 	Item string
 }
 
-func SendRequest(url string) (http.Response, error) {
-	if url == "" {
-		return http.Response{}, errors.New("empty url provided")
-	}
-
-	client := http.Client{
-		Timeout: time.Duration(5 * 60 * time.Second),
-	}
-
-	response, err := client.Get(url)
-	if err != nil {
-		return http.Response{}, err
-	}
-
-	return *response, nil
+// NewDataManager returns a DataManager replicating its store with the peers served at the given base URLs
+func NewDataManager(self string, peers ...string) *DataManager {
+	dm := &DataManager{Store: crdt.NewLWWSet[string](), Self: self}
+	transport := replication.NewHTTPTransport(&http.Client{Timeout: 5 * time.Second})
+	dm.Replicator = replication.New(self, transport, replication.WithPeers(peers...))
+	dm.Replicator.Register("items", replication.Set(dm.Store, time.Minute))
+	return dm
 }
 
-func SendCRDTRequest(peer string) (crdt.LastWriterWinsSet[string], error) {
-	var _lwwSet crdt.LastWriterWinsSet[string]
-
-	// Return an empty LWW Set followed by an error if the peer is nil
-	if peer == "" {
-		return _lwwSet, errors.New("empty peer provided")
-	}
-
-	// generate the request URL
-	url := fmt.Sprintf("http://localhost:%s/raw", peer)
-	response, err := SendRequest(url)
+func (dm *DataManager) SyncWithPeers(context *gin.Context) {
+	// Push our changes to every peer and pull theirs, only what each side has not seen yet is exchanged
+	err := dm.Replicator.SyncOnce(context.Request.Context())
 	if err != nil {
-		return _lwwSet, err
-	}
-
-	// Return an empty LWW Set followed by an error
-	// if the peer's response is not HTTP 200 OK
-	if response.StatusCode != http.StatusOK {
-		return _lwwSet, errors.New("received invalid http response status:" + fmt.Sprint(response.StatusCode))
-	}
-
-	// Decode the peer's LWW Set to be usable by our local LWW Set
-	lwwSet := crdt.NewLWWSet[string]()
-	bodyBytes, err := io.ReadAll(response.Body)
-    if err != nil {
-        log.Fatal(err)
-    }
-	err = json.Unmarshal(bodyBytes, &lwwSet)
-	if err != nil {
-		return _lwwSet, err
-	}
-
-	// Return the decoded peer's LWW Set
-	_lwwSet = lwwSet
-	return _lwwSet, nil
-}
-
-func (dm *DataManager) SyncWithPeers() {
-	// Iterate over the peer list and send a /raw GET request
-	// to each peer to obtain its LWW Set
-	for _, peer := range dm.Peers {
-		peerSet, err := SendCRDTRequest(peer)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err, "peer": peer}).Error("failed sending raw set values request")
-			continue
-		}
-
-		// Merge the peer's Set with our local Set
-		dm.Store.Merge(peerSet)
+		log.WithFields(log.Fields{"error": err}).Error("failed syncing with peers")
 	}
 }
 
@@ -110,7 +56,7 @@ func (dm *DataManager) GetRawData(context *gin.Context) {
 }
 
 func (dm *DataManager) GetSyncedData(context *gin.Context) {
-	dm.SyncWithPeers()
+	dm.SyncWithPeers(context)
 	items, err := dm.Store.Get()
 	if err != nil {
 		context.Error(err)
@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/bjornaer/crdt"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/sync/errgroup"
 )
@@ -33,14 +32,14 @@ func router02(dm *DataManager) http.Handler {
 
 func main() {
 
-	dataMgmt01 := &DataManager{Store: crdt.NewLWWSet[string](), Peers: []string{"8081"}, Self: "01"}
+	dataMgmt01 := NewDataManager("01", "http://localhost:8081/crdt")
 	server01 := &http.Server{
 		Addr:         ":8080",
 		Handler:      router01(dataMgmt01),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
-	dataMgmt02 := &DataManager{Store: crdt.NewLWWSet[string](), Peers: []string{"8080"}, Self: "02"}
+	dataMgmt02 := NewDataManager("02", "http://localhost:8080/crdt")
 	server02 := &http.Server{
 		Addr:         ":8081",
 		Handler:      router02(dataMgmt02),
@@ -4,6 +4,9 @@
 package main
 
 import (
+	"net/http"
+
+	"github.com/bjornaer/crdt/replication"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,4 +16,6 @@ func addRoutes(r *gin.Engine, dm *DataManager) {
 	r.GET("/item", dm.GetSyncedData)
 	r.POST("/item", dm.AddData)
 	r.DELETE("/item", dm.RemoveData)
+	// sync endpoints used by the peers
+	r.Any("/crdt/*path", gin.WrapH(http.StripPrefix("/crdt", replication.NewHandler(dm.Replicator))))
 }
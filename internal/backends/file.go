@@ -0,0 +1,435 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	codec "github.com/bjornaer/crdt/internal/codec"
+)
+
+// FileConfig holds the settings of a FileTimeSet
+type FileConfig struct {
+	// SnapshotEvery is the number of entries appended to the write-ahead log before it is folded into a snapshot,
+	// 0 disables automatic snapshots
+	SnapshotEvery int
+	// Sync flushes every entry to stable storage before Add returns, rather than leaving it to the operating system
+	Sync bool
+}
+
+// FileOption configures a FileTimeSet
+type FileOption func(*FileConfig)
+
+// WithSnapshotEvery sets the number of entries appended to the write-ahead log between two snapshots
+func WithSnapshotEvery(n int) FileOption {
+	return func(cfg *FileConfig) {
+		cfg.SnapshotEvery = n
+	}
+}
+
+// WithSync flushes every entry to stable storage before Add returns
+func WithSync() FileOption {
+	return func(cfg *FileConfig) {
+		cfg.Sync = true
+	}
+}
+
+// snapshotMagic starts every snapshot file, its last byte is the version of the format
+var snapshotMagic = []byte("CRDTSNP\x02")
+
+// opAdd and opDelete tell the records appended by Add from the ones appended by Delete
+const (
+	opAdd byte = iota + 1
+	opDelete
+)
+
+// recordHeader is the length of the payload of a record before the element encoding: its op and timestamp
+const recordHeader = 1 + codec.TimeSize
+
+// errCorrupt is returned for records that are truncated or do not match their checksum
+var errCorrupt = errors.New("corrupt record")
+
+// errBroken is returned for writes to a set whose write-ahead log could not be restored after a failed append
+var errBroken = errors.New("write-ahead log is broken")
+
+// FileTimeSet is a TimeSet persisted in two files: a write-ahead log every Add and Delete is appended to,
+// and a snapshot of every entry the log is periodically folded into
+//
+// Entries are held in memory as well, reads never touch the files. Opening the set recovers the entries
+// of the snapshot and replays the log over them, dropping a record left half written by a crash
+type FileTimeSet[T comparable] struct {
+	memory  TimeMap[T]
+	path    string
+	codec   codec.ElementCodec[T]
+	cfg     FileConfig
+	wal     *os.File
+	offset  int64 // end of the latest record appended whole to the log
+	pending int   // entries appended to the log since the latest snapshot
+	broken  error // set once the log could not be restored after a failed append, writes are refused from then on
+	mutex   sync.Mutex
+}
+
+// OpenFileTimeSet opens the time set persisted at path, creating it if needed, elements are stored in their codec encoding
+//
+// The set lives in path.wal and path.snapshot
+func OpenFileTimeSet[T comparable](path string, c codec.ElementCodec[T], opts ...FileOption) (*FileTimeSet[T], error) {
+	cfg := FileConfig{SnapshotEvery: 10000}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	s := &FileTimeSet[T]{
+		memory: TimeMap[T]{Elements: make(map[T]time.Time)},
+		path:   path,
+		codec:  c,
+		cfg:    cfg,
+	}
+	if err := s.recoverSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := s.recoverLog(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileTimeSet[T]) recoverSnapshot() error {
+	f, err := os.Open(s.path + ".snapshot")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, len(snapshotMagic)+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("snapshot %s: %w", f.Name(), errCorrupt)
+	}
+	if string(header[:len(snapshotMagic)]) != string(snapshotMagic) {
+		return fmt.Errorf("snapshot %s: unknown format", f.Name())
+	}
+	// snapshots are written to the side and renamed once complete, a damaged one is not a crash leftover
+	count := binary.BigEndian.Uint64(header[len(snapshotMagic):])
+	for i := uint64(0); i < count; i++ {
+		op, key, t, _, err := readRecord(r)
+		if err == io.EOF {
+			err = errCorrupt
+		}
+		if err != nil {
+			return fmt.Errorf("snapshot %s: entry %d: %w", f.Name(), i, err)
+		}
+		if err := s.restore(op, key, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileTimeSet[T]) recoverLog() error {
+	f, err := os.OpenFile(s.path+".wal", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	var valid int64
+	for {
+		op, key, t, n, err := readRecord(r)
+		if err == io.EOF || errors.Is(err, errCorrupt) {
+			// records are appended one after the other, a crash can only leave the last one incomplete
+			break
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if err := s.restore(op, key, t); err != nil {
+			f.Close()
+			return err
+		}
+		valid += n
+		s.pending++
+	}
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	s.wal = f
+	s.offset = valid
+	return nil
+}
+
+// restore applies a recovered entry to memory
+func (s *FileTimeSet[T]) restore(op byte, key []byte, t time.Time) error {
+	element, err := s.codec.Decode(key)
+	if err != nil {
+		return err
+	}
+	if op == opDelete {
+		return s.memory.Delete(element)
+	}
+	return s.memory.Add(element, t)
+}
+
+// Add an element to the set, appending it to the write-ahead log first, if it is new or more recent than its entry
+func (s *FileTimeSet[T]) Add(value T, t time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if addedAt, ok := s.memory.AddedAt(value); ok && !t.After(addedAt) {
+		return nil
+	}
+	key, err := s.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	if err := s.append(encodeRecord(opAdd, key, t)); err != nil {
+		return err
+	}
+	if err := s.memory.Add(value, t); err != nil {
+		return err
+	}
+	return s.appended()
+}
+
+// Delete drops the entry of an element, appending the deletion to the write-ahead log first
+func (s *FileTimeSet[T]) Delete(value T) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if _, ok := s.memory.AddedAt(value); !ok {
+		return nil
+	}
+	key, err := s.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	if err := s.append(encodeRecord(opDelete, key, time.Time{})); err != nil {
+		return err
+	}
+	if err := s.memory.Delete(value); err != nil {
+		return err
+	}
+	return s.appended()
+}
+
+// writable returns the error refusing writes to the set, if any
+func (s *FileTimeSet[T]) writable() error {
+	if s.wal == nil {
+		return os.ErrClosed
+	}
+	return s.broken
+}
+
+// append writes a record at the end of the write-ahead log
+//
+// A record that could not be written whole is cut off the log, as recovery stops at the first damaged record and
+// would drop the ones appended after it. If it cannot be cut off, the set refuses every later write. A failed sync
+// leaves the set refusing writes as well, since whether the data made it to stable storage is unknown
+func (s *FileTimeSet[T]) append(record []byte) error {
+	if _, err := s.wal.Write(record); err != nil {
+		return s.rollback(err)
+	}
+	if s.cfg.Sync {
+		if err := s.wal.Sync(); err != nil {
+			err = s.rollback(err)
+			if s.broken == nil {
+				s.broken = fmt.Errorf("%w: sync failed: %v", errBroken, err)
+			}
+			return err
+		}
+	}
+	s.offset += int64(len(record))
+	return nil
+}
+
+// rollback cuts the log back to the end of the latest record appended whole, after an append failed with err
+func (s *FileTimeSet[T]) rollback(err error) error {
+	if terr := s.wal.Truncate(s.offset); terr != nil {
+		s.broken = fmt.Errorf("%w: %v, then cannot truncate: %v", errBroken, err, terr)
+		return s.broken
+	}
+	if _, serr := s.wal.Seek(s.offset, io.SeekStart); serr != nil {
+		s.broken = fmt.Errorf("%w: %v, then cannot seek: %v", errBroken, err, serr)
+		return s.broken
+	}
+	return err
+}
+
+// appended counts a record appended to the log, and folds the log into a snapshot once it holds enough of them
+func (s *FileTimeSet[T]) appended() error {
+	s.pending++
+	if s.cfg.SnapshotEvery > 0 && s.pending >= s.cfg.SnapshotEvery {
+		return s.snapshot()
+	}
+	return nil
+}
+
+// AddedAt returns the timestamp of a given element if it exists
+func (s *FileTimeSet[T]) AddedAt(value T) (time.Time, bool) {
+	return s.memory.AddedAt(value)
+}
+
+// Each traverses the items in the set, calling the provided function for each element/timestamp association
+func (s *FileTimeSet[T]) Each(f func(element T, addedAt time.Time) error) error {
+	return s.memory.Each(f)
+}
+
+func (s *FileTimeSet[T]) Size() int {
+	return s.memory.Size()
+}
+
+// Snapshot folds the write-ahead log into a new snapshot of every entry, and empties the log
+func (s *FileTimeSet[T]) Snapshot() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	return s.snapshot()
+}
+
+func (s *FileTimeSet[T]) snapshot() error {
+	tmp, err := os.Create(s.path + ".snapshot.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	header := make([]byte, len(snapshotMagic)+8)
+	copy(header, snapshotMagic)
+	binary.BigEndian.PutUint64(header[len(snapshotMagic):], uint64(s.memory.Size()))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	err = s.memory.Each(func(element T, addedAt time.Time) error {
+		key, err := s.codec.Encode(element)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(encodeRecord(opAdd, key, addedAt))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path+".snapshot"); err != nil {
+		return err
+	}
+	// the rename has to reach stable storage before the log is emptied, or a crash could lose both
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+
+	// a crash before the log is emptied replays entries the snapshot already holds, which changes nothing
+	if err := s.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.offset = 0
+	s.pending = 0
+	return nil
+}
+
+// syncDir flushes the entries of a directory to stable storage
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// Close closes the write-ahead log, the set cannot be written to anymore
+func (s *FileTimeSet[T]) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.wal == nil {
+		return nil
+	}
+	err := s.wal.Close()
+	s.wal = nil
+	return err
+}
+
+// encodeRecord frames an entry: the length of the payload, the payload (op, timestamp in its codec encoding, ignored
+// for deletions, followed by the element encoding), and the CRC-32 checksum of the payload
+func encodeRecord(op byte, key []byte, t time.Time) []byte {
+	payload := make([]byte, 0, recordHeader+len(key))
+	payload = append(payload, op)
+	payload = append(payload, codec.EncodeTime(t)...)
+	payload = append(payload, key...)
+
+	record := make([]byte, 4+len(payload)+4)
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	copy(record[4:], payload)
+	binary.BigEndian.PutUint32(record[4+len(payload):], crc32.ChecksumIEEE(payload))
+	return record
+}
+
+// readRecord reads a record framed by encodeRecord, along with its size
+//
+// It returns io.EOF at the end of the stream, and errCorrupt for records that are truncated or damaged
+func readRecord(r *bufio.Reader) (byte, []byte, time.Time, int64, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, time.Time{}, 0, io.EOF
+		}
+		return 0, nil, time.Time{}, 0, errCorrupt
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length < recordHeader || length > maxRecordSize {
+		return 0, nil, time.Time{}, 0, errCorrupt
+	}
+	data := make([]byte, length+4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, time.Time{}, 0, errCorrupt
+	}
+	payload := data[:length]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[length:]) {
+		return 0, nil, time.Time{}, 0, errCorrupt
+	}
+	op := payload[0]
+	if op != opAdd && op != opDelete {
+		return 0, nil, time.Time{}, 0, errCorrupt
+	}
+	t, err := codec.DecodeTime(payload[1:recordHeader])
+	if err != nil {
+		return 0, nil, time.Time{}, 0, errCorrupt
+	}
+	return op, payload[recordHeader:], t, int64(4 + length + 4), nil
+}
+
+// maxRecordSize bounds the records read back, so a damaged length does not allocate gigabytes
+const maxRecordSize = 64 << 20
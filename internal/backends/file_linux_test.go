@@ -0,0 +1,54 @@
+//go:build linux
+
+package internal_test
+
+import (
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// checks a record left half written by a failed append does not cost the records appended after it,
+// the file size limit of the process makes the append fail midway
+func TestFileTimeSet_FailedAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items")
+	s := openTestSet(t, path)
+	now := time.Now()
+	if err := s.Add("a", now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Skipf("Cannot read the file size limit: %v", err)
+	}
+	signal.Ignore(syscall.SIGXFSZ)
+	defer signal.Reset(syscall.SIGXFSZ)
+	// the log holds a single 17 bytes record, the next one only fits partially
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &syscall.Rlimit{Cur: 24, Max: limit.Max}); err != nil {
+		t.Skipf("Cannot lower the file size limit: %v", err)
+	}
+	err := s.Add("a long element that does not fit", now)
+	if lerr := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit); lerr != nil {
+		t.Fatalf("Cannot restore the file size limit: %v", lerr)
+	}
+	if err == nil {
+		t.Fatalf("Expected the append to fail")
+	}
+
+	if err := s.Add("b", now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Close()
+	s = openTestSet(t, path)
+	for _, e := range []string{"a", "b"} {
+		if _, ok := s.AddedAt(e); !ok {
+			t.Errorf("Expected %s to be recovered", e)
+		}
+	}
+	if _, ok := s.AddedAt("a long element that does not fit"); ok {
+		t.Errorf("Expected the failed entry not to be recovered")
+	}
+}
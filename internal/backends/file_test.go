@@ -0,0 +1,181 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+	codec "github.com/bjornaer/crdt/internal/codec"
+)
+
+func openTestSet(t *testing.T, path string, opts ...backends.FileOption) *backends.FileTimeSet[string] {
+	t.Helper()
+	s, err := backends.OpenFileTimeSet[string](path, codec.String(), opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// entries returns every element/timestamp association of a time set
+func entries(t *testing.T, s backends.TimeSet[string]) map[string]time.Time {
+	t.Helper()
+	result := make(map[string]time.Time)
+	err := s.Each(func(element string, addedAt time.Time) error {
+		result[element] = addedAt
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result
+}
+
+func checkEntries(t *testing.T, s backends.TimeSet[string], expected map[string]time.Time) {
+	t.Helper()
+	got := entries(t, s)
+	if len(got) != len(expected) {
+		t.Fatalf("Unexpected entries, got: %v, expected: %v.", got, expected)
+	}
+	for element, addedAt := range expected {
+		if !got[element].Equal(addedAt) {
+			t.Errorf("Unexpected timestamp for %s, got: %v, expected: %v.", element, got[element], addedAt)
+		}
+	}
+}
+
+func TestFileTimeSet_Recover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "additions")
+	now := time.Now()
+	s := openTestSet(t, path)
+	s.Add("a", now)
+	s.Add("b", now)
+	s.Add("a", now.Add(time.Second))
+	s.Add("b", now.Add(-time.Second)) // older, not kept
+	s.Close()
+
+	reopened := openTestSet(t, path)
+	checkEntries(t, reopened, map[string]time.Time{"a": now.Add(time.Second), "b": now})
+}
+
+// checks deletions are logged, and survive both a reopening and a snapshot
+func TestFileTimeSet_Delete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "removals")
+	now := time.Now()
+	s := openTestSet(t, path)
+	s.Add("a", now)
+	s.Add("b", now)
+	s.Delete("a")
+	s.Close()
+
+	reopened := openTestSet(t, path)
+	checkEntries(t, reopened, map[string]time.Time{"b": now})
+	reopened.Delete("b")
+	if err := reopened.Snapshot(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reopened.Close()
+
+	checkEntries(t, openTestSet(t, path), map[string]time.Time{})
+}
+
+// checks timestamps nanoseconds since the epoch cannot represent survive both the log and the snapshot, and an entry
+// as old as the earliest of them is not taken for a deletion
+func TestFileTimeSet_Timestamps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "additions")
+	expected := map[string]time.Time{
+		"zero":   {},
+		"1500":   time.Date(1500, 3, 1, 12, 0, 0, 7, time.UTC),
+		"3000":   time.Date(3000, 1, 1, 0, 0, 0, 999999999, time.UTC),
+		"oldest": time.Unix(0, -1<<63),
+	}
+	s := openTestSet(t, path)
+	for element, addedAt := range expected {
+		if err := s.Add(element, addedAt); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	s.Close()
+
+	reopened := openTestSet(t, path)
+	checkEntries(t, reopened, expected)
+	if err := reopened.Snapshot(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reopened.Close()
+	checkEntries(t, openTestSet(t, path), expected)
+}
+
+// checks the log is folded into snapshots, and entries added after the latest one are replayed over it
+func TestFileTimeSet_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "additions")
+	now := time.Now()
+	s := openTestSet(t, path, backends.WithSnapshotEvery(2))
+	s.Add("a", now)
+	s.Add("b", now)
+	s.Add("c", now)
+	s.Close()
+
+	wal, err := os.Stat(path + ".wal")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	snapshot, err := os.Stat(path + ".snapshot")
+	if err != nil {
+		t.Fatalf("Expected a snapshot to be written: %v", err)
+	}
+	if wal.Size() == 0 || wal.Size() >= snapshot.Size() {
+		t.Errorf("Expected the log to only hold the entry added after the snapshot, got %d bytes", wal.Size())
+	}
+	reopened := openTestSet(t, path)
+	checkEntries(t, reopened, map[string]time.Time{"a": now, "b": now, "c": now})
+}
+
+// checks a record left half written by a crash is dropped, and the log keeps working after it
+func TestFileTimeSet_TornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "additions")
+	now := time.Now()
+	s := openTestSet(t, path, backends.WithSync())
+	s.Add("a", now)
+	s.Add("b", now)
+	s.Close()
+
+	info, err := os.Stat(path + ".wal")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.Truncate(path+".wal", info.Size()-3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reopened := openTestSet(t, path)
+	checkEntries(t, reopened, map[string]time.Time{"a": now})
+
+	reopened.Add("c", now)
+	reopened.Close()
+	checkEntries(t, openTestSet(t, path), map[string]time.Time{"a": now, "c": now})
+}
+
+func TestFileTimeSet_CorruptSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "additions")
+	s := openTestSet(t, path)
+	s.Add("a", time.Now())
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Close()
+
+	data, err := os.ReadFile(path + ".snapshot")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path+".snapshot", data, 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := backends.OpenFileTimeSet[string](path, codec.String()); err == nil {
+		t.Errorf("Expected an error opening a damaged snapshot")
+	}
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// Hasher hashes comparable elements, equal elements always hash the same
+//
+// Strings and integers are hashed as they are, other elements are walked through reflection, which is much cheaper
+// than encoding them
+type Hasher[T comparable] struct {
+	seed maphash.Seed
+}
+
+// NewHasher returns a Hasher with a random seed
+func NewHasher[T comparable]() Hasher[T] {
+	return Hasher[T]{seed: maphash.MakeSeed()}
+}
+
+// Sum returns the hash of an element
+func (h Hasher[T]) Sum(value T) uint64 {
+	var m maphash.Hash
+	m.SetSeed(h.seed)
+	switch v := any(value).(type) {
+	case string:
+		m.WriteString(v)
+	case int:
+		writeUint64(&m, uint64(v))
+	case int64:
+		writeUint64(&m, uint64(v))
+	case int32:
+		writeUint64(&m, uint64(v))
+	case uint:
+		writeUint64(&m, uint64(v))
+	case uint64:
+		writeUint64(&m, v)
+	case uint32:
+		writeUint64(&m, uint64(v))
+	case [16]byte:
+		m.Write(v[:])
+	default:
+		writeValue(&m, reflect.ValueOf(value))
+	}
+	return m.Sum64()
+}
+
+func writeUint64(m *maphash.Hash, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	m.Write(b[:])
+}
+
+// writeValue hashes a comparable value field by field, following what == compares
+func writeValue(m *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		m.WriteString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			m.WriteByte(1)
+		} else {
+			m.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(m, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(m, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(m, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		writeFloat(m, real(v.Complex()))
+		writeFloat(m, imag(v.Complex()))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeValue(m, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			writeValue(m, v.Field(i))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			m.WriteByte(0)
+			return
+		}
+		// values of different dynamic types are not equal, hashing them alike only costs a collision
+		writeValue(m, v.Elem())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint64(m, uint64(v.Pointer()))
+	}
+}
+
+// writeFloat hashes a float so that equal floats hash the same, +0 and -0 included
+func writeFloat(m *maphash.Hash, f float64) {
+	if f == 0 {
+		f = 0
+	}
+	writeUint64(m, math.Float64bits(f))
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// History is implemented by time sets retaining every timestamp of their elements, rather than the latest one only
+type History[T comparable] interface {
+	// AddedAsOf returns the latest timestamp of a given element that is not after the given time, if any
+	AddedAsOf(T, time.Time) (time.Time, bool)
+}
+
+// HistoryTimeMap is a TimeSet retaining every timestamp an element was added at, so it can be queried as of any
+// past instant. As a TimeSet, it only exposes the latest timestamp of every element
+//
+// Timestamps are held in memory and never dropped, unless the whole element is deleted
+type HistoryTimeMap[T comparable] struct {
+	Elements map[T][]time.Time `json:"elements"` // timestamps of every element, in increasing order
+	mutex    sync.RWMutex
+}
+
+// NewHistoryTimeMap returns an empty HistoryTimeMap
+func NewHistoryTimeMap[T comparable]() *HistoryTimeMap[T] {
+	return &HistoryTimeMap[T]{Elements: make(map[T][]time.Time)}
+}
+
+// Add records a timestamp of an element, unless it was already recorded
+func (s *HistoryTimeMap[T]) Add(value T, t time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	history := s.Elements[value]
+	i := sort.Search(len(history), func(i int) bool { return !history[i].Before(t) })
+	if i < len(history) && history[i].Equal(t) {
+		return nil
+	}
+	history = append(history, time.Time{})
+	copy(history[i+1:], history[i:])
+	history[i] = t
+	s.Elements[value] = history
+	return nil
+}
+
+// AddedAt returns the latest timestamp of a given element if it exists
+func (s *HistoryTimeMap[T]) AddedAt(value T) (time.Time, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	history := s.Elements[value]
+	if len(history) == 0 {
+		return time.Time{}, false
+	}
+	return history[len(history)-1], true
+}
+
+// AddedAsOf returns the latest timestamp of a given element that is not after the given time, if any
+func (s *HistoryTimeMap[T]) AddedAsOf(value T, t time.Time) (time.Time, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	history := s.Elements[value]
+	i := sort.Search(len(history), func(i int) bool { return history[i].After(t) })
+	if i == 0 {
+		return time.Time{}, false
+	}
+	return history[i-1], true
+}
+
+// Each traverses the items in the set, calling the provided function for each element and its latest timestamp
+func (s *HistoryTimeMap[T]) Each(f func(element T, addedAt time.Time) error) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for element, history := range s.Elements {
+		if err := f(element, history[len(history)-1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete drops every timestamp of an element
+func (s *HistoryTimeMap[T]) Delete(value T) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.Elements, value)
+	return nil
+}
+
+func (s *HistoryTimeMap[T]) Size() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.Elements)
+}
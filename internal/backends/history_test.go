@@ -0,0 +1,37 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+)
+
+// checks every timestamp is retained whatever the order it was added in, while the latest one is exposed
+func TestHistoryTimeMap_AddedAsOf(t *testing.T) {
+	base := time.Now()
+	s := backends.NewHistoryTimeMap[string]()
+	s.Add("a", base.Add(2*time.Second))
+	s.Add("a", base)
+	s.Add("a", base.Add(time.Second))
+	s.Add("a", base)
+
+	if addedAt, _ := s.AddedAt("a"); !addedAt.Equal(base.Add(2 * time.Second)) {
+		t.Errorf("Unexpected latest timestamp, got: %v, expected: %v.", addedAt, base.Add(2*time.Second))
+	}
+	if _, ok := s.AddedAsOf("a", base.Add(-time.Second)); ok {
+		t.Errorf("Unexpected timestamp before the first addition")
+	}
+	for _, at := range []time.Duration{0, time.Second, 1500 * time.Millisecond, time.Hour} {
+		expected := base.Add(at.Truncate(time.Second))
+		if at > 2*time.Second {
+			expected = base.Add(2 * time.Second)
+		}
+		if addedAt, ok := s.AddedAsOf("a", base.Add(at)); !ok || !addedAt.Equal(expected) {
+			t.Errorf("Unexpected timestamp as of %v, got: %v, expected: %v.", at, addedAt, expected)
+		}
+	}
+	if size := s.Size(); size != 1 {
+		t.Errorf("Unexpected size, got: %d, expected: %d.", size, 1)
+	}
+}
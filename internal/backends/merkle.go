@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"sync"
+	"time"
+
+	codec "github.com/bjornaer/crdt/internal/codec"
+	"github.com/bjornaer/crdt/merkle"
+)
+
+// MerkleTimeSet is a TimeSet decorator keeping a Merkle tree up to date with the entries of the decorated TimeSet
+//
+// Several time sets can share a tree, the tag tells their entries apart
+type MerkleTimeSet[T comparable] struct {
+	TimeSet[T]
+	tree  *merkle.Tree
+	tag   byte
+	codec codec.ElementCodec[T]
+	mutex sync.Mutex // makes reading the previous timestamp and replacing it atomic
+}
+
+// NewMerkleTimeSet decorates a TimeSet, entries it already holds are added to the tree
+func NewMerkleTimeSet[T comparable](inner TimeSet[T], tree *merkle.Tree, tag byte, c codec.ElementCodec[T]) (TimeSet[T], error) {
+	s := &MerkleTimeSet[T]{TimeSet: inner, tree: tree, tag: tag, codec: c}
+	err := inner.Each(func(element T, addedAt time.Time) error {
+		key, err := c.Encode(element)
+		if err != nil {
+			return err
+		}
+		tree.Toggle(key, merkle.EntryHash(tag, key, addedAt))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add an element to the decorated TimeSet, replacing the hash of its previous entry in the tree if its timestamp changed
+func (s *MerkleTimeSet[T]) Add(value T, t time.Time) error {
+	key, err := s.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	before, existed := s.TimeSet.AddedAt(value)
+	err = s.TimeSet.Add(value, t)
+	if err != nil {
+		return err
+	}
+	after, _ := s.TimeSet.AddedAt(value)
+	if existed && before.Equal(after) {
+		return nil
+	}
+	if existed {
+		s.tree.Toggle(key, merkle.EntryHash(s.tag, key, before))
+	}
+	s.tree.Toggle(key, merkle.EntryHash(s.tag, key, after))
+	return nil
+}
+
+// Delete drops the entry of an element from the decorated TimeSet, and its hash from the tree
+func (s *MerkleTimeSet[T]) Delete(value T) error {
+	key, err := s.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	before, existed := s.TimeSet.AddedAt(value)
+	if !existed {
+		return nil
+	}
+	err = s.TimeSet.Delete(value)
+	if err != nil {
+		return err
+	}
+	s.tree.Toggle(key, merkle.EntryHash(s.tag, key, before))
+	return nil
+}
+
+// Unwrap returns the decorated TimeSet
+func (s *MerkleTimeSet[T]) Unwrap() TimeSet[T] {
+	return s.TimeSet
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"runtime"
+	"time"
+)
+
+// ShardedTimeMap is a TimeSet spreading its elements over several TimeMaps, each with a lock of its own,
+// so concurrent writers only contend when their elements land in the same shard
+//
+// Elements are assigned to shards by their hash, see Hasher
+type ShardedTimeMap[T comparable] struct {
+	shards []*TimeMap[T]
+	hasher Hasher[T]
+}
+
+// DefaultShards returns the number of shards used when none is given, four per CPU
+func DefaultShards() int {
+	return 4 * runtime.GOMAXPROCS(0)
+}
+
+// NewShardedTimeMap returns an empty ShardedTimeMap, DefaultShards are used when shards is not positive
+func NewShardedTimeMap[T comparable](shards int) *ShardedTimeMap[T] {
+	if shards <= 0 {
+		shards = DefaultShards()
+	}
+	s := &ShardedTimeMap[T]{
+		shards: make([]*TimeMap[T], shards),
+		hasher: NewHasher[T](),
+	}
+	for i := range s.shards {
+		s.shards[i] = &TimeMap[T]{Elements: make(map[T]time.Time)}
+	}
+	return s
+}
+
+// shard returns the shard holding an element
+func (s *ShardedTimeMap[T]) shard(value T) *TimeMap[T] {
+	return s.shards[s.hasher.Sum(value)%uint64(len(s.shards))]
+}
+
+// Add an element in the set if it does not exist yet, or exists with a lesser timestamp than the given one
+func (s *ShardedTimeMap[T]) Add(value T, t time.Time) error {
+	return s.shard(value).Add(value, t)
+}
+
+// AddedAt returns the timestamp of a given element if it exists
+func (s *ShardedTimeMap[T]) AddedAt(value T) (time.Time, bool) {
+	return s.shard(value).AddedAt(value)
+}
+
+// Delete drops the entry of an element, if any
+func (s *ShardedTimeMap[T]) Delete(value T) error {
+	return s.shard(value).Delete(value)
+}
+
+// Each traverses the items in the set one shard after the other, calling the provided function
+// for each element/timestamp association
+func (s *ShardedTimeMap[T]) Each(f func(element T, addedAt time.Time) error) error {
+	for _, shard := range s.shards {
+		if err := shard.Each(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ShardedTimeMap[T]) Size() int {
+	size := 0
+	for _, shard := range s.shards {
+		size += shard.Size()
+	}
+	return size
+}
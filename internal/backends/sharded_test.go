@@ -0,0 +1,65 @@
+package internal_test
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+)
+
+// checks elements of every kind land in the same shard as the elements equal to them
+func TestHasher_Sum(t *testing.T) {
+	type point struct {
+		X, Y  int
+		Label string
+		Ratio float64
+	}
+	h := backends.NewHasher[point]()
+	if h.Sum(point{1, 2, "a", 0}) != h.Sum(point{1, 2, "a", math.Copysign(0, -1)}) {
+		t.Errorf("Expected equal elements to hash the same")
+	}
+	if h.Sum(point{1, 2, "a", 0}) == h.Sum(point{2, 1, "a", 0}) {
+		t.Errorf("Expected different elements to hash differently")
+	}
+	strings := backends.NewHasher[string]()
+	if strings.Sum("element") != strings.Sum(string([]byte("element"))) {
+		t.Errorf("Expected equal strings to hash the same")
+	}
+}
+
+// checks concurrent writers end up with the latest timestamp of every element
+func TestShardedTimeMap_ConcurrentAdd(t *testing.T) {
+	s := backends.NewShardedTimeMap[int](8)
+	base := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				s.Add(i, base.Add(time.Duration(w)*time.Second))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if size := s.Size(); size != 1000 {
+		t.Errorf("Unexpected size, got: %d, expected: %d.", size, 1000)
+	}
+	latest := base.Add(7 * time.Second)
+	err := s.Each(func(element int, addedAt time.Time) error {
+		if !addedAt.Equal(latest) {
+			return fmt.Errorf("element %d added at %v, expected %v", element, addedAt, latest)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, ok := s.AddedAt(1000); ok {
+		t.Errorf("Unexpected timestamp for an element never added")
+	}
+}
@@ -10,6 +10,7 @@ type TimeSet[T comparable] interface {
 	AddedAt(T) (time.Time, bool)
 	Each(func(T, time.Time) error) error
 	Size() int
+	Delete(T) error
 }
 
 // TimeMap is an implementation of a timeSet that uses a map data structure. We map items to timestamps.
@@ -56,6 +57,14 @@ func (s *TimeMap[T]) Each(f func(element T, addedAt time.Time) error) error {
 	return nil
 }
 
+// Delete drops the entry of an element, if any
+func (s *TimeMap[T]) Delete(value T) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.Elements, value)
+	return nil
+}
+
 func (s *TimeMap[T]) Size() int {
 	size := 0
 	s.mutex.RLock()
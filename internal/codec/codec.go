@@ -0,0 +1,158 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+)
+
+// ElementCodec translates set elements to and from their byte representation.
+// Every serializer in the library goes through an ElementCodec, so any type can be used as an element
+// as long as a codec for it is provided
+type ElementCodec[T any] interface {
+	Encode(T) ([]byte, error)
+	Decode([]byte) (T, error)
+}
+
+// Integer is the constraint satisfied by every built-in integer type
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(value string) ([]byte, error) {
+	return []byte(value), nil
+}
+
+func (stringCodec) Decode(data []byte) (string, error) {
+	return string(data), nil
+}
+
+// String returns a codec storing strings as their raw bytes
+func String() ElementCodec[string] {
+	return stringCodec{}
+}
+
+type integerCodec[T Integer] struct{}
+
+// signed reports whether T is a signed integer type
+func (integerCodec[T]) signed() bool {
+	var zero T
+	return ^zero < zero
+}
+
+func (c integerCodec[T]) Encode(value T) ([]byte, error) {
+	u := uint64(value)
+	if c.signed() {
+		// flipping the sign bit keeps the byte order of the encoding equal to the numeric order
+		u = uint64(int64(value)) ^ (1 << 63)
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, u)
+	return data, nil
+}
+
+func (c integerCodec[T]) Decode(data []byte) (T, error) {
+	if len(data) != 8 {
+		return 0, fmt.Errorf("cannot decode integer, expected 8 bytes but got %d", len(data))
+	}
+	u := binary.BigEndian.Uint64(data)
+	if c.signed() {
+		i := int64(u ^ (1 << 63))
+		if int64(T(i)) != i {
+			return 0, fmt.Errorf("cannot decode integer, %d overflows %T", i, T(0))
+		}
+		return T(i), nil
+	}
+	if uint64(T(u)) != u {
+		return 0, fmt.Errorf("cannot decode integer, %d overflows %T", u, T(0))
+	}
+	return T(u), nil
+}
+
+// Int returns a codec storing integers as 8 big-endian bytes, so that encodings sort like the numbers do
+func Int[T Integer]() ElementCodec[T] {
+	return integerCodec[T]{}
+}
+
+type uuidCodec struct{}
+
+func (uuidCodec) Encode(value [16]byte) ([]byte, error) {
+	return value[:], nil
+}
+
+func (uuidCodec) Decode(data []byte) ([16]byte, error) {
+	var value [16]byte
+	if len(data) != len(value) {
+		return value, fmt.Errorf("cannot decode uuid, expected %d bytes but got %d", len(value), len(data))
+	}
+	copy(value[:], data)
+	return value, nil
+}
+
+// UUID returns a codec for UUID-like 16 byte arrays, stored as-is
+func UUID() ElementCodec[[16]byte] {
+	return uuidCodec{}
+}
+
+type gobCodec[T any] struct{}
+
+func (gobCodec[T]) Encode(value T) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// Gob returns a codec using encoding/gob, suitable for structs and any other type gob can handle
+func Gob[T any]() ElementCodec[T] {
+	return gobCodec[T]{}
+}
+
+// Default picks a built-in codec for T: strings, integers and 16 byte arrays get their dedicated codec,
+// anything else falls back to gob
+func Default[T any]() ElementCodec[T] {
+	var zero T
+	var c any
+	switch any(zero).(type) {
+	case string:
+		c = String()
+	case int:
+		c = Int[int]()
+	case int8:
+		c = Int[int8]()
+	case int16:
+		c = Int[int16]()
+	case int32:
+		c = Int[int32]()
+	case int64:
+		c = Int[int64]()
+	case uint:
+		c = Int[uint]()
+	case uint8:
+		c = Int[uint8]()
+	case uint16:
+		c = Int[uint16]()
+	case uint32:
+		c = Int[uint32]()
+	case uint64:
+		c = Int[uint64]()
+	case uintptr:
+		c = Int[uintptr]()
+	case [16]byte:
+		c = UUID()
+	default:
+		c = Gob[T]()
+	}
+	return c.(ElementCodec[T])
+}
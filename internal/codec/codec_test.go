@@ -0,0 +1,133 @@
+package codec_test
+
+import (
+	"bytes"
+	"testing"
+
+	codec "github.com/bjornaer/crdt/internal/codec"
+)
+
+type point struct {
+	X, Y int
+}
+
+func TestString_RoundTrip(t *testing.T) {
+	c := codec.String()
+	for _, value := range []string{"", "item1", "ünïcödé"} {
+		data, err := c.Encode(value)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		got, err := c.Decode(data)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got != value {
+			t.Errorf("Round trip failed, got: %v, expected: %v.", got, value)
+		}
+	}
+}
+
+func TestInt_RoundTrip(t *testing.T) {
+	c := codec.Int[int64]()
+	for _, value := range []int64{-1 << 63, -1, 0, 1, 1<<63 - 1} {
+		data, err := c.Encode(value)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		got, err := c.Decode(data)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got != value {
+			t.Errorf("Round trip failed, got: %v, expected: %v.", got, value)
+		}
+	}
+}
+
+func TestInt_Order(t *testing.T) {
+	c := codec.Int[int]()
+	values := []int{-1000, -1, 0, 1, 1000}
+	for i := 1; i < len(values); i++ {
+		prev, _ := c.Encode(values[i-1])
+		next, _ := c.Encode(values[i])
+		if bytes.Compare(prev, next) >= 0 {
+			t.Errorf("Encoding order mismatch between %v and %v", values[i-1], values[i])
+		}
+	}
+}
+
+func TestInt_Overflow(t *testing.T) {
+	data, _ := codec.Int[int64]().Encode(1000)
+	_, err := codec.Int[int8]().Decode(data)
+	if err == nil {
+		t.Errorf("Expected overflow error decoding 1000 as int8")
+	}
+	_, err = codec.Int[uint32]().Decode([]byte{1, 2})
+	if err == nil {
+		t.Errorf("Expected error decoding a short input")
+	}
+}
+
+func TestUUID_RoundTrip(t *testing.T) {
+	c := codec.UUID()
+	value := [16]byte{0xde, 0xad, 0xbe, 0xef, 15: 0x01}
+	data, err := c.Encode(value)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	got, err := c.Decode(data)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got != value {
+		t.Errorf("Round trip failed, got: %v, expected: %v.", got, value)
+	}
+	_, err = c.Decode(data[:4])
+	if err == nil {
+		t.Errorf("Expected error decoding a short input")
+	}
+}
+
+func TestGob_RoundTrip(t *testing.T) {
+	c := codec.Gob[point]()
+	value := point{X: 1, Y: -2}
+	data, err := c.Encode(value)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	got, err := c.Decode(data)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got != value {
+		t.Errorf("Round trip failed, got: %v, expected: %v.", got, value)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	s, _ := codec.Default[string]().Encode("item1")
+	if string(s) != "item1" {
+		t.Errorf("Default string codec mismatch, got: %v", s)
+	}
+	i, _ := codec.Default[uint16]().Encode(1)
+	if len(i) != 8 || i[7] != 1 {
+		t.Errorf("Default integer codec mismatch, got: %v", i)
+	}
+	u, _ := codec.Default[[16]byte]().Encode([16]byte{1})
+	if len(u) != 16 || u[0] != 1 {
+		t.Errorf("Default uuid codec mismatch, got: %v", u)
+	}
+	p, err := codec.Default[point]().Decode(mustEncode(t, codec.Gob[point](), point{X: 3}))
+	if err != nil || p.X != 3 {
+		t.Errorf("Default gob codec mismatch, got: %v, err: %v", p, err)
+	}
+}
+
+func mustEncode[T any](t *testing.T, c codec.ElementCodec[T], value T) []byte {
+	data, err := c.Encode(value)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return data
+}
@@ -0,0 +1,32 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// TimeSize is the length of the encoding of a timestamp
+const TimeSize = 12
+
+// EncodeTime stores a timestamp as 8 big-endian bytes of seconds since the Unix epoch followed by 4 of nanoseconds,
+// unlike nanoseconds since the epoch, limited to the years 1678 to 2262, it holds every timestamp including the zero
+// time. The location is not kept, timestamps are decoded in the local one
+func EncodeTime(t time.Time) []byte {
+	data := make([]byte, TimeSize)
+	binary.BigEndian.PutUint64(data, uint64(t.Unix()))
+	binary.BigEndian.PutUint32(data[8:], uint32(t.Nanosecond()))
+	return data
+}
+
+// DecodeTime reads back a timestamp stored by EncodeTime
+func DecodeTime(data []byte) (time.Time, error) {
+	if len(data) != TimeSize {
+		return time.Time{}, fmt.Errorf("cannot decode timestamp, expected %d bytes but got %d", TimeSize, len(data))
+	}
+	nsec := binary.BigEndian.Uint32(data[8:])
+	if nsec >= uint32(time.Second) {
+		return time.Time{}, fmt.Errorf("cannot decode timestamp, %d nanoseconds exceed a second", nsec)
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(data)), int64(nsec)), nil
+}
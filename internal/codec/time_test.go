@@ -0,0 +1,41 @@
+package codec_test
+
+import (
+	"testing"
+	"time"
+
+	codec "github.com/bjornaer/crdt/internal/codec"
+)
+
+// checks timestamps survive the encoding, including the ones nanoseconds since the epoch cannot represent
+func TestTime_RoundTrip(t *testing.T) {
+	for _, value := range []time.Time{
+		{},
+		time.Unix(0, 0),
+		time.Date(1500, 3, 1, 12, 0, 0, 7, time.UTC),
+		time.Date(3000, 1, 1, 0, 0, 0, 999999999, time.UTC),
+		time.Unix(1<<63-1, 0),
+		time.Unix(-1<<63, 0),
+		time.Now(),
+	} {
+		got, err := codec.DecodeTime(codec.EncodeTime(value))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !got.Equal(value) {
+			t.Errorf("Round trip failed, got: %v, expected: %v.", got, value)
+		}
+	}
+}
+
+// checks damaged encodings are refused
+func TestTime_Corrupt(t *testing.T) {
+	if _, err := codec.DecodeTime([]byte{1, 2}); err == nil {
+		t.Errorf("Expected error decoding a short input")
+	}
+	data := codec.EncodeTime(time.Unix(0, 0))
+	data[8] = 0xff
+	if _, err := codec.DecodeTime(data); err == nil {
+		t.Errorf("Expected error decoding nanoseconds exceeding a second")
+	}
+}
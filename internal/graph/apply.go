@@ -0,0 +1,52 @@
+package graph
+
+import (
+	"fmt"
+
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/oplog"
+)
+
+// Apply applies an operation replicated from another replica
+//
+// Applying the same operation more than once has no further effect. Edge operations are applied even if
+// their vertices are not known yet, as the operations adding them may still be on their way
+func (g *LWWGraph[T]) Apply(op oplog.Op[T]) error {
+	switch op.Type {
+	case oplog.AddOp, oplog.RemoveOp:
+		tr := g.track(true)
+		defer tr.done()
+		g.mutex.RLock()
+		defer g.mutex.RUnlock()
+		tr.vertex(op.Element)
+		return g.vertices.Apply(op)
+	case oplog.AddEdgeOp:
+		return g.applyEdge(oplog.AddOp, op)
+	case oplog.RemoveEdgeOp:
+		return g.applyEdge(oplog.RemoveOp, op)
+	}
+	return fmt.Errorf("cannot apply operation to graph, unsupported type: %v", op.Type)
+}
+
+// applyEdge applies an edge operation to the edges sets of both its vertices
+func (g *LWWGraph[T]) applyEdge(t oplog.Type, op oplog.Op[T]) error {
+	tr := g.track(true)
+	defer tr.done()
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	tr.edge(op.Element, op.Target)
+
+	if g.edges == nil {
+		g.edges = make(map[T]set.LastWriterWinsSet[T])
+	}
+	for _, pair := range [][2]T{{op.Element, op.Target}, {op.Target, op.Element}} {
+		if _, ok := g.edges[pair[0]]; !ok {
+			g.edges[pair[0]] = g.newSet()
+		}
+		err := g.edges[pair[0]].Apply(oplog.Op[T]{Type: t, Element: pair[1], Timestamp: op.Timestamp, Origin: op.Origin})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
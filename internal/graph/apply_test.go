@@ -0,0 +1,77 @@
+package graph_test
+
+import (
+	"testing"
+	"time"
+
+	graph "github.com/bjornaer/crdt/internal/graph"
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/oplog"
+)
+
+func TestLWWGraph_WithLog(t *testing.T) {
+	log := oplog.NewLog[string]("replica1")
+	g := graph.NewLWWGraph(set.WithLog(log))
+	g.AddVertex("vertex1")
+	g.AddVertex("vertex2")
+	g.AddEdge("vertex1", "vertex2")
+	g.RemoveEdge("vertex1", "vertex2")
+	g.RemoveVertex("vertex2")
+
+	expected := []oplog.Type{oplog.AddOp, oplog.AddOp, oplog.AddEdgeOp, oplog.RemoveEdgeOp, oplog.RemoveOp}
+	ops := log.Read(0)
+	if len(ops) != len(expected) {
+		t.Fatalf("Log mismatch, got: %v, expected types: %v.", ops, expected)
+	}
+	for i, op := range ops {
+		if op.Type != expected[i] {
+			t.Errorf("Operation type mismatch at %v, got: %v, expected: %v.", i, op.Type, expected[i])
+		}
+	}
+	if ops[2].Element != "vertex1" || ops[2].Target != "vertex2" {
+		t.Errorf("Edge operation mismatch, got: %v", ops[2])
+	}
+}
+
+func TestLWWGraph_Apply(t *testing.T) {
+	log := oplog.NewLog[string]("replica1")
+	g1 := graph.NewLWWGraph(set.WithLog(log))
+	g2 := graph.NewLWWGraph[string]()
+	g1.AddVertex("vertex1")
+	g1.AddVertex("vertex2")
+	g1.AddVertex("vertex3")
+	g1.AddEdge("vertex1", "vertex2")
+	g1.AddEdge("vertex2", "vertex3")
+	g1.RemoveEdge("vertex1", "vertex2")
+
+	// applying the log twice must not change the outcome
+	for i := 0; i < 2; i++ {
+		for _, op := range log.Read(0) {
+			err := g2.Apply(op)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}
+	}
+	vertices, _ := g2.GetAllVertices()
+	expected := []string{"vertex1", "vertex2", "vertex3"}
+	if !setsAreEqual(vertices, expected) {
+		t.Errorf("Vertices mismatch, got: %v, expected: %v.", vertices, expected)
+	}
+	if g2.EdgeExists("vertex1", "vertex2") || !g2.EdgeExists("vertex2", "vertex3") {
+		t.Errorf("Edges mismatch after applying the log")
+	}
+}
+
+func TestLWWGraph_ApplyEdgeBeforeVertices(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	err := g.Apply(oplog.Op[string]{Type: oplog.AddEdgeOp, Element: "vertex1", Target: "vertex2", Timestamp: time.Now()})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	g.AddVertex("vertex1")
+	g.AddVertex("vertex2")
+	if !g.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Edge applied before its vertices was lost")
+	}
+}
@@ -0,0 +1,55 @@
+package graph
+
+import (
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// Compact drops the vertices and edges removed before the given time, along with their additions, and returns
+// the number of dropped entries, see set.LWWSet.Compact for when it is safe. Edges sets left empty are dropped too,
+// the mutations their delta holds are still returned by TakeDelta
+func (g *LWWGraph[T]) Compact(before time.Time) (int, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	dropped, err := g.vertices.Compact(before)
+	if err != nil {
+		return dropped, err
+	}
+	for vertex, edges := range g.edges {
+		n, err := edges.Compact(before)
+		dropped += n
+		if err != nil {
+			return dropped, err
+		}
+		if edges.GetAdditions().Size() > 0 || edges.GetRemovals().Size() > 0 {
+			continue
+		}
+		// an edges set left without entries only held purged ones, but its delta may still hold mutations
+		// to ship, such as the removal of its latest edge
+		if err := g.keepDelta(vertex, edges.TakeDelta()); err != nil {
+			return dropped, err
+		}
+		delete(g.edges, vertex)
+	}
+	return dropped, nil
+}
+
+// keepDelta holds the delta of a dropped edges set until the delta of the graph is taken, the graph lock has to be held
+func (g *LWWGraph[T]) keepDelta(vertex T, delta set.LastWriterWinsSet[T]) error {
+	if delta.GetAdditions().Size() == 0 && delta.GetRemovals().Size() == 0 {
+		return nil
+	}
+	if g.compacted == nil {
+		g.compacted = make(map[T]set.LastWriterWinsSet[T])
+	}
+	if kept, ok := g.compacted[vertex]; ok {
+		joined, err := set.Join(kept, delta)
+		if err != nil {
+			return err
+		}
+		delta = joined
+	}
+	g.compacted[vertex] = delta
+	return nil
+}
@@ -0,0 +1,114 @@
+package graph_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	graph "github.com/bjornaer/crdt/internal/graph"
+)
+
+// checks removed vertices and edges are dropped, and the rest of the graph is left untouched
+func TestLWWGraph_Compact(t *testing.T) {
+	g := setupTestGraph()
+	g.RemoveEdge("vertex2", "vertex3")
+	g.RemoveVertex("vertex3")
+
+	dropped, err := g.Compact(time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// the vertex addition and removal, and both sides of the edge addition and removal
+	if dropped != 6 {
+		t.Errorf("Unexpected number of dropped entries, got: %d, expected: %d.", dropped, 6)
+	}
+	vertices, _ := g.GetAllVertices()
+	if expected := []string{"vertex1", "vertex2"}; !setsAreEqual(vertices, expected) {
+		t.Errorf("Vertices mismatch, got: %v, expected: %v.", vertices, expected)
+	}
+	if !g.EdgeExists("vertex1", "vertex2") || g.EdgeExists("vertex2", "vertex3") {
+		t.Errorf("Edges mismatch after compaction")
+	}
+
+	if dropped, _ := g.Compact(time.Now().Add(time.Second)); dropped != 0 {
+		t.Errorf("Unexpected number of dropped entries, got: %d, expected: %d.", dropped, 0)
+	}
+}
+
+// checks edges sets left empty by a compaction are dropped, and their vertex still has no edges
+func TestLWWGraph_CompactDropsEmptyEdges(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	g.AddVertex("vertex1")
+	g.AddVertex("vertex2")
+	g.AddVertex("vertex3")
+	g.AddEdge("vertex1", "vertex2")
+	g.AddEdge("vertex2", "vertex3")
+	g.RemoveEdge("vertex1", "vertex2")
+
+	if _, err := g.Compact(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m, err := graph.ToProto[string](g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := len(m.GetEdges()); got != 2 {
+		t.Errorf("Unexpected number of edges sets, got: %d, expected: %d.", got, 2)
+	}
+	edges, err := g.GetVertexEdges("vertex1")
+	if err != nil || len(edges) != 0 {
+		t.Errorf("Unexpected edges of vertex1, got: %v (%v), expected none.", edges, err)
+	}
+	path, err := g.FindPath("vertex1", "vertex3")
+	if err != nil || contains(path, "vertex3") {
+		t.Errorf("Unexpected path, got: %v (%v), expected none.", path, err)
+	}
+}
+
+// checks the delta of an edges set a compaction drops is still shipped, so peers learn the removal of its edges
+func TestLWWGraph_CompactKeepsDelta(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	g.AddVertex("vertex1")
+	g.AddVertex("vertex2")
+	g.AddEdge("vertex1", "vertex2")
+	peer := graph.NewLWWGraph[string]()
+	if err := peer.Merge(g.TakeDelta()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	g.RemoveEdge("vertex1", "vertex2")
+	if _, err := g.Compact(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := peer.Merge(g.TakeDelta()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if peer.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Expected the removal of the compacted edge to reach the peer")
+	}
+}
+
+// checks edges are read under the graph lock while compactions drop edges sets, run with -race
+func TestLWWGraph_CompactConcurrentReads(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	g.AddVertex("vertex1")
+	g.AddVertex("vertex2")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			g.AddEdge("vertex1", "vertex2")
+			g.RemoveEdge("vertex1", "vertex2")
+			g.Compact(time.Now().Add(time.Second))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			g.GetVertexEdges("vertex1")
+			g.FindPath("vertex1", "vertex2")
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,67 @@
+package graph
+
+import (
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// TakeDelta returns the delta-state holding every local mutation of the graph since the previous call, and starts a new one
+//
+// A delta is itself a LastWriterWinsGraph made of the deltas of the vertices set and of every edges set,
+// replicas Merge it in place of the full state
+func (g *LWWGraph[T]) TakeDelta() LastWriterWinsGraph[T] {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	delta := &LWWGraph[T]{
+		vertices: g.vertices.TakeDelta(),
+		edges:    make(map[T]set.LastWriterWinsSet[T]),
+		setOpts:  g.setOpts,
+	}
+	for vertex, edges := range g.edges {
+		edgesDelta := edges.TakeDelta()
+		if edgesDelta.GetAdditions().Size() > 0 || edgesDelta.GetRemovals().Size() > 0 {
+			delta.edges[vertex] = edgesDelta
+		}
+	}
+	// deltas of edges sets dropped by Compact come first, the sets created again since only hold later mutations
+	for vertex, kept := range g.compacted {
+		if edgesDelta, ok := delta.edges[vertex]; ok {
+			// deltas are in-memory time maps, joining them never fails
+			kept, _ = set.Join(kept, edgesDelta)
+		}
+		delta.edges[vertex] = kept
+	}
+	g.compacted = nil
+	return delta
+}
+
+// Join returns a new delta-state holding the union of the given graph deltas, leaving them untouched
+func Join[T comparable](deltas ...LastWriterWinsGraph[T]) (LastWriterWinsGraph[T], error) {
+	var opts []set.Option[T]
+	if len(deltas) > 0 {
+		opts = append(opts, set.WithCodec(deltas[0].getV().GetCodec()))
+	}
+	// sets are joined rather than merged, so the joined graph is shipped as is without recording its entries again
+	var vertices []set.LastWriterWinsSet[T]
+	edges := make(map[T][]set.LastWriterWinsSet[T])
+	for _, delta := range deltas {
+		vertices = append(vertices, delta.getV())
+		for v, es := range edgesOf(delta) {
+			edges[v] = append(edges[v], es)
+		}
+	}
+	joined := newLWWGraph(opts...)
+	var err error
+	joined.vertices, err = set.Join(vertices...)
+	if err != nil {
+		return nil, err
+	}
+	joined.edges = make(map[T]set.LastWriterWinsSet[T], len(edges))
+	for v, es := range edges {
+		joined.edges[v], err = set.Join(es...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return joined, nil
+}
@@ -0,0 +1,72 @@
+package graph_test
+
+import (
+	"testing"
+
+	graph "github.com/bjornaer/crdt/internal/graph"
+)
+
+func TestLWWGraph_TakeDelta(t *testing.T) {
+	g1 := setupTestGraph()
+	g2 := graph.NewLWWGraph[string]()
+	err := g2.Merge(g1.TakeDelta())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	g1.AddVertex("vertex4")
+	g1.AddEdge("vertex3", "vertex4")
+	g1.RemoveEdge("vertex1", "vertex2")
+
+	delta := g1.TakeDelta()
+	vertices, _ := delta.GetAllVertices()
+	if !setsAreEqual(vertices, []string{"vertex4"}) {
+		t.Errorf("Delta vertices mismatch, got: %v, expected: %v.", vertices, []string{"vertex4"})
+	}
+	err = g2.Merge(delta)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !g2.EdgeExists("vertex3", "vertex4") || g2.EdgeExists("vertex1", "vertex2") || !g2.EdgeExists("vertex2", "vertex3") {
+		t.Errorf("Edges mismatch after merging deltas")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	g.AddVertex("vertex1")
+	d1 := g.TakeDelta()
+	g.AddVertex("vertex2")
+	g.AddEdge("vertex1", "vertex2")
+	d2 := g.TakeDelta()
+
+	joined, err := graph.Join(d1, d2)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	vertices, _ := joined.GetAllVertices()
+	if !setsAreEqual(vertices, []string{"vertex1", "vertex2"}) || !joined.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Join mismatch, got vertices: %v", vertices)
+	}
+	if d1.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Join modified its input")
+	}
+	// like the joined sets, the joined graph is shipped as is and does not record what it holds
+	delta := joined.TakeDelta()
+	if vertices, _ := delta.GetAllVertices(); len(vertices) != 0 || delta.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Join recorded its entries in the delta, got vertices: %v", vertices)
+	}
+}
+
+func TestLWWGraph_MergeIntoEmpty(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	other := setupTestGraph()
+	err := g.Merge(other)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	// edges sets must not be shared with the merged graph
+	other.RemoveEdge("vertex1", "vertex2")
+	if !g.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Mutation of the merged graph leaked into the current one")
+	}
+}
@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"errors"
+
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/report"
+)
+
+// Diff compares two graphs without modifying either
+//
+// Graphs written to while compared may be reported in any state they went through
+func Diff[T comparable](a, b LastWriterWinsGraph[T]) (report.GraphDifference[T], error) {
+	var d report.GraphDifference[T]
+	if a == nil || b == nil {
+		return d, errors.New("cannot diff, graph is nil")
+	}
+	var err error
+	d.Vertices, err = set.Diff(a.getV(), b.getV())
+	if err != nil {
+		return d, err
+	}
+
+	edgesA, edgesB := edgesOf(a), edgesOf(b)
+	empty := set.NewLWWSet[T]()
+	for _, v := range vertices(edgesA, edgesB) {
+		ea, ok := edgesA[v]
+		if !ok {
+			ea = empty
+		}
+		eb, ok := edgesB[v]
+		if !ok {
+			eb = empty
+		}
+		diff, err := set.Diff(ea, eb)
+		if err != nil {
+			return d, err
+		}
+		if diff.Equal() {
+			continue
+		}
+		if d.Edges == nil {
+			d.Edges = make(map[T]report.Difference[T])
+		}
+		d.Edges[v] = diff
+	}
+
+	d.OnlyA, err = onlyEdges(edgesA, edgesB)
+	if err != nil {
+		return d, err
+	}
+	d.OnlyB, err = onlyEdges(edgesB, edgesA)
+	return d, err
+}
+
+// vertices returns the vertices holding an edges set in either graph
+func vertices[T comparable](a, b map[T]set.LastWriterWinsSet[T]) []T {
+	var all []T
+	for v := range a {
+		all = append(all, v)
+	}
+	for v := range b {
+		if _, ok := a[v]; !ok {
+			all = append(all, v)
+		}
+	}
+	return all
+}
+
+// present checks if two vertices share an edge in the given edges sets
+func present[T comparable](edges map[T]set.LastWriterWinsSet[T], v1, v2 T) bool {
+	edges1, ok1 := edges[v1]
+	edges2, ok2 := edges[v2]
+	return ok1 && ok2 && edges1.Exists(v2) && edges2.Exists(v1)
+}
+
+// onlyEdges returns the edges present in the first edges sets and not in the second ones, once per edge
+func onlyEdges[T comparable](from, other map[T]set.LastWriterWinsSet[T]) ([][2]T, error) {
+	var only [][2]T
+	seen := make(map[[2]T]bool)
+	for v1, es := range from {
+		targets, err := es.Get()
+		if err != nil {
+			return nil, err
+		}
+		for _, v2 := range targets {
+			if seen[[2]T{v2, v1}] || !present(from, v1, v2) || present(other, v1, v2) {
+				continue
+			}
+			seen[[2]T{v1, v2}] = true
+			only = append(only, [2]T{v1, v2})
+		}
+	}
+	return only, nil
+}
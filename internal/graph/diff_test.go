@@ -0,0 +1,38 @@
+package graph_test
+
+import (
+	"testing"
+
+	graph "github.com/bjornaer/crdt/internal/graph"
+)
+
+// checks diffs list the vertices and edges present on one side only
+func TestDiff(t *testing.T) {
+	a := setupTestGraph()
+	b := graph.NewLWWGraph[string]()
+	if err := b.Merge(a); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	a.AddEdge("vertex1", "vertex3")
+	b.AddVertex("vertex4")
+
+	d, err := graph.Diff(a, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(d.Vertices.OnlyA) != 0 || !setsAreEqual(d.Vertices.OnlyB, []string{"vertex4"}) {
+		t.Errorf("Unexpected vertices, got: %v only in a and %v only in b, expected vertex4 only in b.", d.Vertices.OnlyA, d.Vertices.OnlyB)
+	}
+	if len(d.OnlyA) != 1 || len(d.OnlyB) != 0 {
+		t.Errorf("Unexpected edges, got: %v only in a and %v only in b, expected one only in a.", d.OnlyA, d.OnlyB)
+	}
+	if len(d.Edges) != 2 {
+		t.Errorf("Unexpected edges sets, got: %v, expected the ones of vertex1 and vertex3.", d.Edges)
+	}
+	if b.EdgeExists("vertex1", "vertex3") {
+		t.Errorf("Expected the compared graphs not to be modified")
+	}
+	if d, _ := graph.Diff(b, b); !d.Equal() {
+		t.Errorf("Expected a graph not to diverge from itself, got: %v.", d)
+	}
+}
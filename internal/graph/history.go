@@ -0,0 +1,31 @@
+package graph
+
+import "time"
+
+// VertexExistsAt checks if a vertex was in the graph at a given instant, according to the entries known by now
+//
+// It returns set.ErrNoHistory unless the graph was built WithHistory
+func (g *LWWGraph[T]) VertexExistsAt(v T, t time.Time) (bool, error) {
+	return g.vertices.ExistsAt(v, t)
+}
+
+// EdgeExistsAt checks if two vertices shared an edge at a given instant, according to the entries known by now
+//
+// It returns set.ErrNoHistory unless the graph was built WithHistory
+func (g *LWWGraph[T]) EdgeExistsAt(v1, v2 T, t time.Time) (bool, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	edges1, ok1 := g.edges[v1]
+	edges2, ok2 := g.edges[v2]
+	if !ok1 || !ok2 {
+		// the vertices set tells whether the graph retains history at all
+		_, err := g.vertices.ExistsAt(v1, t)
+		return false, err
+	}
+	exists, err := edges1.ExistsAt(v2, t)
+	if err != nil || !exists {
+		return false, err
+	}
+	return edges2.ExistsAt(v1, t)
+}
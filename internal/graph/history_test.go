@@ -0,0 +1,42 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	graph "github.com/bjornaer/crdt/internal/graph"
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// checks vertices and edges are evaluated as of past instants
+func TestLWWGraph_ExistsAt(t *testing.T) {
+	g := graph.NewLWWGraph(set.WithHistory[string]())
+	g.AddVertex("vertex1")
+	g.AddVertex("vertex2")
+	g.AddEdge("vertex1", "vertex2")
+	connected := time.Now()
+	time.Sleep(time.Millisecond)
+	g.RemoveEdge("vertex1", "vertex2")
+	g.RemoveVertex("vertex2")
+
+	if exists, err := g.VertexExistsAt("vertex2", connected); err != nil || !exists {
+		t.Errorf("Expected vertex2 to exist before its removal, got: %v, %v.", exists, err)
+	}
+	if exists, _ := g.VertexExistsAt("vertex2", time.Now()); exists {
+		t.Errorf("Expected vertex2 not to exist after its removal")
+	}
+	if exists, err := g.EdgeExistsAt("vertex2", "vertex1", connected); err != nil || !exists {
+		t.Errorf("Expected the edge to exist before its removal, got: %v, %v.", exists, err)
+	}
+	if exists, _ := g.EdgeExistsAt("vertex1", "vertex2", time.Now()); exists {
+		t.Errorf("Expected the edge not to exist after its removal")
+	}
+	if exists, err := g.EdgeExistsAt("vertex1", "vertex3", connected); err != nil || exists {
+		t.Errorf("Expected an unknown edge not to exist, got: %v, %v.", exists, err)
+	}
+
+	if _, err := setupTestGraph().EdgeExistsAt("vertex1", "vertex3", connected); !errors.Is(err, set.ErrNoHistory) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrNoHistory)
+	}
+}
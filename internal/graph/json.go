@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"encoding/json"
+
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// jsonAdjacency is the JSON representation of the edges set of a single vertex
+// the vertex is stored in its codec encoding, so any vertex type can be serialized
+type jsonAdjacency struct {
+	Vertex []byte          `json:"vertex"`
+	Edges  json.RawMessage `json:"edges"`
+}
+
+type jsonGraph struct {
+	Vertices json.RawMessage `json:"vertices"`
+	Edges    []jsonAdjacency `json:"edges"`
+}
+
+// MarshalJSON serializes the graph, encoding its vertices with the codec of the graph sets
+func (g *LWWGraph[T]) MarshalJSON() ([]byte, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	var err error
+	var raw jsonGraph
+	raw.Vertices, err = json.Marshal(g.vertices)
+	if err != nil {
+		return nil, err
+	}
+	c := g.vertices.GetCodec()
+	raw.Edges = make([]jsonAdjacency, 0, len(g.edges))
+	for vertex, edges := range g.edges {
+		var adjacency jsonAdjacency
+		adjacency.Vertex, err = c.Encode(vertex)
+		if err != nil {
+			return nil, err
+		}
+		adjacency.Edges, err = json.Marshal(edges)
+		if err != nil {
+			return nil, err
+		}
+		raw.Edges = append(raw.Edges, adjacency)
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON merges a serialized graph into the current one like Merge, decoding its vertices with the codec
+// of the graph sets
+func (g *LWWGraph[T]) UnmarshalJSON(data []byte) error {
+	var raw jsonGraph
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	g.mutex.Lock()
+	if g.vertices == nil {
+		g.vertices = set.NewLWWSet(g.setOpts...)
+	}
+	if g.edges == nil {
+		g.edges = make(map[T]set.LastWriterWinsSet[T])
+	}
+	decoded := newLWWGraph(g.setOpts...)
+	g.mutex.Unlock()
+
+	// the serialized graph is decoded apart and merged, so it is recorded in the delta and observed as any other
+	err = json.Unmarshal(raw.Vertices, decoded.vertices)
+	if err != nil {
+		return err
+	}
+	decoded.edges = make(map[T]set.LastWriterWinsSet[T])
+	c := decoded.vertices.GetCodec()
+	for _, adjacency := range raw.Edges {
+		vertex, err := c.Decode(adjacency.Vertex)
+		if err != nil {
+			return err
+		}
+		if _, ok := decoded.edges[vertex]; !ok {
+			decoded.edges[vertex] = decoded.newSet()
+		}
+		err = json.Unmarshal(adjacency.Edges, decoded.edges[vertex])
+		if err != nil {
+			return err
+		}
+	}
+	return g.Merge(decoded)
+}
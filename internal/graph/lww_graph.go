@@ -3,10 +3,14 @@ package graph
 import (
 	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
+	"github.com/bjornaer/crdt/event"
 	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/oplog"
+	"github.com/bjornaer/crdt/report"
 )
 
 type LastWriterWinsGraph[T comparable] interface {
@@ -17,25 +21,72 @@ type LastWriterWinsGraph[T comparable] interface {
 	AddEdge(v1, v2 T) error
 	RemoveEdge(v1, v2 T) error
 	EdgeExists(v1, v2 T) bool
+	VertexExistsAt(T, time.Time) (bool, error)
+	EdgeExistsAt(v1, v2 T, t time.Time) (bool, error)
 	GetVertexEdges(v T) ([]T, error)
 	FindPath(v1, v2 T) ([]T, error)
 	Merge(LastWriterWinsGraph[T]) error
+	MergeWithReport(LastWriterWinsGraph[T]) (report.GraphMerge[T], error)
+	TakeDelta() LastWriterWinsGraph[T]
+	Apply(oplog.Op[T]) error
+	Snapshot(io.Writer) error
+	Restore(io.Reader) error
+	Compact(time.Time) (int, error)
+	OnChange(func(event.Event[T])) func()
 	getV() set.LastWriterWinsSet[T]
 	getE() map[T]set.LastWriterWinsSet[T]
 }
 
 // LWWGraph is a structure for a graph with vertices and edges based on LWW sets
 type LWWGraph[T comparable] struct {
-	vertices set.LastWriterWinsSet[T]
-	edges    map[T]set.LastWriterWinsSet[T]
-	mutex    sync.RWMutex // Maps in Go are not thread safe by default and that's why we use a mutex
+	vertices  set.LastWriterWinsSet[T]
+	edges     map[T]set.LastWriterWinsSet[T]
+	setOpts   []set.Option[T]                // options used for the vertices set, and for every edges set through edgesOpts
+	log       *oplog.Log[T]                  // records local mutations when set
+	mutex     sync.RWMutex                   // Maps in Go are not thread safe by default and that's why we use a mutex
+	observers set.Observers[T]               // functions registered with OnChange
+	compacted map[T]set.LastWriterWinsSet[T] // deltas of the edges sets Compact dropped, until taken by TakeDelta
 }
 
 // NewLWWGraph returns an empty LWW based LWWGraph
-func NewLWWGraph[T comparable]() LastWriterWinsGraph[T] {
-	return &LWWGraph[T]{
-		vertices: set.NewLWWSet[T](),
+//
+// The given set options apply to the vertices set as well as to every edges set of the graph,
+// except for the operation log which records graph operations rather than the ones of each set,
+// for digests which are not maintained for graphs, for time sets which belong to a single set,
+// and for shards which only split the vertices set, see set.WithShards
+func NewLWWGraph[T comparable](opts ...set.Option[T]) LastWriterWinsGraph[T] {
+	return newLWWGraph(opts...)
+}
+
+func newLWWGraph[T comparable](opts ...set.Option[T]) *LWWGraph[T] {
+	// the graph records its own operations, and a digest per edges set would cost more than it saves
+	graphOnly := func(cfg *set.Config[T]) {
+		cfg.Log = nil
+		cfg.Digest = false
+		cfg.Additions = nil
+		cfg.Removals = nil
+	}
+	setOpts := append(append([]set.Option[T]{}, opts...), graphOnly)
+	g := &LWWGraph[T]{
+		setOpts: setOpts,
+		log:     set.NewConfig(opts...).Log,
+	}
+	g.vertices = set.NewLWWSet(g.setOpts...)
+	return g
+}
+
+// newSet returns an empty edges set configured like the rest of the graph edges sets
+func (g *LWWGraph[T]) newSet() set.LastWriterWinsSet[T] {
+	return set.NewLWWSet(g.edgesOpts()...)
+}
+
+// edgesOpts returns the options of the edges sets: the ones of the graph, without shards as edges sets are
+// too many and too small to gain from them
+func (g *LWWGraph[T]) edgesOpts() []set.Option[T] {
+	unsharded := func(cfg *set.Config[T]) {
+		cfg.Shards = 0
 	}
+	return append(g.setOpts[:len(g.setOpts):len(g.setOpts)], unsharded)
 }
 
 // access private vertices
@@ -48,9 +99,41 @@ func (g *LWWGraph[T]) getE() map[T]set.LastWriterWinsSet[T] {
 	return g.edges
 }
 
+// edgesOf returns a copy of the edges sets of a graph, taken under its lock when it is an LWWGraph
+func edgesOf[T comparable](g LastWriterWinsGraph[T]) map[T]set.LastWriterWinsSet[T] {
+	if lww, ok := g.(*LWWGraph[T]); ok {
+		lww.mutex.RLock()
+		defer lww.mutex.RUnlock()
+	}
+	edges := make(map[T]set.LastWriterWinsSet[T], len(g.getE()))
+	for v, es := range g.getE() {
+		edges[v] = es
+	}
+	return edges
+}
+
+// record appends a local mutation to the operation log of the graph, if any
+func (g *LWWGraph[T]) record(t oplog.Type, v1, v2 T, at time.Time) {
+	if g.log != nil {
+		g.log.Record(t, v1, v2, at)
+	}
+}
+
 // AddVertex adds a vertex to the graph
 func (g *LWWGraph[T]) AddVertex(v T) error {
-	return g.vertices.Add(v, time.Now())
+	tr := g.track(false)
+	defer tr.done()
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	tr.vertex(v)
+	now := time.Now()
+	err := g.vertices.Add(v, now)
+	if err != nil {
+		return err
+	}
+	var none T
+	g.record(oplog.AddOp, v, none, now)
+	return nil
 }
 
 // GetAllVertices get all vertices from the LWWGraph
@@ -60,7 +143,19 @@ func (g *LWWGraph[T]) GetAllVertices() ([]T, error) {
 
 // RemoveVertex removes a vertex from the LWWGraph
 func (g *LWWGraph[T]) RemoveVertex(v T) error {
-	return g.vertices.Remove(v, time.Now())
+	tr := g.track(false)
+	defer tr.done()
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	tr.vertex(v)
+	now := time.Now()
+	err := g.vertices.Remove(v, now)
+	if err != nil {
+		return err
+	}
+	var none T
+	g.record(oplog.RemoveOp, v, none, now)
+	return nil
 }
 
 // VertexExists checks if a vertex is in the LWWGraph
@@ -70,8 +165,11 @@ func (g *LWWGraph[T]) VertexExists(v T) bool {
 
 // AddEdge adds an edge to the LWWGraph
 func (g *LWWGraph[T]) AddEdge(v1, v2 T) error {
+	tr := g.track(false)
+	defer tr.done()
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
+	tr.edge(v1, v2)
 
 	if !g.vertices.Exists(v1) {
 		return fmt.Errorf("cannot add edge, missing node in graph: %v", v1)
@@ -82,63 +180,88 @@ func (g *LWWGraph[T]) AddEdge(v1, v2 T) error {
 	if g.edges == nil {
 		g.edges = make(map[T]set.LastWriterWinsSet[T])
 	}
+	now := time.Now()
 	if _, ok := g.edges[v1]; !ok {
-		g.edges[v1] = set.NewLWWSet[T]()
+		g.edges[v1] = g.newSet()
 	}
-	err := g.edges[v1].Add(v2, time.Now())
+	err := g.edges[v1].Add(v2, now)
 	if err != nil {
 		return err
 	}
 	if _, ok := g.edges[v2]; !ok {
-		g.edges[v2] = set.NewLWWSet[T]()
+		g.edges[v2] = g.newSet()
 	}
-	err = g.edges[v2].Add(v1, time.Now())
+	err = g.edges[v2].Add(v1, now)
 	if err != nil {
 		return err
 	}
+	g.record(oplog.AddEdgeOp, v1, v2, now)
 	return nil
 }
 
 // RemoveEdge removes an edge from the LWWGraph
 func (g *LWWGraph[T]) RemoveEdge(v1, v2 T) error {
+	tr := g.track(false)
+	defer tr.done()
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
+	tr.edge(v1, v2)
 
 	if g.edges == nil {
 		g.edges = make(map[T]set.LastWriterWinsSet[T])
 	}
+	now := time.Now()
 	if _, ok := g.edges[v1]; !ok {
-		g.edges[v1] = set.NewLWWSet[T]()
+		g.edges[v1] = g.newSet()
 	}
-	err := g.edges[v1].Remove(v2, time.Now())
+	err := g.edges[v1].Remove(v2, now)
 	if err != nil {
 		return err
 	}
 	if _, ok := g.edges[v2]; !ok {
-		g.edges[v2] = set.NewLWWSet[T]()
+		g.edges[v2] = g.newSet()
 	}
-	err = g.edges[v2].Remove(v1, time.Now())
+	err = g.edges[v2].Remove(v1, now)
 	if err != nil {
 		return err
 	}
+	g.record(oplog.RemoveEdgeOp, v1, v2, now)
 	return nil
 }
 
 // EdgeExists checks if two vertices share an edge
 func (g *LWWGraph[T]) EdgeExists(v1, v2 T) bool {
-	return g.edges[v1].Exists(v2) && g.edges[v2].Exists(v1)
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	return g.edgeExists(v1, v2)
+}
+
+// edgeExists checks if two vertices share an edge, the graph lock has to be held
+func (g *LWWGraph[T]) edgeExists(v1, v2 T) bool {
+	return present(g.edges, v1, v2)
 }
 
 // GetVertexEdges allows querying for all vertices connected to a single vertex
 func (g *LWWGraph[T]) GetVertexEdges(v T) ([]T, error) {
-	if !g.VertexExists(v) {
+	// Compact drops edges sets under the write lock
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if !g.vertices.Exists(v) {
 		return nil, errors.New("cannot query for edges, vertex does not exist")
 	}
-	return g.edges[v].Get()
+	// vertices without edges have no edges set, nor do the ones whose edges were all compacted
+	edges, ok := g.edges[v]
+	if !ok {
+		return nil, nil
+	}
+	return edges.Get()
 }
 
 // FindPath finds a connecting path between two given vertices
 func (g *LWWGraph[T]) FindPath(v1, v2 T) ([]T, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
 	if !g.vertices.Exists(v1) {
 		return nil, fmt.Errorf("cannot find path, missing node in graph: %v", v1)
 	} else if !g.vertices.Exists(v2) {
@@ -155,6 +278,7 @@ func (g *LWWGraph[T]) FindPath(v1, v2 T) ([]T, error) {
 	return path, nil
 }
 
+// findPathRecursive walks the edges from v1 depth first until it reaches v2, the graph lock has to be held
 func (g *LWWGraph[T]) findPathRecursive(
 	v1,
 	v2 T,
@@ -170,9 +294,12 @@ func (g *LWWGraph[T]) findPathRecursive(
 		return seen, path, nil
 	}
 
-	edges, err := g.edges[v1].Get()
-	if err != nil {
-		return nil, nil, err
+	var edges []T
+	if es, ok := g.edges[v1]; ok {
+		edges, err = es.Get()
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	for _, vertex := range edges {
@@ -197,19 +324,48 @@ func (g *LWWGraph[T]) Merge(other LastWriterWinsGraph[T]) error {
 		return errors.New("cannot merge, other graph is nil")
 	}
 
-	err := g.vertices.Merge(other.getV())
+	tr := g.track(true)
+	defer tr.done()
+	return g.merge(other, tr, nil)
+}
+
+// merge merges the vertices and edges of another graph, recording the entries it takes in the report r if set
+func (g *LWWGraph[T]) merge(other LastWriterWinsGraph[T], tr *tracker[T], r *report.GraphMerge[T]) error {
+	// the edges sets of other are copied under its own lock, before taking the one of the graph which other may be
+	otherEdges := edgesOf(other)
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	tr.merging(other.getV(), otherEdges)
+	// sets are only asked for reports when the graph is
+	mergeSet := func(to, from set.LastWriterWinsSet[T]) (report.Merge[T], error) {
+		if r == nil {
+			return report.Merge[T]{}, to.Merge(from)
+		}
+		return to.MergeWithReport(from)
+	}
+
+	vertices, err := mergeSet(g.vertices, other.getV())
+	if r != nil {
+		r.Vertices = vertices
+	}
 	if err != nil {
 		return err
 	}
 
-	for otherVertex, otherEdges := range other.getE() {
-		if currentEdges, ok := g.edges[otherVertex]; ok {
-			err = currentEdges.Merge(otherEdges)
-			if err != nil {
-				return err
-			}
-		} else {
-			g.edges[otherVertex] = otherEdges
+	if g.edges == nil {
+		g.edges = make(map[T]set.LastWriterWinsSet[T])
+	}
+	for otherVertex, edgesOfOther := range otherEdges {
+		// edges sets are merged into sets owned by this graph, so later mutations of other do not leak in
+		if _, ok := g.edges[otherVertex]; !ok {
+			g.edges[otherVertex] = g.newSet()
+		}
+		edges, err := mergeSet(g.edges[otherVertex], edgesOfOther)
+		if r != nil {
+			mergedEdges(r, otherVertex, edges)
+		}
+		if err != nil {
+			return err
 		}
 	}
 	return nil
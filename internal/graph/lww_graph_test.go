@@ -4,6 +4,7 @@ package graph_test
 // By doing so, in the tests we will only have access to the public part of our code
 
 import (
+	"encoding/json"
 	"testing"
 
 	graph "github.com/bjornaer/crdt/internal/graph"
@@ -309,3 +310,25 @@ func TestLWWGraph_Idempotence(t *testing.T) {
 		t.Errorf("Merge not idempotent, g1: %v, g1 v g1: %v.", beforeVertices, afterVertices)
 	}
 }
+
+func TestLWWGraph_JSON(t *testing.T) {
+	g := setupTestGraph()
+	g.RemoveEdge("vertex2", "vertex3")
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded := graph.NewLWWGraph[string]()
+	err = json.Unmarshal(data, decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vertices, _ := decoded.GetAllVertices()
+	expected := []string{"vertex1", "vertex2", "vertex3"}
+	if !setsAreEqual(vertices, expected) {
+		t.Errorf("Vertices mismatch, got: %v, expected: %v.", vertices, expected)
+	}
+	if !decoded.EdgeExists("vertex1", "vertex2") || decoded.EdgeExists("vertex2", "vertex3") {
+		t.Errorf("Edges mismatch after JSON round trip")
+	}
+}
@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/bjornaer/crdt/event"
+	backends "github.com/bjornaer/crdt/internal/backends"
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// OnChange registers a function called for every vertex that becomes present or stops being present, and for every
+// edge that appears or disappears, whether by a local mutation, a merge or an applied operation. It returns the
+// function unregistering it
+//
+// The function is called by the goroutine that made the change, once it is applied. A mutation that changes
+// nothing, such as adding a vertex that is already present, is not reported
+func (g *LWWGraph[T]) OnChange(f func(event.Event[T])) func() {
+	return g.observers.Add(f)
+}
+
+// tracker records whether the vertices and edges a change touches were present before it
+type tracker[T comparable] struct {
+	g        *LWWGraph[T]
+	remote   bool
+	observed bool
+	vertices map[T]bool
+	edges    map[[2]T]bool
+	events   []event.Event[T] // changes, once computed
+	computed bool
+}
+
+// track begins a change of the graph, the returned tracker is nil unless it is observed
+func (g *LWWGraph[T]) track(remote bool) *tracker[T] {
+	if !g.observers.Begin() {
+		return nil
+	}
+	tr := g.tracking(remote)
+	tr.observed = true
+	return tr
+}
+
+// tracking returns a tracker of a change, whether it is observed or not
+func (g *LWWGraph[T]) tracking(remote bool) *tracker[T] {
+	return &tracker[T]{g: g, remote: remote, vertices: make(map[T]bool), edges: make(map[[2]T]bool)}
+}
+
+// vertex records whether a vertex is present, before the change modifies its entries
+func (tr *tracker[T]) vertex(v T) {
+	if tr == nil {
+		return
+	}
+	if _, ok := tr.vertices[v]; !ok {
+		tr.vertices[v] = tr.g.vertices.Exists(v)
+	}
+}
+
+// edge records whether an edge is present, before the change modifies its entries, the graph lock has to be held
+func (tr *tracker[T]) edge(v1, v2 T) {
+	if tr == nil {
+		return
+	}
+	_, ok1 := tr.edges[[2]T{v1, v2}]
+	_, ok2 := tr.edges[[2]T{v2, v1}]
+	if !ok1 && !ok2 {
+		tr.edges[[2]T{v1, v2}] = tr.g.edgeExists(v1, v2)
+	}
+}
+
+// merging records whether the vertices and edges of another graph are present, ahead of merging it, given its
+// vertices set and a copy of its edges sets, the graph lock has to be held
+func (tr *tracker[T]) merging(vertices set.LastWriterWinsSet[T], edges map[T]set.LastWriterWinsSet[T]) {
+	if tr == nil {
+		return
+	}
+	each := func(s set.LastWriterWinsSet[T], f func(T)) {
+		for _, entries := range []backends.TimeSet[T]{s.GetAdditions(), s.GetRemovals()} {
+			_ = entries.Each(func(element T, _ time.Time) error {
+				f(element)
+				return nil
+			})
+		}
+	}
+	each(vertices, tr.vertex)
+	for v, targets := range edges {
+		each(targets, func(target T) { tr.edge(v, target) })
+	}
+}
+
+// changes returns an event for every vertex and edge whose presence the change modified, the graph lock must
+// not be held
+func (tr *tracker[T]) changes() []event.Event[T] {
+	if tr.computed {
+		return tr.events
+	}
+	tr.computed = true
+	for v, present := range tr.vertices {
+		if tr.g.vertices.Exists(v) == present {
+			continue
+		}
+		e := event.Event[T]{Type: event.Added, Element: v, Remote: tr.remote}
+		if present {
+			e.Type = event.Removed
+			e.Timestamp, _ = tr.g.vertices.GetRemovals().AddedAt(v)
+		} else {
+			e.Timestamp, _ = tr.g.vertices.GetAdditions().AddedAt(v)
+		}
+		tr.events = append(tr.events, e)
+	}
+
+	tr.g.mutex.RLock()
+	defer tr.g.mutex.RUnlock()
+	for pair, present := range tr.edges {
+		if tr.g.edgeExists(pair[0], pair[1]) == present {
+			continue
+		}
+		e := event.Event[T]{Type: event.EdgeAdded, Element: pair[0], Target: pair[1], Remote: tr.remote}
+		if present {
+			e.Type = event.EdgeRemoved
+		}
+		e.Timestamp = tr.g.edgeStamp(pair[0], pair[1], !present)
+		tr.events = append(tr.events, e)
+	}
+	return tr.events
+}
+
+// done ends the change, once the graph lock was released, reporting the vertices and edges whose presence it
+// changed to the observers
+func (tr *tracker[T]) done() {
+	if tr == nil || !tr.observed {
+		return
+	}
+	tr.g.observers.End(tr.changes())
+}
+
+// edgeStamp returns the latest addition of an edge on either side if present, or its latest removal otherwise
+func (g *LWWGraph[T]) edgeStamp(v1, v2 T, present bool) time.Time {
+	var latest time.Time
+	for _, pair := range [][2]T{{v1, v2}, {v2, v1}} {
+		edges, ok := g.edges[pair[0]]
+		if !ok {
+			continue
+		}
+		entries := edges.GetRemovals()
+		if present {
+			entries = edges.GetAdditions()
+		}
+		if t, ok := entries.AddedAt(pair[1]); ok && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
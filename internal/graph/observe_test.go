@@ -0,0 +1,66 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/bjornaer/crdt/event"
+	graph "github.com/bjornaer/crdt/internal/graph"
+)
+
+// checks vertex and edge mutations are reported when they change the graph
+func TestLWWGraph_OnChange(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	var events []event.Event[string]
+	g.OnChange(func(e event.Event[string]) {
+		events = append(events, e)
+	})
+
+	g.AddVertex("vertex1")
+	g.AddVertex("vertex2")
+	g.AddEdge("vertex1", "vertex2")
+	g.AddEdge("vertex2", "vertex1")
+	g.RemoveEdge("vertex2", "vertex1")
+
+	expected := []event.Event[string]{
+		{Type: event.Added, Element: "vertex1"},
+		{Type: event.Added, Element: "vertex2"},
+		{Type: event.EdgeAdded, Element: "vertex1", Target: "vertex2"},
+		{Type: event.EdgeRemoved, Element: "vertex2", Target: "vertex1"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Unexpected events, got: %v, expected: %v.", events, expected)
+	}
+	for i := range expected {
+		if events[i].Type != expected[i].Type || events[i].Element != expected[i].Element ||
+			events[i].Target != expected[i].Target || events[i].Timestamp.IsZero() {
+			t.Errorf("Unexpected event, got: %v, expected: %v.", events[i], expected[i])
+		}
+	}
+}
+
+// checks merges report the vertices and edges they add as remote changes
+func TestLWWGraph_OnChangeMerge(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	g.AddVertex("vertex1")
+
+	other := graph.NewLWWGraph[string]()
+	other.AddVertex("vertex1")
+	other.AddVertex("vertex2")
+	other.AddEdge("vertex1", "vertex2")
+
+	counts := make(map[event.Type]int)
+	g.OnChange(func(e event.Event[string]) {
+		if !e.Remote {
+			t.Errorf("Expected merged changes to be remote, got: %v.", e)
+		}
+		counts[e.Type]++
+	})
+	if err := g.Merge(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[event.Type]int{event.Added: 1, event.EdgeAdded: 1}
+	if len(counts) != len(expected) || counts[event.Added] != 1 || counts[event.EdgeAdded] != 1 {
+		t.Errorf("Unexpected events, got: %v, expected: %v.", counts, expected)
+	}
+}
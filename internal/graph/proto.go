@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/bjornaer/crdt/crdtpb"
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// ToProto converts a graph to its protobuf message, encoding its vertices with the codec of the graph sets
+// adjacencies are sorted by vertex encoding so the same state always produces the same bytes
+func ToProto[T comparable](g LastWriterWinsGraph[T]) (*crdtpb.LWWGraph, error) {
+	return toProto(g, edgesOf(g))
+}
+
+// toProto converts a graph to its protobuf message given its edges sets, which callers holding the graph lock
+// pass as is
+func toProto[T comparable](g LastWriterWinsGraph[T], edgesSets map[T]set.LastWriterWinsSet[T]) (*crdtpb.LWWGraph, error) {
+	vertices, err := set.ToProto(g.getV())
+	if err != nil {
+		return nil, err
+	}
+	m := &crdtpb.LWWGraph{Vertices: vertices}
+	c := g.getV().GetCodec()
+	for vertex, edges := range edgesSets {
+		data, err := c.Encode(vertex)
+		if err != nil {
+			return nil, err
+		}
+		e, err := set.ToProto(edges)
+		if err != nil {
+			return nil, err
+		}
+		m.Edges = append(m.Edges, &crdtpb.Adjacency{Vertex: data, Edges: e})
+	}
+	sort.Slice(m.Edges, func(i, j int) bool {
+		return bytes.Compare(m.Edges[i].Vertex, m.Edges[j].Vertex) < 0
+	})
+	return m, nil
+}
+
+// FromProto builds a graph out of its protobuf message, the options must provide the codec the message was encoded with
+func FromProto[T comparable](m *crdtpb.LWWGraph, opts ...set.Option[T]) (LastWriterWinsGraph[T], error) {
+	g := newLWWGraph(opts...)
+	vertices, err := set.FromProto(m.GetVertices(), g.setOpts...)
+	if err != nil {
+		return nil, err
+	}
+	g.vertices = vertices
+	g.edges = make(map[T]set.LastWriterWinsSet[T])
+	c := vertices.GetCodec()
+	for _, adjacency := range m.GetEdges() {
+		vertex, err := c.Decode(adjacency.GetVertex())
+		if err != nil {
+			return nil, err
+		}
+		edges, err := set.FromProto(adjacency.GetEdges(), g.edgesOpts()...)
+		if err != nil {
+			return nil, err
+		}
+		if current, ok := g.edges[vertex]; ok {
+			err = current.Merge(edges)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		g.edges[vertex] = edges
+	}
+	return g, nil
+}
+
+// DeltaToProto wraps a graph delta-state in a protobuf delta message
+func DeltaToProto[T comparable](origin string, sequence uint64, delta LastWriterWinsGraph[T]) (*crdtpb.Delta, error) {
+	m, err := ToProto(delta)
+	if err != nil {
+		return nil, err
+	}
+	return &crdtpb.Delta{Origin: origin, Sequence: sequence, State: &crdtpb.Delta_Graph{Graph: m}}, nil
+}
+
+// DeltaFromProto extracts the graph delta-state of a protobuf delta message along with its origin and sequence
+func DeltaFromProto[T comparable](m *crdtpb.Delta, opts ...set.Option[T]) (string, uint64, LastWriterWinsGraph[T], error) {
+	if m.GetGraph() == nil {
+		return "", 0, nil, errors.New("cannot decode delta, it does not hold a graph")
+	}
+	delta, err := FromProto(m.GetGraph(), opts...)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return m.GetOrigin(), m.GetSequence(), delta, nil
+}
@@ -0,0 +1,107 @@
+package graph_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bjornaer/crdt/crdtpb"
+	graph "github.com/bjornaer/crdt/internal/graph"
+	"google.golang.org/protobuf/proto"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// checkGolden compares a message encoding with the content of a golden file, rewriting it when -update is set
+func checkGolden(t *testing.T, name string, m proto.Message) []byte {
+	got, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	path := filepath.Join("testdata", name)
+	if *update {
+		err = os.WriteFile(path, got, 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Encoding of %s does not match its golden file, got: %x, expected: %x.", name, got, expected)
+	}
+	return expected
+}
+
+// graph mutations are stamped with time.Now, so the golden graph is built straight from its wire message
+func goldenGraphMessage() *crdtpb.LWWGraph {
+	entry := func(element string, timestamp int64) *crdtpb.Entry {
+		return &crdtpb.Entry{Element: []byte(element), Timestamp: timestamp}
+	}
+	return &crdtpb.LWWGraph{
+		Vertices: &crdtpb.LWWSet{
+			Additions: []*crdtpb.Entry{entry("vertex1", 100), entry("vertex2", 100), entry("vertex3", 100)},
+			Removals:  []*crdtpb.Entry{entry("vertex3", 200)},
+		},
+		Edges: []*crdtpb.Adjacency{
+			{Vertex: []byte("vertex1"), Edges: &crdtpb.LWWSet{Additions: []*crdtpb.Entry{entry("vertex2", 150)}}},
+			{Vertex: []byte("vertex2"), Edges: &crdtpb.LWWSet{Additions: []*crdtpb.Entry{entry("vertex1", 150)}}},
+		},
+	}
+}
+
+func TestProto_Golden(t *testing.T) {
+	g, err := graph.FromProto[string](goldenGraphMessage())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vertices, _ := g.GetAllVertices()
+	expected := []string{"vertex1", "vertex2"}
+	if !setsAreEqual(vertices, expected) || !g.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Graph mismatch, got vertices: %v, expected: %v.", vertices, expected)
+	}
+
+	m, err := graph.ToProto(g)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data := checkGolden(t, "graph.golden", m)
+
+	var decoded crdtpb.LWWGraph
+	err = proto.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !proto.Equal(&decoded, goldenGraphMessage()) {
+		t.Errorf("Golden graph does not decode to the original message")
+	}
+}
+
+func TestDeltaProto_Golden(t *testing.T) {
+	delta, err := graph.FromProto[string](goldenGraphMessage())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m, err := graph.DeltaToProto("replica1", 3, delta)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data := checkGolden(t, "graph_delta.golden", m)
+
+	var decoded crdtpb.Delta
+	err = proto.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	origin, sequence, got, err := graph.DeltaFromProto[string](&decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if origin != "replica1" || sequence != 3 || !got.EdgeExists("vertex1", "vertex2") {
+		t.Errorf("Delta mismatch, got: %v %v", origin, sequence)
+	}
+}
@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"errors"
+
+	"github.com/bjornaer/crdt/event"
+	"github.com/bjornaer/crdt/report"
+)
+
+// mergedEdges records the report of the edges set of a vertex into a graph merge report, unless the merge took
+// nothing for it
+func mergedEdges[T comparable](r *report.GraphMerge[T], v T, edges report.Merge[T]) {
+	if edges.Additions == 0 && edges.Removals == 0 {
+		return
+	}
+	if r.Edges == nil {
+		r.Edges = make(map[T]report.Merge[T])
+	}
+	r.Edges[v] = edges
+}
+
+// MergeWithReport merges another graph like Merge, and returns a report of what the merge changed
+//
+// Entries merged before an error are reported, as they are kept. Mutations made concurrently by other goroutines
+// may be reported as part of the merge, unless the graph is observed with OnChange, which serializes changes
+func (g *LWWGraph[T]) MergeWithReport(other LastWriterWinsGraph[T]) (report.GraphMerge[T], error) {
+	var r report.GraphMerge[T]
+	if other == nil {
+		return r, errors.New("cannot merge, other graph is nil")
+	}
+	tr := g.track(true)
+	if tr == nil {
+		tr = g.tracking(true)
+	}
+	defer tr.done()
+	err := g.merge(other, tr, &r)
+	for _, e := range tr.changes() {
+		switch e.Type {
+		case event.EdgeAdded:
+			r.AddedEdges = append(r.AddedEdges, [2]T{e.Element, e.Target})
+		case event.EdgeRemoved:
+			r.RemovedEdges = append(r.RemovedEdges, [2]T{e.Element, e.Target})
+		}
+	}
+	return r, err
+}
@@ -0,0 +1,37 @@
+package graph_test
+
+import (
+	"testing"
+
+	graph "github.com/bjornaer/crdt/internal/graph"
+)
+
+// checks merge reports list the vertices and edges made present
+func TestLWWGraph_MergeWithReport(t *testing.T) {
+	g := graph.NewLWWGraph[string]()
+	g.AddVertex("vertex1")
+
+	other := graph.NewLWWGraph[string]()
+	other.AddVertex("vertex1")
+	other.AddVertex("vertex2")
+	other.AddEdge("vertex1", "vertex2")
+
+	report, err := g.MergeWithReport(other)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !setsAreEqual(report.Vertices.Added, []string{"vertex2"}) {
+		t.Errorf("Unexpected added vertices, got: %v, expected: %v.", report.Vertices.Added, []string{"vertex2"})
+	}
+	if len(report.AddedEdges) != 1 || len(report.RemovedEdges) != 0 {
+		t.Errorf("Unexpected edges, got: %v added and %v removed, expected one added.", report.AddedEdges, report.RemovedEdges)
+	}
+	if len(report.Edges) != 2 || report.Overwritten() != 1 {
+		t.Errorf("Unexpected entries, got: %v edges sets and %v overwritten, expected: 2 and 1.", len(report.Edges), report.Overwritten())
+	}
+
+	report, err = g.MergeWithReport(other)
+	if err != nil || report.Changed() || len(report.Edges) != 0 {
+		t.Errorf("Expected merging again to change nothing, got: %v, %v.", report, err)
+	}
+}
@@ -0,0 +1,37 @@
+package graph
+
+import (
+	"io"
+
+	"github.com/bjornaer/crdt/crdtpb"
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// Snapshot writes the state of the graph, see set.WriteSnapshot for the format
+//
+// Writes wait while the state is copied (vertex writes hold the graph lock shared for that purpose),
+// so the snapshot holds the graph as it was at a single point in time
+func (g *LWWGraph[T]) Snapshot(w io.Writer) error {
+	g.mutex.Lock()
+	m, err := toProto[T](g, g.edges)
+	g.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return set.WriteSnapshot(w, set.GraphSnapshot, m)
+}
+
+// Restore merges a snapshot written by Snapshot into the graph, restoring into an empty graph recovers the snapshot state
+//
+// Vertices are decoded with the options of the graph, which must hold the codec of the snapshot graph
+func (g *LWWGraph[T]) Restore(r io.Reader) error {
+	m := &crdtpb.LWWGraph{}
+	if err := set.ReadSnapshot(r, set.GraphSnapshot, m); err != nil {
+		return err
+	}
+	restored, err := FromProto(m, g.setOpts...)
+	if err != nil {
+		return err
+	}
+	return g.Merge(restored)
+}
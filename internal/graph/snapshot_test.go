@@ -0,0 +1,47 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	graph "github.com/bjornaer/crdt/internal/graph"
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+func TestLWWGraph_Snapshot(t *testing.T) {
+	g := setupTestGraph()
+	g.RemoveEdge("vertex2", "vertex3")
+	var buf bytes.Buffer
+	if err := g.Snapshot(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restored := graph.NewLWWGraph[string]()
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vertices, _ := restored.GetAllVertices()
+	expected := []string{"vertex1", "vertex2", "vertex3"}
+	if !setsAreEqual(vertices, expected) {
+		t.Errorf("Vertices mismatch, got: %v, expected: %v.", vertices, expected)
+	}
+	if !restored.EdgeExists("vertex1", "vertex2") || restored.EdgeExists("vertex2", "vertex3") {
+		t.Errorf("Edges mismatch after snapshot round trip")
+	}
+}
+
+// checks a set snapshot is not restored into a graph
+func TestLWWGraph_RestoreSetSnapshot(t *testing.T) {
+	s := set.NewLWWSet[string]()
+	s.Add("vertex1", time.Now())
+	var buf bytes.Buffer
+	if err := s.Snapshot(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := graph.NewLWWGraph[string]().Restore(&buf)
+	if !errors.Is(err, set.ErrInvalidSnapshot) {
+		t.Errorf("Unexpected error restoring a set snapshot, got: %v, expected: %v.", err, set.ErrInvalidSnapshot)
+	}
+}
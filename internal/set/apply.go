@@ -0,0 +1,38 @@
+package set
+
+import (
+	"fmt"
+
+	"github.com/bjornaer/crdt/oplog"
+)
+
+// Apply applies an operation replicated from another replica
+//
+// Applying the same operation more than once has no further effect. Unlike merged entries, applied operations
+// are neither recorded in the delta nor in the operation log of the set. Operations stamped before the garbage
+// collection horizon are filtered like merged entries, see Collect
+func (s *LWWSet[T]) Apply(op oplog.Op[T]) error {
+	tr := s.track(true)
+	defer tr.done()
+	sh := s.shardOf(op.Element)
+	sh.writes.RLock()
+	defer sh.writes.RUnlock()
+	tr.touch(op.Element)
+	before := op.Timestamp.Before(s.horizon)
+	switch op.Type {
+	case oplog.AddOp:
+		if before {
+			if !s.known(op.Element, op.Timestamp) {
+				return s.staleError(op.Element, op.Timestamp)
+			}
+			return nil
+		}
+		return s.Additions.Add(op.Element, op.Timestamp)
+	case oplog.RemoveOp:
+		if before && !s.removes(op.Element, op.Timestamp) {
+			return nil
+		}
+		return s.Removals.Add(op.Element, op.Timestamp)
+	}
+	return fmt.Errorf("cannot apply operation to set, unsupported type: %v", op.Type)
+}
@@ -0,0 +1,78 @@
+package set_test
+
+import (
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/oplog"
+)
+
+func TestLWWSet_WithLog(t *testing.T) {
+	log := oplog.NewLog[string]("replica1")
+	s := set.NewLWWSet(set.WithLog(log))
+	s.Add("item1", time.Now())
+	s.Remove("item1", time.Now())
+	s.Merge(setupTestSet())
+
+	ops := log.Read(0)
+	if len(ops) != 2 || ops[0].Type != oplog.AddOp || ops[1].Type != oplog.RemoveOp {
+		t.Errorf("Log mismatch, got: %v", ops)
+	}
+	if ops[0].Origin != "replica1" || ops[0].Element != "item1" {
+		t.Errorf("Operation mismatch, got: %v", ops[0])
+	}
+}
+
+func TestLWWSet_Apply(t *testing.T) {
+	log := oplog.NewLog[string]("replica1")
+	s1 := set.NewLWWSet(set.WithLog(log))
+	s2 := set.NewLWWSet[string]()
+	s1.Add("item1", time.Now())
+	s1.Add("item2", time.Now())
+
+	// s2 catches up from the start of the log, then again from where it stopped
+	offset := uint64(0)
+	catchUp := func() {
+		ops := log.Read(offset)
+		for _, op := range ops {
+			err := s2.Apply(op)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}
+		offset += uint64(len(ops))
+	}
+	catchUp()
+	s1.Remove("item1", time.Now())
+	catchUp()
+
+	items1, _ := s1.Get()
+	items2, _ := s2.Get()
+	if !setsAreEqual(items1, items2) {
+		t.Errorf("Replicas diverged, s1: %v, s2: %v.", items1, items2)
+	}
+	if log.Len() != 3 {
+		t.Errorf("Applied operations were recorded, got %v operations, expected: %v.", log.Len(), 3)
+	}
+}
+
+func TestLWWSet_ApplyIdempotence(t *testing.T) {
+	s := set.NewLWWSet[string]()
+	now := time.Now()
+	ops := []oplog.Op[string]{
+		{Type: oplog.AddOp, Element: "item1", Timestamp: now},
+		{Type: oplog.RemoveOp, Element: "item1", Timestamp: now.Add(time.Second)},
+	}
+	// replaying the whole history, in any order, leads to the same state
+	for _, op := range append(append(ops, ops[1]), ops...) {
+		s.Apply(op)
+	}
+	if s.Exists("item1") || s.GetAdditions().Size() != 1 || s.GetRemovals().Size() != 1 {
+		t.Errorf("Apply not idempotent")
+	}
+	err := s.Apply(oplog.Op[string]{Type: oplog.AddEdgeOp, Element: "item1", Timestamp: now})
+	if err == nil {
+		t.Errorf("Expected error applying an edge operation to a set")
+	}
+}
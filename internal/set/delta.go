@@ -0,0 +1,77 @@
+package set
+
+import (
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+)
+
+// newDelta returns an empty set sharing the codec of the current one, used to hold delta-states
+func (s *LWWSet[T]) newDelta() *LWWSet[T] {
+	return &LWWSet[T]{
+		Additions: backends.NewTimeSet[T](),
+		Removals:  backends.NewTimeSet[T](),
+		codec:     s.GetCodec(),
+	}
+}
+
+// record applies a local mutation, or a merged entry that won, to the delta of the shard of the element
+func (sh *shard[T]) record(s *LWWSet[T], mutate func(*LWWSet[T]) error) error {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+	if sh.delta == nil {
+		sh.delta = s.newDelta()
+	}
+	return mutate(sh.delta)
+}
+
+// TakeDelta returns the delta-state holding every local Add and Remove since the previous call, along with the
+// merged entries that were new to the set, and starts a new one
+//
+// A delta is itself a LastWriterWinsSet: deltas are joined together with Merge, and shipped to other replicas
+// which Merge them in place of the full state. The delta keeps growing until it is taken, so replicas that
+// only ever exchange full states pay for a copy of their local mutations
+func (s *LWWSet[T]) TakeDelta() LastWriterWinsSet[T] {
+	var taken []*LWWSet[T]
+	s.each(func(sh *shard[T]) {
+		sh.mutex.Lock()
+		if sh.delta != nil {
+			taken = append(taken, sh.delta)
+		}
+		sh.delta = nil
+		sh.mutex.Unlock()
+	})
+	if len(taken) == 0 {
+		return s.newDelta()
+	}
+	// shards hold different elements, so their deltas are simply gathered
+	delta := taken[0]
+	copyInto := func(to backends.TimeSet[T]) func(T, time.Time) error {
+		return func(element T, t time.Time) error {
+			return to.Add(element, t)
+		}
+	}
+	for _, d := range taken[1:] {
+		// deltas are in-memory time maps, which never fail to add an entry
+		_ = d.Additions.Each(copyInto(delta.Additions))
+		_ = d.Removals.Each(copyInto(delta.Removals))
+	}
+	return delta
+}
+
+// Join returns a new delta-state holding the union of the given deltas, leaving them untouched
+func Join[T comparable](deltas ...LastWriterWinsSet[T]) (LastWriterWinsSet[T], error) {
+	var opts []Option[T]
+	if len(deltas) > 0 {
+		opts = append(opts, WithCodec(deltas[0].GetCodec()))
+	}
+	joined := NewLWWSet(opts...).(*LWWSet[T])
+	for _, delta := range deltas {
+		// the joined set is shipped as is, so recording its entries again would only waste memory
+		err := joined.merge(delta, false, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return joined, nil
+}
@@ -0,0 +1,94 @@
+package set_test
+
+import (
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+func TestLWWSet_TakeDelta(t *testing.T) {
+	s := setupTestSet()
+	s.TakeDelta()
+	s.Add("item4", time.Now())
+	s.Remove("item1", time.Now())
+
+	delta := s.TakeDelta()
+	if delta.GetAdditions().Size() != 1 || delta.GetRemovals().Size() != 1 {
+		t.Errorf("Delta size mismatch, got: %v additions and %v removals, expected: 1 and 1.",
+			delta.GetAdditions().Size(), delta.GetRemovals().Size())
+	}
+	if !delta.Exists("item4") {
+		t.Errorf("Missing item in delta: %v", "item4")
+	}
+
+	empty := s.TakeDelta()
+	if empty.GetAdditions().Size() != 0 || empty.GetRemovals().Size() != 0 {
+		t.Errorf("Delta not reset after being taken")
+	}
+}
+
+func TestLWWSet_MergeDelta(t *testing.T) {
+	s1 := setupTestSet()
+	s2 := set.NewLWWSet[string]()
+	err := s2.Merge(s1.TakeDelta())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	s1.Add("item4", time.Now())
+	s1.Remove("item2", time.Now())
+	err = s2.Merge(s1.TakeDelta())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	items1, _ := s1.Get()
+	items2, _ := s2.Get()
+	if !setsAreEqual(items1, items2) {
+		t.Errorf("Replicas diverged after merging deltas, s1: %v, s2: %v.", items1, items2)
+	}
+	// entries new to s2 are relayed through its delta, so replicas s1 does not talk to get them too
+	delta := s2.TakeDelta()
+	relayed, _ := delta.Get()
+	if !setsAreEqual(relayed, items1) {
+		t.Errorf("Unexpected relayed elements, got: %v, expected: %v.", relayed, items1)
+	}
+}
+
+// checks entries that are already known are not relayed again, so relaying between replicas stops
+func TestLWWSet_MergeKnownEntries(t *testing.T) {
+	s1 := setupTestSet()
+	s2 := set.NewLWWSet[string]()
+	s2.Merge(s1)
+	s2.TakeDelta()
+	err := s2.Merge(s1)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	delta := s2.TakeDelta()
+	if delta.GetAdditions().Size() != 0 || delta.GetRemovals().Size() != 0 {
+		t.Errorf("Known entries were recorded in the delta")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	s := set.NewLWWSet[string]()
+	s.Add("item1", time.Now())
+	d1 := s.TakeDelta()
+	s.Add("item2", time.Now())
+	s.Remove("item1", time.Now())
+	d2 := s.TakeDelta()
+
+	joined, err := set.Join(d1, d2)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	items, _ := joined.Get()
+	expected := []string{"item2"}
+	if !setsAreEqual(items, expected) {
+		t.Errorf("Join mismatch, got: %v, expected: %v.", items, expected)
+	}
+	if d1.GetRemovals().Size() != 0 {
+		t.Errorf("Join modified its input")
+	}
+}
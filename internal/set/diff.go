@@ -0,0 +1,72 @@
+package set
+
+import (
+	"errors"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+	"github.com/bjornaer/crdt/report"
+)
+
+// Diff compares two sets without modifying either
+//
+// Winners are the entries a merge keeps regardless of garbage collection horizons, see Collect. Sets written to
+// while compared may be reported in any state they went through
+func Diff[T comparable](a, b LastWriterWinsSet[T]) (report.Difference[T], error) {
+	var d report.Difference[T]
+	if a == nil || b == nil {
+		return d, errors.New("cannot diff, set is nil")
+	}
+	for _, removal := range []bool{false, true} {
+		entriesA, entriesB := a.GetAdditions(), b.GetAdditions()
+		if removal {
+			entriesA, entriesB = a.GetRemovals(), b.GetRemovals()
+		}
+		if err := diverging(&d, entriesA, entriesB, removal); err != nil {
+			return d, err
+		}
+	}
+
+	present := func(from, other LastWriterWinsSet[T], only *[]T) error {
+		elements, err := from.Get()
+		if err != nil {
+			return err
+		}
+		for _, element := range elements {
+			if !other.Exists(element) {
+				*only = append(*only, element)
+			}
+		}
+		return nil
+	}
+	if err := present(a, b, &d.OnlyA); err != nil {
+		return d, err
+	}
+	return d, present(b, a, &d.OnlyB)
+}
+
+// diverging records the entries whose timestamps differ between the time sets of two sets
+func diverging[T comparable](d *report.Difference[T], a, b backends.TimeSet[T], removal bool) error {
+	err := a.Each(func(element T, t time.Time) error {
+		other, _ := b.AddedAt(element)
+		if t.Equal(other) {
+			return nil
+		}
+		winner := report.SideA
+		if other.After(t) {
+			winner = report.SideB
+		}
+		d.Entries = append(d.Entries, report.Divergence[T]{Element: element, Removal: removal, A: t, B: other, Winner: winner})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return b.Each(func(element T, t time.Time) error {
+		if _, ok := a.AddedAt(element); ok {
+			return nil
+		}
+		d.Entries = append(d.Entries, report.Divergence[T]{Element: element, Removal: removal, B: t, Winner: report.SideB})
+		return nil
+	})
+}
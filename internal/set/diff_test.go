@@ -0,0 +1,62 @@
+package set_test
+
+import (
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/report"
+)
+
+// checks diffs list the elements present on one side, the diverging entries and their winners
+func TestDiff(t *testing.T) {
+	base := time.Now()
+	a := set.NewLWWSet[string]()
+	a.Add("item1", base)
+	a.Add("item2", base)
+	a.Add("item3", base)
+
+	b := set.NewLWWSet[string]()
+	b.Add("item1", base)
+	b.Add("item2", base.Add(time.Second))
+	b.Remove("item3", base.Add(time.Second))
+	b.Add("item4", base)
+
+	d, err := set.Diff[string](a, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !setsAreEqual(d.OnlyA, []string{"item3"}) {
+		t.Errorf("Unexpected elements only in a, got: %v, expected: %v.", d.OnlyA, []string{"item3"})
+	}
+	if !setsAreEqual(d.OnlyB, []string{"item4"}) {
+		t.Errorf("Unexpected elements only in b, got: %v, expected: %v.", d.OnlyB, []string{"item4"})
+	}
+
+	expected := map[string]report.Divergence[string]{
+		"item2":         {Element: "item2", A: base, B: base.Add(time.Second), Winner: report.SideB},
+		"item3":         {Element: "item3", A: base, Winner: report.SideA},
+		"item3 removed": {Element: "item3", Removal: true, B: base.Add(time.Second), Winner: report.SideB},
+		"item4":         {Element: "item4", B: base, Winner: report.SideB},
+	}
+	if len(d.Entries) != len(expected) {
+		t.Fatalf("Unexpected entries, got: %v, expected: %v.", d.Entries, expected)
+	}
+	for _, e := range d.Entries {
+		key := e.Element
+		if e.Removal {
+			key += " removed"
+		}
+		x := expected[key]
+		if e.Element != x.Element || e.Removal != x.Removal || !e.A.Equal(x.A) || !e.B.Equal(x.B) || e.Winner != x.Winner {
+			t.Errorf("Unexpected entry, got: %v, expected: %v.", e, x)
+		}
+	}
+
+	if !a.Exists("item3") || a.Exists("item4") || b.Exists("item3") {
+		t.Errorf("Expected the compared sets not to be modified")
+	}
+	if d, _ := set.Diff[string](a, a); !d.Equal() {
+		t.Errorf("Expected a set not to diverge from itself, got: %v.", d)
+	}
+}
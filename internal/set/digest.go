@@ -0,0 +1,65 @@
+package set
+
+import (
+	"errors"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+	"github.com/bjornaer/crdt/merkle"
+)
+
+// tags telling apart additions and removals in the digest tree
+const (
+	additionsTag byte = iota
+	removalsTag
+)
+
+// enableDigest decorates both time sets so they keep a shared Merkle tree up to date
+func (s *LWWSet[T]) enableDigest() {
+	s.digest = merkle.NewTree(merkle.DefaultFanout, merkle.DefaultDepth)
+	// the time sets are still empty at construction time, so decorating them cannot fail
+	s.Additions, _ = backends.NewMerkleTimeSet(s.Additions, s.digest, additionsTag, s.GetCodec())
+	s.Removals, _ = backends.NewMerkleTimeSet(s.Removals, s.digest, removalsTag, s.GetCodec())
+}
+
+// Digest returns the Merkle tree summarizing the additions and removals of the set, or nil unless the set
+// was built WithDigest
+//
+// Two replicas compare their digests with merkle.Diff, and exchange the entries of the differing leaves
+// through ExtractBuckets instead of their whole state
+func (s *LWWSet[T]) Digest() *merkle.Tree {
+	return s.digest
+}
+
+// ExtractBuckets returns a delta-state holding the entries of the given leaves of the digest tree
+func (s *LWWSet[T]) ExtractBuckets(leaves []int) (LastWriterWinsSet[T], error) {
+	if s.digest == nil {
+		return nil, errors.New("cannot extract buckets, the set does not maintain a digest")
+	}
+	wanted := make(map[int]bool, len(leaves))
+	for _, leaf := range leaves {
+		wanted[leaf] = true
+	}
+	extracted := s.newDelta()
+	extract := func(from, to backends.TimeSet[T]) error {
+		return from.Each(func(element T, addedAt time.Time) error {
+			key, err := s.GetCodec().Encode(element)
+			if err != nil {
+				return err
+			}
+			if !wanted[s.digest.Leaf(key)] {
+				return nil
+			}
+			return to.Add(element, addedAt)
+		})
+	}
+	err := extract(s.Additions, extracted.Additions)
+	if err != nil {
+		return nil, err
+	}
+	err = extract(s.Removals, extracted.Removals)
+	if err != nil {
+		return nil, err
+	}
+	return extracted, nil
+}
@@ -0,0 +1,92 @@
+package set_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/merkle"
+)
+
+func TestLWWSet_Digest(t *testing.T) {
+	now := time.Now()
+	s1 := set.NewLWWSet(set.WithDigest[string]())
+	s2 := set.NewLWWSet(set.WithDigest[string]())
+	for i := 0; i < 1000; i++ {
+		item := fmt.Sprintf("item%d", i)
+		s1.Add(item, now)
+		s2.Add(item, now)
+	}
+	r1, _ := s1.Digest().Root()
+	r2, _ := s2.Digest().Root()
+	if r1 != r2 {
+		t.Errorf("Digests differ for equal sets")
+	}
+
+	s1.Add("only_in_s1", now)
+	s2.Remove("item3", now.Add(time.Second))
+	s2.Add("item4", now.Add(time.Second))
+
+	leaves, err := merkle.Diff(s1.Digest(), s2.Digest())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(leaves) == 0 || len(leaves) > 3 {
+		t.Errorf("Unexpected number of differing leaves, got: %v, expected between 1 and 3.", len(leaves))
+	}
+
+	d1, err := s1.ExtractBuckets(leaves)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	d2, err := s2.ExtractBuckets(leaves)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// only the few elements sharing the differing buckets are exchanged, not the whole sets
+	if d1.GetAdditions().Size() > 10 || d2.GetAdditions().Size() > 10 {
+		t.Errorf("Too many entries exchanged, got: %v and %v.", d1.GetAdditions().Size(), d2.GetAdditions().Size())
+	}
+	s1.Merge(d2)
+	s2.Merge(d1)
+
+	r1, _ = s1.Digest().Root()
+	r2, _ = s2.Digest().Root()
+	items1, _ := s1.Get()
+	items2, _ := s2.Get()
+	if r1 != r2 || !setsAreEqual(items1, items2) {
+		t.Errorf("Replicas did not converge after exchanging the differing buckets")
+	}
+}
+
+func TestLWWSet_DigestTracksTimestamps(t *testing.T) {
+	s := set.NewLWWSet(set.WithDigest[string]())
+	s.Add("item1", time.Unix(100, 0))
+	before, _ := s.Digest().Root()
+	s.Add("item1", time.Unix(50, 0))
+	unchanged, _ := s.Digest().Root()
+	s.Add("item1", time.Unix(200, 0))
+	after, _ := s.Digest().Root()
+	if before != unchanged || before == after {
+		t.Errorf("Digest does not follow the effective timestamp of elements")
+	}
+
+	other := set.NewLWWSet(set.WithDigest[string]())
+	other.Add("item1", time.Unix(200, 0))
+	root, _ := other.Digest().Root()
+	if root != after {
+		t.Errorf("Digest depends on the history of the set rather than on its content")
+	}
+}
+
+func TestLWWSet_WithoutDigest(t *testing.T) {
+	s := setupTestSet()
+	if s.Digest() != nil {
+		t.Errorf("Unexpected digest for a set built without one")
+	}
+	_, err := s.ExtractBuckets([]int{0})
+	if err == nil {
+		t.Errorf("Expected error extracting buckets without a digest")
+	}
+}
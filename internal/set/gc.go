@@ -0,0 +1,149 @@
+package set
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+)
+
+// ErrStaleState is returned for entries stamped before the garbage collection horizon of a set that could bring
+// a purged element back, only a replica left behind by the collection can hold them
+var ErrStaleState = errors.New("state older than the garbage collection horizon")
+
+// Collect purges the tombstones stamped before the horizon: an element removed before it, and not added again
+// since its removal, loses both its entries. It returns the number of dropped entries
+//
+// Purging is only safe once every replica observed the tombstones, and none can still send entries stamped before
+// the horizon, which is up to the caller, see Purge for the collection replicators run. The horizon of the
+// set only moves forward, and entries stamped before it are filtered from then on: merged ones the set holds or
+// supersedes are dropped, while merged additions that could bring a purged element back, and local additions
+// and removals, are refused with ErrStaleState. The horizon is only held in memory
+func (s *LWWSet[T]) Collect(horizon time.Time) (int, error) {
+	s.lock()
+	defer s.unlock()
+	if horizon.After(s.horizon) {
+		s.horizon = horizon
+	}
+	return s.compact(s.horizon)
+}
+
+// Compact drops the tombstones stamped before the given time like Collect, and returns the number of dropped entries
+//
+// It is the simpler alternative relying on a retention period rather than on what peers observed: the set keeps
+// merging entries stamped before the time, so an addition reaching it after the removal that superseded it was
+// dropped brings the element back, and replicas should all compact with the same retention, or the ones that did
+// not compact yet send the tombstones again
+func (s *LWWSet[T]) Compact(before time.Time) (int, error) {
+	s.lock()
+	defer s.unlock()
+	return s.compact(before)
+}
+
+// Purge drops the tombstones the given set holds, which replication.WithTombstoneGC gathers from the deltas every
+// peer acknowledged: an element whose removal is still stamped as in the given set, and not added again since, loses
+// both its entries. It returns the number of dropped entries
+//
+// Unlike Collect it leaves the horizon alone, entries of purged elements are merged as any other from then on
+func (s *LWWSet[T]) Purge(tombstones LastWriterWinsSet[T]) (int, error) {
+	s.lock()
+	defer s.unlock()
+	var purged []T
+	err := tombstones.GetRemovals().Each(func(element T, removedAt time.Time) error {
+		if current, ok := s.Removals.AddedAt(element); !ok || !current.Equal(removedAt) {
+			return nil
+		}
+		if addedAt, ok := s.Additions.AddedAt(element); ok && !addedAt.Before(removedAt) {
+			return nil
+		}
+		purged = append(purged, element)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return s.drop(purged)
+}
+
+// compact drops the elements removed before the given time and not added again since, the writes lock has to be held
+func (s *LWWSet[T]) compact(before time.Time) (int, error) {
+	var purged []T
+	err := s.Removals.Each(func(element T, removedAt time.Time) error {
+		if !removedAt.Before(before) {
+			return nil
+		}
+		if addedAt, ok := s.Additions.AddedAt(element); ok && !addedAt.Before(removedAt) {
+			return nil
+		}
+		purged = append(purged, element)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return s.drop(purged)
+}
+
+// drop deletes both entries of the given elements, and returns the number of deleted entries
+func (s *LWWSet[T]) drop(elements []T) (int, error) {
+	dropped := 0
+	for _, element := range elements {
+		if _, ok := s.Additions.AddedAt(element); ok {
+			if err := s.Additions.Delete(element); err != nil {
+				return dropped, err
+			}
+			dropped++
+		}
+		if err := s.Removals.Delete(element); err != nil {
+			return dropped, err
+		}
+		dropped++
+	}
+	return dropped, nil
+}
+
+// Horizon returns the latest horizon the set was collected at, the zero time if it never was
+func (s *LWWSet[T]) Horizon() time.Time {
+	s.rlock()
+	defer s.runlock()
+	return s.horizon
+}
+
+// known checks if an addition stamped before the horizon is accounted for, either because the set holds it
+// or a later one, or because a removal of the set or one of the given removals supersedes it
+func (s *LWWSet[T]) known(value T, t time.Time, removals ...backends.TimeSet[T]) bool {
+	if addedAt, ok := s.Additions.AddedAt(value); ok && !addedAt.Before(t) {
+		return true
+	}
+	for _, r := range append(removals, s.Removals) {
+		if removedAt, ok := r.AddedAt(value); ok && t.Before(removedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// removes checks if a removal stamped before the horizon still removes something from the set
+func (s *LWWSet[T]) removes(value T, t time.Time) bool {
+	addedAt, ok := s.Additions.AddedAt(value)
+	return ok && addedAt.Before(t)
+}
+
+// checkStale returns ErrStaleState if another set holds an addition stamped before the horizon that is not known
+func (s *LWWSet[T]) checkStale(other LastWriterWinsSet[T]) error {
+	if s.horizon.IsZero() {
+		return nil
+	}
+	return other.GetAdditions().Each(func(element T, addedAt time.Time) error {
+		if !addedAt.Before(s.horizon) || s.known(element, addedAt, other.GetRemovals()) {
+			return nil
+		}
+		return s.staleError(element, addedAt)
+	})
+}
+
+// staleError reports an entry refused because it is stamped before the horizon
+func (s *LWWSet[T]) staleError(value T, t time.Time) error {
+	return fmt.Errorf("%w: %v stamped at %v, collected up to %v", ErrStaleState, value, t, s.horizon)
+}
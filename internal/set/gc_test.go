@@ -0,0 +1,167 @@
+package set_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/oplog"
+)
+
+// checks only the elements removed before the horizon, and not added again since, are purged
+func TestLWWSet_Collect(t *testing.T) {
+	base := time.Now()
+	s := set.NewLWWSet[string]()
+	s.Add("kept", base)
+	s.Add("removed", base)
+	s.Remove("removed", base.Add(time.Second))
+	s.Add("readded", base)
+	s.Remove("readded", base.Add(time.Second))
+	s.Add("readded", base.Add(2*time.Second))
+	s.Remove("late", base.Add(time.Minute))
+
+	purged, err := s.Collect(base.Add(30 * time.Second))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("Unexpected number of dropped entries, got: %d, expected: %d.", purged, 2)
+	}
+	if _, ok := s.GetAdditions().AddedAt("removed"); ok {
+		t.Errorf("Expected the addition of a purged element to be dropped")
+	}
+	if size := s.GetRemovals().Size(); size != 2 {
+		t.Errorf("Unexpected number of removals, got: %d, expected: %d.", size, 2)
+	}
+	items, _ := s.Get()
+	if expected := []string{"kept", "readded"}; !setsAreEqual(items, expected) {
+		t.Errorf("Unexpected items, got: %v, expected: %v.", items, expected)
+	}
+}
+
+// collectedSet returns a set where an element was added then removed, and collected
+func collectedSet(t *testing.T, base time.Time) set.LastWriterWinsSet[string] {
+	s := set.NewLWWSet[string]()
+	s.Add("item1", base)
+	s.Add("item2", base)
+	s.Remove("item2", base.Add(time.Second))
+	if _, err := s.Collect(base.Add(time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return s
+}
+
+// checks only the tombstones still stamped as in the given set, and not superseded by an addition, are purged
+func TestLWWSet_Purge(t *testing.T) {
+	base := time.Now()
+	s := set.NewLWWSet[string]()
+	tombstones := set.NewLWWSet[string]()
+	for _, element := range []string{"removed", "removed again", "readded"} {
+		s.Add(element, base)
+		s.Remove(element, base.Add(time.Second))
+		tombstones.Remove(element, base.Add(time.Second))
+	}
+	s.Remove("removed again", base.Add(time.Minute))
+	s.Add("readded", base.Add(time.Minute))
+
+	purged, err := s.Purge(tombstones)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("Unexpected number of dropped entries, got: %d, expected: %d.", purged, 2)
+	}
+	if _, ok := s.GetRemovals().AddedAt("removed"); ok {
+		t.Errorf("Expected the removal of a purged element to be dropped")
+	}
+	if size := s.GetRemovals().Size(); size != 2 {
+		t.Errorf("Unexpected number of removals, got: %d, expected: %d.", size, 2)
+	}
+	if !s.Horizon().IsZero() {
+		t.Errorf("Unexpected horizon, got: %v, expected the zero time.", s.Horizon())
+	}
+}
+
+// checks a replica that did not collect yet merges without bringing tombstones back, and a stale one is refused
+func TestLWWSet_MergeBeforeHorizon(t *testing.T) {
+	base := time.Now()
+	s := collectedSet(t, base)
+
+	other := set.NewLWWSet[string]()
+	other.Add("item2", base)
+	other.Remove("item2", base.Add(time.Second))
+	other.Remove("item1", base.Add(2*time.Second))
+	if err := s.Merge(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Exists("item1") || s.Exists("item2") {
+		t.Errorf("Expected both elements to be removed")
+	}
+	if _, ok := s.GetRemovals().AddedAt("item2"); ok {
+		t.Errorf("Expected the purged tombstone not to be merged again")
+	}
+
+	stale := set.NewLWWSet[string]()
+	stale.Add("item2", base)
+	stale.Add("item3", base.Add(time.Hour))
+	err := s.Merge(stale)
+	if !errors.Is(err, set.ErrStaleState) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrStaleState)
+	}
+	if s.Exists("item2") || s.Exists("item3") {
+		t.Errorf("Expected a stale state to be refused as a whole")
+	}
+}
+
+// checks additions stamped before the horizon are refused, whether local or applied, and so are local removals
+func TestLWWSet_AddBeforeHorizon(t *testing.T) {
+	base := time.Now()
+	s := collectedSet(t, base)
+	if err := s.Add("item2", base.Add(2*time.Second)); !errors.Is(err, set.ErrStaleState) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrStaleState)
+	}
+	if err := s.Remove("item2", base.Add(2*time.Second)); !errors.Is(err, set.ErrStaleState) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrStaleState)
+	}
+	err := s.Apply(oplog.Op[string]{Type: oplog.AddOp, Element: "item2", Timestamp: base})
+	if !errors.Is(err, set.ErrStaleState) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrStaleState)
+	}
+	if err := s.Add("item2", base.Add(time.Hour)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !s.Exists("item2") {
+		t.Errorf("Expected an addition after the horizon to be kept")
+	}
+	if horizon := s.Horizon(); !horizon.Equal(base.Add(time.Minute)) {
+		t.Errorf("Unexpected horizon, got: %v, expected: %v.", horizon, base.Add(time.Minute))
+	}
+}
+
+// checks compaction drops tombstones without refusing older entries merged afterwards
+func TestLWWSet_Compact(t *testing.T) {
+	base := time.Now()
+	s := set.NewLWWSet[string]()
+	s.Add("item1", base)
+	s.Remove("item1", base.Add(time.Second))
+	dropped, err := s.Compact(base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dropped != 2 || s.GetRemovals().Size() != 0 {
+		t.Errorf("Unexpected compaction, got: %d dropped and %d removals left, expected: 2 and 0.", dropped, s.GetRemovals().Size())
+	}
+	if !s.Horizon().IsZero() {
+		t.Errorf("Unexpected horizon after a compaction: %v", s.Horizon())
+	}
+
+	late := set.NewLWWSet[string]()
+	late.Add("item1", base)
+	if err := s.Merge(late); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !s.Exists("item1") {
+		t.Errorf("Expected an addition merged after the compaction to bring the element back")
+	}
+}
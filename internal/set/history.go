@@ -0,0 +1,92 @@
+package set
+
+import (
+	"errors"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+	codec "github.com/bjornaer/crdt/internal/codec"
+)
+
+// ErrNoHistory is returned for queries about the past of a set whose time sets only keep the latest timestamps
+var ErrNoHistory = errors.New("time sets do not retain history")
+
+// WithHistory keeps every timestamp of the elements in memory rather than the latest one only, so the set can be
+// queried as of any past instant, see GetAt. Its time maps are not sharded, WithShards only splits the locks and the
+// delta of the set then, and compaction drops the history of the elements it drops
+func WithHistory[T comparable]() Option[T] {
+	return func(cfg *Config[T]) {
+		cfg.Backend = func(codec.ElementCodec[T]) backends.TimeSet[T] {
+			return backends.NewHistoryTimeMap[T]()
+		}
+	}
+}
+
+// history returns the history of a time set, looking through the decorators wrapping it
+func history[T comparable](ts backends.TimeSet[T]) (backends.History[T], bool) {
+	for {
+		if h, ok := ts.(backends.History[T]); ok {
+			return h, true
+		}
+		wrapper, ok := ts.(interface{ Unwrap() backends.TimeSet[T] })
+		if !ok {
+			return nil, false
+		}
+		ts = wrapper.Unwrap()
+	}
+}
+
+// histories returns the histories of both time sets, or ErrNoHistory if one of them does not retain it
+func (s *LWWSet[T]) histories() (backends.History[T], backends.History[T], error) {
+	additions, ok := history(s.Additions)
+	if !ok {
+		return nil, nil, ErrNoHistory
+	}
+	removals, ok := history(s.Removals)
+	if !ok {
+		return nil, nil, ErrNoHistory
+	}
+	return additions, removals, nil
+}
+
+// existsAt evaluates Exists as of a given instant, only considering the entries stamped until then
+func existsAt[T comparable](additions, removals backends.History[T], value T, t time.Time) bool {
+	addedAt, added := additions.AddedAsOf(value, t)
+	if !added {
+		return false
+	}
+	removedAt, removed := removals.AddedAsOf(value, t)
+	return !removed || !addedAt.Before(removedAt)
+}
+
+// ExistsAt checks if an element was present in the set at a given instant, according to the entries known by now
+//
+// It returns ErrNoHistory unless the set was built WithHistory, or with time sets retaining it
+func (s *LWWSet[T]) ExistsAt(value T, t time.Time) (bool, error) {
+	additions, removals, err := s.histories()
+	if err != nil {
+		return false, err
+	}
+	return existsAt(additions, removals, value, t), nil
+}
+
+// GetAt returns the content of the set at a given instant, according to the entries known by now
+//
+// It returns ErrNoHistory unless the set was built WithHistory, or with time sets retaining it
+func (s *LWWSet[T]) GetAt(t time.Time) ([]T, error) {
+	additions, removals, err := s.histories()
+	if err != nil {
+		return nil, err
+	}
+	var result []T
+	err = s.Additions.Each(func(element T, _ time.Time) error {
+		if existsAt(additions, removals, element, t) {
+			result = append(result, element)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
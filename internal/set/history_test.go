@@ -0,0 +1,56 @@
+package set_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// checks the content of the set is evaluated as of past instants, including entries merged afterwards
+func TestLWWSet_GetAt(t *testing.T) {
+	base := time.Now()
+	s := set.NewLWWSet(set.WithHistory[string](), set.WithDigest[string]())
+	s.Add("item1", base)
+	s.Add("item2", base)
+	s.Remove("item1", base.Add(time.Second))
+	s.Add("item1", base.Add(2*time.Second))
+
+	other := set.NewLWWSet[string]()
+	other.Remove("item2", base.Add(time.Second))
+	if err := s.Merge(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := map[time.Duration][]string{
+		-time.Second:            nil,
+		0:                       {"item1", "item2"},
+		time.Second:             nil,
+		1500 * time.Millisecond: nil,
+		2 * time.Second:         {"item1"},
+	}
+	for at, expected := range cases {
+		items, err := s.GetAt(base.Add(at))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !setsAreEqual(items, expected) {
+			t.Errorf("Unexpected items as of %v, got: %v, expected: %v.", at, items, expected)
+		}
+	}
+	if exists, _ := s.ExistsAt("item1", base.Add(time.Second)); exists {
+		t.Errorf("Expected item1 to be removed as of its removal")
+	}
+}
+
+// checks sets keeping the latest timestamps only refuse queries about the past
+func TestLWWSet_GetAtWithoutHistory(t *testing.T) {
+	s := setupTestSet()
+	if _, err := s.GetAt(time.Now()); !errors.Is(err, set.ErrNoHistory) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrNoHistory)
+	}
+	if _, err := s.ExistsAt("item1", time.Now()); !errors.Is(err, set.ErrNoHistory) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrNoHistory)
+	}
+}
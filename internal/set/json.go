@@ -0,0 +1,95 @@
+package set
+
+import (
+	"encoding/json"
+	"time"
+
+	backends "github.com/bjornaer/crdt/internal/backends"
+	codec "github.com/bjornaer/crdt/internal/codec"
+)
+
+// jsonEntry is the JSON representation of an element/timestamp association
+// the element is stored in its codec encoding, so any element type can be serialized
+type jsonEntry struct {
+	Element   []byte    `json:"element"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type jsonSet struct {
+	Additions []jsonEntry `json:"additions"`
+	Removals  []jsonEntry `json:"removals"`
+}
+
+// encodeEntries serializes every element/timestamp association of a TimeSet through the codec
+func encodeEntries[T comparable](ts backends.TimeSet[T], c codec.ElementCodec[T]) ([]jsonEntry, error) {
+	entries := make([]jsonEntry, 0, ts.Size())
+	err := ts.Each(func(element T, addedAt time.Time) error {
+		data, err := c.Encode(element)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, jsonEntry{Element: data, Timestamp: addedAt})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// decodeEntries adds every serialized element/timestamp association to a TimeSet
+func decodeEntries[T comparable](entries []jsonEntry, ts backends.TimeSet[T], c codec.ElementCodec[T]) error {
+	for _, entry := range entries {
+		element, err := c.Decode(entry.Element)
+		if err != nil {
+			return err
+		}
+		err = ts.Add(element, entry.Timestamp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON serializes the set, encoding its elements with the set codec
+func (s *LWWSet[T]) MarshalJSON() ([]byte, error) {
+	var err error
+	var raw jsonSet
+	raw.Additions, err = encodeEntries(s.Additions, s.GetCodec())
+	if err != nil {
+		return nil, err
+	}
+	raw.Removals, err = encodeEntries(s.Removals, s.GetCodec())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON merges a serialized set into the current one like Merge, decoding its elements with the set codec
+func (s *LWWSet[T]) UnmarshalJSON(data []byte) error {
+	var raw jsonSet
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	if s.Additions == nil {
+		s.Additions = backends.NewTimeSet[T]()
+	}
+	if s.Removals == nil {
+		s.Removals = backends.NewTimeSet[T]()
+	}
+	// the serialized set is decoded apart and merged, so it goes through the horizon, the delta, the digest
+	// and the observers as any other
+	decoded := NewLWWSet(WithCodec(s.GetCodec()))
+	err = decodeEntries(raw.Additions, decoded.GetAdditions(), s.GetCodec())
+	if err != nil {
+		return err
+	}
+	err = decodeEntries(raw.Removals, decoded.GetRemovals(), s.GetCodec())
+	if err != nil {
+		return err
+	}
+	return s.Merge(decoded)
+}
@@ -1,9 +1,15 @@
 package set
 
 import (
+	"io"
 	"time"
 
+	"github.com/bjornaer/crdt/event"
 	backends "github.com/bjornaer/crdt/internal/backends"
+	codec "github.com/bjornaer/crdt/internal/codec"
+	"github.com/bjornaer/crdt/merkle"
+	"github.com/bjornaer/crdt/oplog"
+	"github.com/bjornaer/crdt/report"
 )
 
 type LastWriterWinsSet[T comparable] interface {
@@ -11,16 +17,129 @@ type LastWriterWinsSet[T comparable] interface {
 	Remove(T, time.Time) error
 	Exists(T) bool
 	Get() ([]T, error)
+	ExistsAt(T, time.Time) (bool, error)
+	GetAt(time.Time) ([]T, error)
 	GetRaw() LastWriterWinsSet[T]
 	Merge(LastWriterWinsSet[T]) error
+	MergeWithReport(LastWriterWinsSet[T]) (report.Merge[T], error)
 	GetAdditions() backends.TimeSet[T]
 	GetRemovals() backends.TimeSet[T]
+	GetCodec() codec.ElementCodec[T]
+	TakeDelta() LastWriterWinsSet[T]
+	Apply(oplog.Op[T]) error
+	Digest() *merkle.Tree
+	ExtractBuckets([]int) (LastWriterWinsSet[T], error)
+	Snapshot(io.Writer) error
+	Restore(io.Reader) error
+	Collect(time.Time) (int, error)
+	Purge(LastWriterWinsSet[T]) (int, error)
+	Compact(time.Time) (int, error)
+	Horizon() time.Time
+	OnChange(func(event.Event[T])) func()
 }
 
 // LWWSet is a Last-Writer-Wins Set implementation
 type LWWSet[T comparable] struct {
 	Additions backends.TimeSet[T] `json:"additions"`
 	Removals  backends.TimeSet[T] `json:"removals"`
+	codec     codec.ElementCodec[T]
+	log       *oplog.Log[T]      // records local mutations when set
+	digest    *merkle.Tree       // summarizes both time sets when set
+	single    shard[T]           // lock and delta of every element, unless the set is sharded
+	shards    []shard[T]         // locks and deltas of the elements, split by hash when set, see WithShards
+	hasher    backends.Hasher[T] // assigns elements to shards
+	horizon   time.Time          // tombstones stamped before it were purged, see Collect
+	observers Observers[T]       // functions registered with OnChange
+}
+
+// Config holds the construction options of a LWWSet, graphs share it for their vertices and edges sets
+type Config[T comparable] struct {
+	Codec  codec.ElementCodec[T]
+	Log    *oplog.Log[T]
+	Digest bool
+	// Additions and Removals hold the entries of the set, they are created with Backend when nil
+	Additions backends.TimeSet[T]
+	Removals  backends.TimeSet[T]
+	// Backend creates in-memory time sets for the entries of the set, given the codec of its elements
+	Backend func(codec.ElementCodec[T]) backends.TimeSet[T]
+	// Shards splits the locks and the delta of the set, and its time sets unless a Backend is given, see WithShards
+	Shards int
+}
+
+// Option configures a LWWSet at construction time
+type Option[T comparable] func(*Config[T])
+
+// WithCodec sets the codec used to serialize the elements of the set
+func WithCodec[T comparable](c codec.ElementCodec[T]) Option[T] {
+	return func(cfg *Config[T]) {
+		cfg.Codec = c
+	}
+}
+
+// WithLog records every local Add and Remove of the set in the given operation log
+func WithLog[T comparable](log *oplog.Log[T]) Option[T] {
+	return func(cfg *Config[T]) {
+		cfg.Log = log
+	}
+}
+
+// WithDigest maintains a Merkle tree over the entries of the set, see Digest
+func WithDigest[T comparable]() Option[T] {
+	return func(cfg *Config[T]) {
+		cfg.Digest = true
+	}
+}
+
+// WithTimeSets stores the additions and removals of the set in the given time sets, such as file-backed ones
+//
+// Entries the time sets already hold are part of the set. They belong to a single set, graphs ignore the option
+func WithTimeSets[T comparable](additions, removals backends.TimeSet[T]) Option[T] {
+	return func(cfg *Config[T]) {
+		cfg.Additions = additions
+		cfg.Removals = removals
+	}
+}
+
+// WithShards splits the set in the given number of shards, elements being assigned to them by hash: each shard has
+// a lock and a delta of its own, and a time map of its own for the entries, which lets concurrent writers scale.
+// A number that is not positive picks one according to the CPUs
+//
+// Graphs only shard their vertices set, edges sets are too many and too small to gain from it
+func WithShards[T comparable](shards int) Option[T] {
+	return func(cfg *Config[T]) {
+		if shards <= 0 {
+			shards = backends.DefaultShards()
+		}
+		cfg.Shards = shards
+	}
+}
+
+// NewConfig applies the given options over the defaults
+func NewConfig[T comparable](opts ...Option[T]) Config[T] {
+	var cfg Config[T]
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = codec.Default[T]()
+	}
+	if cfg.Backend == nil && cfg.Shards > 1 {
+		cfg.Backend = func(codec.ElementCodec[T]) backends.TimeSet[T] {
+			return backends.NewShardedTimeMap[T](cfg.Shards)
+		}
+	}
+	if cfg.Backend == nil {
+		cfg.Backend = func(codec.ElementCodec[T]) backends.TimeSet[T] {
+			return backends.NewTimeSet[T]()
+		}
+	}
+	if cfg.Additions == nil {
+		cfg.Additions = cfg.Backend(cfg.Codec)
+	}
+	if cfg.Removals == nil {
+		cfg.Removals = cfg.Backend(cfg.Codec)
+	}
+	return cfg
 }
 
 func (s *LWWSet[T]) GetRaw() LastWriterWinsSet[T] {
@@ -28,8 +147,30 @@ func (s *LWWSet[T]) GetRaw() LastWriterWinsSet[T] {
 }
 
 // Add marks an element to be added at a given timestamp
+//
+// The addition is also recorded in the delta of the set, see TakeDelta, and in its operation log if any.
+// Additions stamped before the garbage collection horizon are refused, see Collect
 func (s *LWWSet[T]) Add(value T, t time.Time) error {
-	return s.Additions.Add(value, t)
+	tr := s.track(false)
+	defer tr.done()
+	sh := s.shardOf(value)
+	sh.writes.RLock()
+	defer sh.writes.RUnlock()
+	tr.touch(value)
+	if t.Before(s.horizon) {
+		return s.staleError(value, t)
+	}
+	err := s.Additions.Add(value, t)
+	if err != nil {
+		return err
+	}
+	if s.log != nil {
+		var none T
+		s.log.Record(oplog.AddOp, value, none, t)
+	}
+	return sh.record(s, func(delta *LWWSet[T]) error {
+		return delta.Additions.Add(value, t)
+	})
 }
 
 func (s *LWWSet[T]) GetAdditions() backends.TimeSet[T] {
@@ -37,14 +178,44 @@ func (s *LWWSet[T]) GetAdditions() backends.TimeSet[T] {
 }
 
 // Remove marks an element to be removed at a given timestamp
+//
+// The removal is also recorded in the delta of the set, see TakeDelta, and in its operation log if any.
+// Like additions, removals stamped before the garbage collection horizon are refused, see Collect
 func (s *LWWSet[T]) Remove(value T, t time.Time) error {
-	return s.Removals.Add(value, t)
+	tr := s.track(false)
+	defer tr.done()
+	sh := s.shardOf(value)
+	sh.writes.RLock()
+	defer sh.writes.RUnlock()
+	tr.touch(value)
+	if t.Before(s.horizon) {
+		return s.staleError(value, t)
+	}
+	err := s.Removals.Add(value, t)
+	if err != nil {
+		return err
+	}
+	if s.log != nil {
+		var none T
+		s.log.Record(oplog.RemoveOp, value, none, t)
+	}
+	return sh.record(s, func(delta *LWWSet[T]) error {
+		return delta.Removals.Add(value, t)
+	})
 }
 
 func (s *LWWSet[T]) GetRemovals() backends.TimeSet[T] {
 	return s.Removals
 }
 
+// GetCodec returns the codec used to serialize the elements of the set
+func (s *LWWSet[T]) GetCodec() codec.ElementCodec[T] {
+	if s.codec == nil {
+		s.codec = codec.Default[T]()
+	}
+	return s.codec
+}
+
 // Exists checks if an element is marked as present in the set
 func (s *LWWSet[T]) Exists(value T) bool {
 	addedAt, added := s.Additions.AddedAt(value)
@@ -88,38 +259,78 @@ func (s *LWWSet[T]) Get() ([]T, error) {
 }
 
 // Merge additions and removals from other LWWSet into current set
+//
+// other can either be the full state of a replica or a delta taken from it, merged entries that win
+// are recorded in the delta of the current set, but not in its operation log. Once the set was collected,
+// a state holding additions the collection may have purged is refused as a whole, see Collect
 func (s *LWWSet[T]) Merge(other LastWriterWinsSet[T]) error {
-	err := other.GetAdditions().Each(func(element T, addedAt time.Time) error {
-		err := s.Add(element, addedAt)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
+	tr := s.track(true)
+	defer tr.done()
+	return s.merge(other, true, tr, nil)
+}
 
-	if err != nil {
+// merge merges the entries of another set, and records the ones that won into the delta if asked to and into
+// the report r if set, the elements it modifies are touched on the tracker of the change
+//
+// Recording them lets delta-states relay changes to the replicas their origin does not talk to, and since
+// entries that are already known do not win, relaying stops once every replica has seen them
+func (s *LWWSet[T]) merge(other LastWriterWinsSet[T], record bool, tr *tracker[T], r *report.Merge[T]) error {
+	s.rlock()
+	defer s.runlock()
+	if err := s.checkStale(other); err != nil {
 		return err
 	}
+	// entries stamped before the horizon are only kept when they still make a difference
+	mergeInto := func(from, to backends.TimeSet[T], removal bool, delta func(*LWWSet[T]) backends.TimeSet[T], keep func(T, time.Time) bool) error {
+		return from.Each(func(element T, addedAt time.Time) error {
+			if addedAt.Before(s.horizon) && !keep(element, addedAt) {
+				return nil
+			}
+			current, ok := to.AddedAt(element)
+			if ok && !addedAt.After(current) {
+				return nil
+			}
+			tr.touch(element)
+			err := to.Add(element, addedAt)
+			if err != nil {
+				return err
+			}
+			merged(r, element, removal, current, ok, addedAt)
+			if !record {
+				return nil
+			}
+			return s.shardOf(element).record(s, func(d *LWWSet[T]) error {
+				return delta(d).Add(element, addedAt)
+			})
+		})
+	}
 
-	err = other.GetRemovals().Each(func(element T, addedAt time.Time) error {
-		err := s.Remove(element, addedAt)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-
+	// additions before the horizon are known at this point, so they never make a difference
+	err := mergeInto(other.GetAdditions(), s.Additions, false, func(d *LWWSet[T]) backends.TimeSet[T] { return d.Additions },
+		func(T, time.Time) bool { return false })
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return mergeInto(other.GetRemovals(), s.Removals, true, func(d *LWWSet[T]) backends.TimeSet[T] { return d.Removals }, s.removes)
 }
 
 // NewLWWSet returns an implementation of a LastWriterWinsSet
-func NewLWWSet[T comparable]() LastWriterWinsSet[T] {
-	return &LWWSet[T]{
-		Additions: backends.NewTimeSet[T](),
-		Removals:  backends.NewTimeSet[T](),
+//
+// Elements are serialized with codec.Default unless another codec is given through WithCodec
+func NewLWWSet[T comparable](opts ...Option[T]) LastWriterWinsSet[T] {
+	cfg := NewConfig(opts...)
+	s := &LWWSet[T]{
+		Additions: cfg.Additions,
+		Removals:  cfg.Removals,
+		codec:     cfg.Codec,
+		log:       cfg.Log,
 	}
+	if cfg.Shards > 1 {
+		s.shards = make([]shard[T], cfg.Shards)
+		s.hasher = backends.NewHasher[T]()
+	}
+	if cfg.Digest {
+		s.enableDigest()
+	}
+	return s
 }
@@ -4,10 +4,18 @@ package set_test
 // By doing so, in the tests we will only have access to the public part of our code
 
 import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
+	crdtbolt "github.com/bjornaer/crdt/bolt"
+	"github.com/bjornaer/crdt/event"
+	backends "github.com/bjornaer/crdt/internal/backends"
+	codec "github.com/bjornaer/crdt/internal/codec"
 	set "github.com/bjornaer/crdt/internal/set"
+	bolt "go.etcd.io/bbolt"
 )
 
 func setupTestSet() set.LastWriterWinsSet[string] {
@@ -202,3 +210,168 @@ func TestLWWSet_Idempotence(t *testing.T) {
 		t.Errorf("Merge not idempotent, g1: %v, g1 v g1: %v.", beforeItems, afterItems)
 	}
 }
+
+type point struct {
+	X, Y int
+}
+
+func TestLWWSet_JSON(t *testing.T) {
+	s := set.NewLWWSet[point]()
+	s.Add(point{1, 2}, time.Now())
+	s.Add(point{3, 4}, time.Now())
+	s.Remove(point{3, 4}, time.Now())
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded := set.NewLWWSet[point]()
+	err = json.Unmarshal(data, decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	items, _ := decoded.Get()
+	expected := []point{{1, 2}}
+	if !setsAreEqual(items, expected) || decoded.GetRemovals().Size() != 1 {
+		t.Errorf("JSON round trip failed, got: %v, expected: %v.", items, expected)
+	}
+}
+
+func TestLWWSet_WithCodec(t *testing.T) {
+	s := set.NewLWWSet(set.WithCodec[int](codec.Gob[int]()))
+	s.Add(42, time.Now())
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// decoding with a different codec must fail, proving the configured one was used
+	err = json.Unmarshal(data, set.NewLWWSet[int]())
+	if err == nil {
+		t.Errorf("Expected error decoding gob elements with the integer codec")
+	}
+	decoded := set.NewLWWSet(set.WithCodec[int](codec.Gob[int]()))
+	err = json.Unmarshal(data, decoded)
+	if err != nil || !decoded.Exists(42) {
+		t.Errorf("JSON round trip with custom codec failed, err: %v", err)
+	}
+}
+
+// checks a decoded set is merged like any other state: observed, recorded in the delta, and refused when stale
+func TestLWWSet_JSONMerges(t *testing.T) {
+	base := time.Now()
+	source := set.NewLWWSet[string]()
+	source.Add("item1", base)
+	data, err := json.Marshal(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s := set.NewLWWSet[string]()
+	var events []event.Event[string]
+	s.OnChange(func(e event.Event[string]) {
+		events = append(events, e)
+	})
+	if err := json.Unmarshal(data, s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != event.Added || !events[0].Remote {
+		t.Errorf("Unexpected events, got: %v, expected a remote addition of item1.", events)
+	}
+	if size := s.TakeDelta().GetAdditions().Size(); size != 1 {
+		t.Errorf("Delta size mismatch, got: %v, expected: %v.", size, 1)
+	}
+
+	stale := set.NewLWWSet[string]()
+	stale.Add("item2", base)
+	data, err = json.Marshal(stale)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	collected := collectedSet(t, base)
+	if err := json.Unmarshal(data, collected); !errors.Is(err, set.ErrStaleState) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, set.ErrStaleState)
+	}
+	if collected.Exists("item2") {
+		t.Errorf("Expected the collected element to stay removed")
+	}
+}
+
+// checks a set stored in file-backed time sets is recovered once they are opened again
+func TestLWWSet_WithTimeSets(t *testing.T) {
+	dir := t.TempDir()
+	open := func() (*backends.FileTimeSet[string], *backends.FileTimeSet[string]) {
+		additions, err := backends.OpenFileTimeSet[string](filepath.Join(dir, "additions"), codec.String())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		removals, err := backends.OpenFileTimeSet[string](filepath.Join(dir, "removals"), codec.String())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return additions, removals
+	}
+
+	additions, removals := open()
+	s := set.NewLWWSet(set.WithCodec(codec.String()), set.WithTimeSets[string](additions, removals))
+	s.Add("item1", time.Now())
+	s.Add("item2", time.Now())
+	s.Remove("item1", time.Now())
+	additions.Close()
+	removals.Close()
+
+	additions, removals = open()
+	defer additions.Close()
+	defer removals.Close()
+	recovered := set.NewLWWSet(set.WithCodec(codec.String()), set.WithTimeSets[string](additions, removals))
+	items, err := recovered.Get()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !setsAreEqual(items, []string{"item2"}) {
+		t.Errorf("Unexpected recovered items, got: %v, expected: %v.", items, []string{"item2"})
+	}
+}
+
+func TestLWWSet_BoltTimeSets(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "set.db"), 0o600, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer db.Close()
+	additions, err := crdtbolt.NewTimeSet[string](db, "additions", codec.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	removals, err := crdtbolt.NewTimeSet[string](db, "removals", codec.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s := set.NewLWWSet(set.WithCodec(codec.String()), set.WithTimeSets[string](additions, removals))
+	other := setupTestSet()
+	other.Remove("item3", time.Now())
+	if err := s.Merge(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	items, err := s.Get()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"item1", "item2"}; !setsAreEqual(items, expected) {
+		t.Errorf("Unexpected items, got: %v, expected: %v.", items, expected)
+	}
+}
+
+func TestLWWSet_WithShards(t *testing.T) {
+	s := set.NewLWWSet(set.WithShards[string](4))
+	if err := s.Merge(setupTestSet()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Remove("item2", time.Now())
+	items, err := s.Get()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"item1", "item3"}; !setsAreEqual(items, expected) {
+		t.Errorf("Unexpected items, got: %v, expected: %v.", items, expected)
+	}
+}
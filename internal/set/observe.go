@@ -0,0 +1,141 @@
+package set
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/bjornaer/crdt/event"
+)
+
+// observer is a function registered with OnChange
+type observer[T comparable] struct {
+	id int
+	f  func(event.Event[T])
+}
+
+// Observers holds the functions observing the changes of a set or a graph
+//
+// Changes made while observed are serialized, so the presence of what they touch can be compared before and after
+// each of them, and every effective change is reported once. Unobserved changes run as usual
+type Observers[T comparable] struct {
+	observers []observer[T]
+	next      int
+	count     int32      // number of observers, read atomically so unobserved changes take no lock to find out
+	mutex     sync.Mutex // guards observers and next
+	changes   sync.Mutex // held from the beginning of an observed change to its end
+}
+
+// Add registers a function called for every change, and returns the function unregistering it
+func (o *Observers[T]) Add(f func(event.Event[T])) func() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	id := o.next
+	o.next++
+	o.observers = append(o.observers, observer[T]{id: id, f: f})
+	atomic.AddInt32(&o.count, 1)
+	return func() {
+		o.mutex.Lock()
+		defer o.mutex.Unlock()
+		for i, obs := range o.observers {
+			if obs.id == id {
+				o.observers = append(o.observers[:i:i], o.observers[i+1:]...)
+				atomic.AddInt32(&o.count, -1)
+				return
+			}
+		}
+	}
+}
+
+// Begin starts a change, and tells whether it is observed. An observed change has to be ended with End
+func (o *Observers[T]) Begin() bool {
+	if atomic.LoadInt32(&o.count) == 0 {
+		return false
+	}
+	o.changes.Lock()
+	return true
+}
+
+// End ends an observed change, and reports the events it produced
+//
+// Observers are called once the change is over, every lock released, so they may read or mutate the CRDT
+func (o *Observers[T]) End(events []event.Event[T]) {
+	o.changes.Unlock()
+	if len(events) == 0 {
+		return
+	}
+	o.mutex.Lock()
+	observers := append([]observer[T]{}, o.observers...)
+	o.mutex.Unlock()
+	for _, e := range events {
+		for _, obs := range observers {
+			obs.f(e)
+		}
+	}
+}
+
+// OnChange registers a function called for every element that becomes present or stops being present, whether
+// by a local mutation, a merge or an applied operation, and returns the function unregistering it
+//
+// The function is called by the goroutine that made the change, once it is applied. A mutation that changes
+// nothing, such as adding an element that is already present, is not reported
+func (s *LWWSet[T]) OnChange(f func(event.Event[T])) func() {
+	return s.observers.Add(f)
+}
+
+// tracker records whether the elements a change touches were present before it
+type tracker[T comparable] struct {
+	s        *LWWSet[T]
+	remote   bool
+	observed bool
+	present  map[T]bool
+	events   []event.Event[T] // changes, once computed
+	computed bool
+}
+
+// track begins a change of the set, the returned tracker is nil unless it is observed
+func (s *LWWSet[T]) track(remote bool) *tracker[T] {
+	if !s.observers.Begin() {
+		return nil
+	}
+	return &tracker[T]{s: s, remote: remote, observed: true, present: make(map[T]bool)}
+}
+
+// touch records whether an element is present, before the change modifies its entries
+func (tr *tracker[T]) touch(value T) {
+	if tr == nil {
+		return
+	}
+	if _, ok := tr.present[value]; !ok {
+		tr.present[value] = tr.s.Exists(value)
+	}
+}
+
+// changes returns an event for every element whose presence the change modified
+func (tr *tracker[T]) changes() []event.Event[T] {
+	if tr.computed {
+		return tr.events
+	}
+	tr.computed = true
+	for value, present := range tr.present {
+		if tr.s.Exists(value) == present {
+			continue
+		}
+		e := event.Event[T]{Type: event.Added, Element: value, Remote: tr.remote}
+		if present {
+			e.Type = event.Removed
+			e.Timestamp, _ = tr.s.Removals.AddedAt(value)
+		} else {
+			e.Timestamp, _ = tr.s.Additions.AddedAt(value)
+		}
+		tr.events = append(tr.events, e)
+	}
+	return tr.events
+}
+
+// done ends the change, reporting the elements whose presence it changed to the observers
+func (tr *tracker[T]) done() {
+	if tr == nil || !tr.observed {
+		return
+	}
+	tr.s.observers.End(tr.changes())
+}
@@ -0,0 +1,89 @@
+package set_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt/event"
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// checks local mutations are reported when they change the content of the set
+func TestLWWSet_OnChange(t *testing.T) {
+	base := time.Now()
+	s := set.NewLWWSet[string]()
+	var events []event.Event[string]
+	unsubscribe := s.OnChange(func(e event.Event[string]) {
+		events = append(events, e)
+	})
+
+	s.Add("item1", base)
+	s.Add("item1", base.Add(time.Second))
+	s.Remove("item1", base.Add(2*time.Second))
+	s.Remove("item2", base.Add(2*time.Second))
+
+	expected := []event.Event[string]{
+		{Type: event.Added, Element: "item1", Timestamp: base},
+		{Type: event.Removed, Element: "item1", Timestamp: base.Add(2 * time.Second)},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Unexpected events, got: %v, expected: %v.", events, expected)
+	}
+	for i := range expected {
+		if events[i].Type != expected[i].Type || events[i].Element != expected[i].Element ||
+			!events[i].Timestamp.Equal(expected[i].Timestamp) || events[i].Remote {
+			t.Errorf("Unexpected event, got: %v, expected: %v.", events[i], expected[i])
+		}
+	}
+
+	unsubscribe()
+	s.Add("item3", base)
+	if len(events) != len(expected) {
+		t.Errorf("Expected no event once unsubscribed, got: %v.", events[len(expected):])
+	}
+}
+
+// checks merges report the elements they add or remove as remote changes, and nothing else
+func TestLWWSet_OnChangeMerge(t *testing.T) {
+	base := time.Now()
+	s := set.NewLWWSet[string]()
+	s.Add("item1", base)
+	s.Add("item2", base)
+
+	other := set.NewLWWSet[string]()
+	other.Add("item1", base.Add(time.Second))
+	other.Remove("item2", base.Add(time.Second))
+	other.Add("item3", base.Add(time.Second))
+
+	events := make(map[string]event.Event[string])
+	s.OnChange(func(e event.Event[string]) {
+		events[e.Element] = e
+	})
+	if err := s.Merge(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Unexpected events, got: %v, expected 2.", events)
+	}
+	if e := events["item2"]; e.Type != event.Removed || !e.Remote {
+		t.Errorf("Unexpected event for item2, got: %v, expected a remote removal.", e)
+	}
+	if e := events["item3"]; e.Type != event.Added || !e.Remote {
+		t.Errorf("Unexpected event for item3, got: %v, expected a remote addition.", e)
+	}
+}
+
+// checks observers may mutate the set they observe
+func TestLWWSet_OnChangeReentrant(t *testing.T) {
+	s := set.NewLWWSet[string]()
+	s.OnChange(func(e event.Event[string]) {
+		if e.Type == event.Added && e.Element == "item1" {
+			s.Add("item2", time.Now())
+		}
+	})
+	s.Add("item1", time.Now())
+	if !s.Exists("item2") {
+		t.Errorf("Expected the observer to add item2")
+	}
+}
@@ -0,0 +1,98 @@
+package set
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/bjornaer/crdt/crdtpb"
+	backends "github.com/bjornaer/crdt/internal/backends"
+	codec "github.com/bjornaer/crdt/internal/codec"
+)
+
+// entriesToProto converts every element/timestamp association of a TimeSet to its protobuf message
+// entries are sorted by element encoding so the same state always produces the same bytes
+func entriesToProto[T comparable](ts backends.TimeSet[T], c codec.ElementCodec[T]) ([]*crdtpb.Entry, error) {
+	entries := make([]*crdtpb.Entry, 0, ts.Size())
+	err := ts.Each(func(element T, addedAt time.Time) error {
+		data, err := c.Encode(element)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, &crdtpb.Entry{Element: data, Timestamp: addedAt.UnixNano()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Element, entries[j].Element) < 0
+	})
+	return entries, nil
+}
+
+// entriesFromProto adds every protobuf entry to a TimeSet
+func entriesFromProto[T comparable](entries []*crdtpb.Entry, ts backends.TimeSet[T], c codec.ElementCodec[T]) error {
+	for _, entry := range entries {
+		element, err := c.Decode(entry.GetElement())
+		if err != nil {
+			return err
+		}
+		err = ts.Add(element, time.Unix(0, entry.GetTimestamp()).UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToProto converts a set to its protobuf message, encoding its elements with the set codec
+func ToProto[T comparable](s LastWriterWinsSet[T]) (*crdtpb.LWWSet, error) {
+	var err error
+	m := &crdtpb.LWWSet{}
+	m.Additions, err = entriesToProto(s.GetAdditions(), s.GetCodec())
+	if err != nil {
+		return nil, err
+	}
+	m.Removals, err = entriesToProto(s.GetRemovals(), s.GetCodec())
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FromProto builds a set out of its protobuf message, the options must provide the codec the message was encoded with
+func FromProto[T comparable](m *crdtpb.LWWSet, opts ...Option[T]) (LastWriterWinsSet[T], error) {
+	s := NewLWWSet(opts...)
+	err := entriesFromProto(m.GetAdditions(), s.GetAdditions(), s.GetCodec())
+	if err != nil {
+		return nil, err
+	}
+	err = entriesFromProto(m.GetRemovals(), s.GetRemovals(), s.GetCodec())
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// DeltaToProto wraps a set delta-state in a protobuf delta message
+func DeltaToProto[T comparable](origin string, sequence uint64, delta LastWriterWinsSet[T]) (*crdtpb.Delta, error) {
+	m, err := ToProto(delta)
+	if err != nil {
+		return nil, err
+	}
+	return &crdtpb.Delta{Origin: origin, Sequence: sequence, State: &crdtpb.Delta_Set{Set: m}}, nil
+}
+
+// DeltaFromProto extracts the set delta-state of a protobuf delta message along with its origin and sequence
+func DeltaFromProto[T comparable](m *crdtpb.Delta, opts ...Option[T]) (string, uint64, LastWriterWinsSet[T], error) {
+	if m.GetSet() == nil {
+		return "", 0, nil, errors.New("cannot decode delta, it does not hold a set")
+	}
+	delta, err := FromProto(m.GetSet(), opts...)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return m.GetOrigin(), m.GetSequence(), delta, nil
+}
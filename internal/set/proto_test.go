@@ -0,0 +1,111 @@
+package set_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt/crdtpb"
+	set "github.com/bjornaer/crdt/internal/set"
+	"google.golang.org/protobuf/proto"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// fixed timestamps keep the encoded state stable across runs
+var epoch = time.Unix(1650000000, 0)
+
+func setupGoldenSet() set.LastWriterWinsSet[string] {
+	s := set.NewLWWSet[string]()
+	s.Add("item1", epoch)
+	s.Add("item2", epoch.Add(time.Second))
+	s.Add("item3", epoch.Add(2*time.Second))
+	s.Remove("item2", epoch.Add(3*time.Second))
+	return s
+}
+
+// checkGolden compares a message encoding with the content of a golden file, rewriting it when -update is set
+func checkGolden(t *testing.T, name string, m proto.Message) []byte {
+	got, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	path := filepath.Join("testdata", name)
+	if *update {
+		err = os.WriteFile(path, got, 0644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Encoding of %s does not match its golden file, got: %x, expected: %x.", name, got, expected)
+	}
+	return expected
+}
+
+func TestToProto_Golden(t *testing.T) {
+	m, err := set.ToProto(setupGoldenSet())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkGolden(t, "set.golden", m)
+}
+
+func TestFromProto_Golden(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "set.golden"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var m crdtpb.LWWSet
+	err = proto.Unmarshal(data, &m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := set.FromProto[string](&m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	items, _ := s.Get()
+	expected := []string{"item1", "item3"}
+	if !setsAreEqual(items, expected) {
+		t.Errorf("Items mismatch, got: %v, expected: %v.", items, expected)
+	}
+	addedAt, _ := s.GetAdditions().AddedAt("item3")
+	if !addedAt.Equal(epoch.Add(2 * time.Second)) {
+		t.Errorf("Timestamp mismatch, got: %v, expected: %v.", addedAt, epoch.Add(2*time.Second))
+	}
+}
+
+func TestDeltaProto_Golden(t *testing.T) {
+	delta := set.NewLWWSet[string]()
+	delta.Add("item4", epoch)
+	m, err := set.DeltaToProto("replica1", 7, delta)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data := checkGolden(t, "set_delta.golden", m)
+
+	var decoded crdtpb.Delta
+	err = proto.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	origin, sequence, got, err := set.DeltaFromProto[string](&decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if origin != "replica1" || sequence != 7 || !got.Exists("item4") {
+		t.Errorf("Delta mismatch, got: %v %v %v", origin, sequence, got)
+	}
+	_, _, _, err = set.DeltaFromProto[string](&crdtpb.Delta{})
+	if err == nil {
+		t.Errorf("Expected error decoding a delta without a set")
+	}
+}
@@ -0,0 +1,45 @@
+package set
+
+import (
+	"time"
+
+	"github.com/bjornaer/crdt/event"
+	"github.com/bjornaer/crdt/report"
+)
+
+// merged records an entry taken from the other set into a merge report if any, and the timestamp it replaced if any
+func merged[T comparable](r *report.Merge[T], value T, removal bool, previous time.Time, replaced bool, t time.Time) {
+	if r == nil {
+		return
+	}
+	if removal {
+		r.Removals++
+	} else {
+		r.Additions++
+	}
+	if replaced {
+		r.Overwritten = append(r.Overwritten, report.Overwrite[T]{Element: value, Removal: removal, Previous: previous, Current: t})
+	}
+}
+
+// MergeWithReport merges another set like Merge, and returns a report of what the merge changed
+//
+// Entries merged before an error are reported, as they are kept. Mutations made concurrently by other goroutines
+// may be reported as part of the merge, unless the set is observed with OnChange, which serializes changes
+func (s *LWWSet[T]) MergeWithReport(other LastWriterWinsSet[T]) (report.Merge[T], error) {
+	var r report.Merge[T]
+	tr := s.track(true)
+	if tr == nil {
+		tr = &tracker[T]{s: s, remote: true, present: make(map[T]bool)}
+	}
+	defer tr.done()
+	err := s.merge(other, true, tr, &r)
+	for _, e := range tr.changes() {
+		if e.Type == event.Added {
+			r.Added = append(r.Added, e.Element)
+		} else {
+			r.Removed = append(r.Removed, e.Element)
+		}
+	}
+	return r, err
+}
@@ -0,0 +1,46 @@
+package set_test
+
+import (
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// checks merge reports list the elements made present or absent and the timestamps replaced
+func TestLWWSet_MergeWithReport(t *testing.T) {
+	base := time.Now()
+	s := set.NewLWWSet[string]()
+	s.Add("item1", base)
+	s.Add("item2", base)
+	s.Remove("item4", base)
+
+	other := set.NewLWWSet[string]()
+	other.Add("item1", base.Add(time.Second))
+	other.Remove("item2", base.Add(time.Second))
+	other.Add("item3", base)
+	other.Add("item4", base.Add(-time.Second))
+
+	report, err := s.MergeWithReport(other)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !setsAreEqual(report.Added, []string{"item3"}) {
+		t.Errorf("Unexpected added elements, got: %v, expected: %v.", report.Added, []string{"item3"})
+	}
+	if !setsAreEqual(report.Removed, []string{"item2"}) {
+		t.Errorf("Unexpected removed elements, got: %v, expected: %v.", report.Removed, []string{"item2"})
+	}
+	if len(report.Overwritten) != 1 || report.Overwritten[0].Element != "item1" || report.Overwritten[0].Removal ||
+		!report.Overwritten[0].Previous.Equal(base) || !report.Overwritten[0].Current.Equal(base.Add(time.Second)) {
+		t.Errorf("Unexpected overwritten entries, got: %v, expected item1 overwritten.", report.Overwritten)
+	}
+	if report.Additions != 3 || report.Removals != 1 {
+		t.Errorf("Unexpected counts, got: %v additions and %v removals, expected: 3 and 1.", report.Additions, report.Removals)
+	}
+
+	report, err = s.MergeWithReport(other)
+	if err != nil || report.Changed() || report.Additions+report.Removals != 0 {
+		t.Errorf("Expected merging again to change nothing, got: %v, %v.", report, err)
+	}
+}
@@ -0,0 +1,51 @@
+package set
+
+import (
+	"sync"
+)
+
+// shard holds the lock and the delta of the elements hashed to it, so writers of elements in different shards
+// do not contend
+type shard[T comparable] struct {
+	writes sync.RWMutex // held shared by writers, and exclusively, on every shard, to copy or purge a consistent state
+	mutex  sync.Mutex   // guards delta
+	delta  *LWWSet[T]   // local mutations since the last TakeDelta, nil until the first one
+	_      [64]byte     // keeps shards apart from each other in memory, so their locks do not share a cache line
+}
+
+// shardOf returns the shard of an element, sets built without WithShards have a single one
+func (s *LWWSet[T]) shardOf(value T) *shard[T] {
+	if len(s.shards) == 0 {
+		return &s.single
+	}
+	return &s.shards[s.hasher.Sum(value)%uint64(len(s.shards))]
+}
+
+// each calls a function for every shard, in order
+func (s *LWWSet[T]) each(f func(*shard[T])) {
+	if len(s.shards) == 0 {
+		f(&s.single)
+		return
+	}
+	for i := range s.shards {
+		f(&s.shards[i])
+	}
+}
+
+// lock locks every shard for writing, so the set holds still
+func (s *LWWSet[T]) lock() {
+	s.each(func(sh *shard[T]) { sh.writes.Lock() })
+}
+
+func (s *LWWSet[T]) unlock() {
+	s.each(func(sh *shard[T]) { sh.writes.Unlock() })
+}
+
+// rlock locks every shard for writers, for changes touching any element
+func (s *LWWSet[T]) rlock() {
+	s.each(func(sh *shard[T]) { sh.writes.RLock() })
+}
+
+func (s *LWWSet[T]) runlock() {
+	s.each(func(sh *shard[T]) { sh.writes.RUnlock() })
+}
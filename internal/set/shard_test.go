@@ -0,0 +1,78 @@
+package set_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+// checks the delta of a sharded set gathers the mutations of every shard
+func TestLWWSet_ShardedTakeDelta(t *testing.T) {
+	s := set.NewLWWSet(set.WithShards[int](8))
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		s.Add(i, now)
+	}
+	s.Remove(7, now.Add(time.Second))
+
+	delta := s.TakeDelta()
+	if delta.GetAdditions().Size() != 100 || delta.GetRemovals().Size() != 1 {
+		t.Errorf("Delta size mismatch, got: %v additions and %v removals, expected: 100 and 1.",
+			delta.GetAdditions().Size(), delta.GetRemovals().Size())
+	}
+	if delta.Exists(7) || !delta.Exists(8) {
+		t.Errorf("Delta content mismatch, got: %v and %v for 7 and 8, expected: false and true.",
+			delta.Exists(7), delta.Exists(8))
+	}
+	empty := s.TakeDelta()
+	if empty.GetAdditions().Size() != 0 || empty.GetRemovals().Size() != 0 {
+		t.Errorf("Delta not reset after being taken")
+	}
+}
+
+// checks concurrent writers of a sharded set neither lose entries nor deltas
+func TestLWWSet_ShardedConcurrentAdd(t *testing.T) {
+	s := set.NewLWWSet(set.WithShards[int](4))
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s.Add(w*100+i, time.Now())
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	items, _ := s.Get()
+	if len(items) != 800 {
+		t.Errorf("Set size mismatch, got: %v, expected: %v.", len(items), 800)
+	}
+	if size := s.TakeDelta().GetAdditions().Size(); size != 800 {
+		t.Errorf("Delta size mismatch, got: %v, expected: %v.", size, 800)
+	}
+}
+
+func BenchmarkLWWSet_ParallelAdd(b *testing.B) {
+	for _, shards := range []int{0, 4, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			var opts []set.Option[int]
+			if shards > 0 {
+				opts = append(opts, set.WithShards[int](shards))
+			}
+			s := set.NewLWWSet(opts...)
+			now := time.Now()
+			var next int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					s.Add(int(atomic.AddInt64(&next, 1)), now)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/bjornaer/crdt/crdtpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// SnapshotKind tells what a snapshot holds
+type SnapshotKind byte
+
+const (
+	SetSnapshot SnapshotKind = iota + 1
+	GraphSnapshot
+)
+
+func (k SnapshotKind) String() string {
+	switch k {
+	case SetSnapshot:
+		return "set"
+	case GraphSnapshot:
+		return "graph"
+	}
+	return fmt.Sprintf("SnapshotKind(%d)", byte(k))
+}
+
+// snapshotMagic starts every snapshot
+var snapshotMagic = []byte("CRDTSNAP")
+
+// snapshotVersion is the version of the snapshot format written by WriteSnapshot
+const snapshotVersion = 1
+
+// snapshotHeaderSize is the size of the magic, version, kind and payload length
+var snapshotHeaderSize = len(snapshotMagic) + 2 + 1 + 8
+
+// ErrInvalidSnapshot is returned when restoring from data that is not a snapshot of the expected kind, or is damaged
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WriteSnapshot writes a snapshot of the given kind holding a protobuf message, laid out as:
+//
+//	"CRDTSNAP" | version (uint16) | kind (byte) | length (uint64) | message | CRC-32C of everything before
+//
+// Integers are big-endian, the message is one of the crdtpb messages
+func WriteSnapshot(w io.Writer, kind SnapshotKind, m proto.Message) error {
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, snapshotHeaderSize, snapshotHeaderSize+len(payload)+4)
+	copy(data, snapshotMagic)
+	binary.BigEndian.PutUint16(data[len(snapshotMagic):], snapshotVersion)
+	data[len(snapshotMagic)+2] = byte(kind)
+	binary.BigEndian.PutUint64(data[len(snapshotMagic)+3:], uint64(len(payload)))
+	data = append(data, payload...)
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.Checksum(data, castagnoli))
+	_, err = w.Write(append(data, checksum...))
+	return err
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot into m, after checking its kind and checksum
+func ReadSnapshot(r io.Reader, kind SnapshotKind, m proto.Message) error {
+	header := make([]byte, snapshotHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) {
+		return fmt.Errorf("%w: not a snapshot", ErrInvalidSnapshot)
+	}
+	if version := binary.BigEndian.Uint16(header[len(snapshotMagic):]); version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+	if got := SnapshotKind(header[len(snapshotMagic)+2]); got != kind {
+		return fmt.Errorf("%w: snapshot of a %v, expected a %v", ErrInvalidSnapshot, got, kind)
+	}
+	length := binary.BigEndian.Uint64(header[len(snapshotMagic)+3:])
+
+	// the payload is read as it comes rather than allocated upfront, a damaged length cannot exhaust memory
+	payload, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return err
+	}
+	if uint64(len(payload)) != length {
+		return fmt.Errorf("%w: truncated", ErrInvalidSnapshot)
+	}
+	checksum := make([]byte, 4)
+	if _, err := io.ReadFull(r, checksum); err != nil {
+		return fmt.Errorf("%w: truncated", ErrInvalidSnapshot)
+	}
+	digest := crc32.New(castagnoli)
+	digest.Write(header)
+	digest.Write(payload)
+	if digest.Sum32() != binary.BigEndian.Uint32(checksum) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+	return proto.Unmarshal(payload, m)
+}
+
+// Snapshot writes the state of the set, see WriteSnapshot for the format
+//
+// Writes wait while the state is copied, so the snapshot holds the set as it was at a single point in time
+func (s *LWWSet[T]) Snapshot(w io.Writer) error {
+	s.lock()
+	m, err := ToProto[T](s)
+	s.unlock()
+	if err != nil {
+		return err
+	}
+	return WriteSnapshot(w, SetSnapshot, m)
+}
+
+// Restore merges a snapshot written by Snapshot into the set, restoring into an empty set recovers the snapshot state
+//
+// Elements are decoded with the codec of the set, which must be the one of the snapshot set
+func (s *LWWSet[T]) Restore(r io.Reader) error {
+	m := &crdtpb.LWWSet{}
+	if err := ReadSnapshot(r, SetSnapshot, m); err != nil {
+		return err
+	}
+	restored, err := FromProto(m, WithCodec(s.GetCodec()))
+	if err != nil {
+		return err
+	}
+	return s.Merge(restored)
+}
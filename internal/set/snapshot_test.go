@@ -0,0 +1,110 @@
+package set_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	set "github.com/bjornaer/crdt/internal/set"
+)
+
+func TestLWWSet_Snapshot(t *testing.T) {
+	s := setupTestSet()
+	s.Remove("item2", time.Now())
+	var buf bytes.Buffer
+	if err := s.Snapshot(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restored := set.NewLWWSet[string]()
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	items, err := restored.Get()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"item1", "item3"}; !setsAreEqual(items, expected) {
+		t.Errorf("Unexpected restored items, got: %v, expected: %v.", items, expected)
+	}
+	if size := restored.GetRemovals().Size(); size != 1 {
+		t.Errorf("Unexpected number of restored removals, got: %d, expected: %d.", size, 1)
+	}
+}
+
+func TestLWWSet_RestoreInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := setupTestSet().Snapshot(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	snapshot := buf.Bytes()
+
+	flipped := append([]byte{}, snapshot...)
+	flipped[len(flipped)/2] ^= 0xff
+	otherVersion := append([]byte{}, snapshot...)
+	otherVersion[9] = 2
+	graph := append([]byte{}, snapshot...)
+	graph[10] = byte(set.GraphSnapshot)
+
+	tests := map[string][]byte{
+		"damaged":       flipped,
+		"truncated":     snapshot[:len(snapshot)-1],
+		"empty":         nil,
+		"not snapshot":  []byte("this is not a snapshot of anything"),
+		"other version": otherVersion,
+		"other kind":    graph,
+	}
+	for name, data := range tests {
+		err := set.NewLWWSet[string]().Restore(bytes.NewReader(data))
+		if !errors.Is(err, set.ErrInvalidSnapshot) {
+			t.Errorf("Unexpected error restoring a %s snapshot, got: %v, expected: %v.", name, err, set.ErrInvalidSnapshot)
+		}
+	}
+}
+
+// checks a snapshot taken while writes continue holds the set at a single point in time
+func TestLWWSet_SnapshotConsistency(t *testing.T) {
+	s := set.NewLWWSet[string]()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// every element is removed right after being added
+		for i := 0; i < 50000; i++ {
+			element := fmt.Sprint("item", i)
+			now := time.Now()
+			s.Add(element, now)
+			s.Remove(element, now.Add(time.Nanosecond))
+		}
+	}()
+
+	for snapshots := 0; ; snapshots++ {
+		select {
+		case <-done:
+			if snapshots == 0 {
+				t.Skip("writes ended before any snapshot was taken")
+			}
+			return
+		default:
+		}
+		var buf bytes.Buffer
+		if err := s.Snapshot(&buf); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		restored := set.NewLWWSet[string]()
+		if err := restored.Restore(&buf); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		err := restored.GetRemovals().Each(func(element string, removedAt time.Time) error {
+			if _, ok := restored.GetAdditions().AddedAt(element); !ok {
+				return fmt.Errorf("%s removed without being added", element)
+			}
+			return nil
+		})
+		if err != nil {
+			<-done
+			t.Fatalf("Inconsistent snapshot: %v", err)
+		}
+	}
+}
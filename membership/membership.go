@@ -0,0 +1,582 @@
+// Package membership tracks the members of a cluster and detects their failures with the SWIM protocol
+//
+// The member list is a LastWriterWinsSet of member addresses, so it can itself be replicated like any other
+// CRDT of this library, while the SWIM state of each member (alive, suspect, dead, and its incarnation number)
+// is disseminated by piggybacking on the protocol messages
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/replication"
+)
+
+// State is the state of a member as seen by the local member
+type State int
+
+const (
+	Alive State = iota
+	Suspect
+	Dead
+)
+
+func (s State) String() string {
+	switch s {
+	case Alive:
+		return "alive"
+	case Suspect:
+		return "suspect"
+	case Dead:
+		return "dead"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Member is a member of the cluster
+type Member struct {
+	Addr string `json:"addr"`
+	// Meta is advertised by the member along with its address, the address of its replicator for instance
+	Meta  string `json:"meta,omitempty"`
+	State State  `json:"state"`
+	// Incarnation is increased by the member every time it refutes a suspicion, newer incarnations override older ones
+	Incarnation uint64 `json:"incarnation"`
+}
+
+// Config holds the settings of a Memberlist
+type Config struct {
+	// ProbeInterval is the time between two probes, a probed member has that long to answer
+	ProbeInterval time.Duration
+	// ProbeTimeout is how long a direct ping waits for its ack before other members are asked to ping
+	ProbeTimeout time.Duration
+	// IndirectProbes is the number of members asked to ping a member that did not answer a direct ping
+	IndirectProbes int
+	// SuspicionTimeout is how long a member stays suspect before it is declared dead
+	SuspicionTimeout time.Duration
+	// Meta is advertised to the other members
+	Meta string
+	// Rand picks the probed members
+	Rand *rand.Rand
+}
+
+// Option configures a Memberlist
+type Option func(*Config)
+
+// WithProbe sets the time between probes and how long a direct ping waits for its ack
+func WithProbe(interval, timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.ProbeInterval = interval
+		cfg.ProbeTimeout = timeout
+	}
+}
+
+// WithIndirectProbes sets the number of members asked to ping a member that did not answer
+func WithIndirectProbes(n int) Option {
+	return func(cfg *Config) {
+		cfg.IndirectProbes = n
+	}
+}
+
+// WithSuspicionTimeout sets how long a member stays suspect before it is declared dead
+func WithSuspicionTimeout(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.SuspicionTimeout = d
+	}
+}
+
+// WithMeta sets the metadata advertised to the other members
+func WithMeta(meta string) Option {
+	return func(cfg *Config) {
+		cfg.Meta = meta
+	}
+}
+
+// WithRand sets the source picking the probed members, for reproducible simulations
+func WithRand(rnd *rand.Rand) Option {
+	return func(cfg *Config) {
+		cfg.Rand = rnd
+	}
+}
+
+// messageType tells what a message asks of the member receiving it
+type messageType int
+
+const (
+	pingMessage messageType = iota
+	pingReqMessage
+	ackMessage
+	joinMessage
+)
+
+// message is the datagram exchanged between members, every message piggybacks membership updates
+type message struct {
+	Type messageType `json:"type"`
+	Seq  uint64      `json:"seq"`
+	From string      `json:"from"`
+	// Target is the member to ping on behalf of the sender, for ping-reqs
+	Target  string   `json:"target,omitempty"`
+	Updates []Member `json:"updates,omitempty"`
+}
+
+// maxPiggyback bounds the number of updates piggybacked on a message
+const maxPiggyback = 8
+
+// member is the SWIM state of a member
+type member struct {
+	Member
+	suspectedAt time.Time
+}
+
+// broadcast is an update being disseminated, along with the number of messages it was piggybacked on
+type broadcast struct {
+	update    Member
+	transmits int
+}
+
+// Memberlist is the local view of the cluster membership
+type Memberlist struct {
+	transport   Transport
+	self        string
+	cfg         Config
+	list        crdt.LastWriterWinsSet[string] // addresses of the members not known to be dead
+	members     map[string]*member
+	incarnation uint64
+	seq         uint64
+	pending     map[uint64]func() // called when the ack of the sequence is received
+	broadcasts  map[string]*broadcast
+	subscribers []func()
+	mutex       sync.Mutex
+}
+
+// New returns the Memberlist of the member reachable through the given transport, it starts receiving messages
+// right away, Run probes the other members
+func New(transport Transport, opts ...Option) *Memberlist {
+	cfg := Config{
+		ProbeInterval:    time.Second,
+		ProbeTimeout:     300 * time.Millisecond,
+		IndirectProbes:   3,
+		SuspicionTimeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	m := &Memberlist{
+		transport:  transport,
+		self:       transport.Addr(),
+		cfg:        cfg,
+		list:       crdt.NewLWWSet[string](),
+		members:    make(map[string]*member),
+		pending:    make(map[uint64]func()),
+		broadcasts: make(map[string]*broadcast),
+	}
+	// the list is local at this point, adding to it cannot fail
+	_ = m.list.Add(m.self, time.Now())
+	go m.receive()
+	return m
+}
+
+// List returns the CRDT holding the addresses of the members, it can be registered with a Replicator so the
+// member list also spreads through anti-entropy
+func (m *Memberlist) List() crdt.LastWriterWinsSet[string] {
+	return m.list
+}
+
+// Members returns the other members that are not known to be dead
+func (m *Memberlist) Members() []Member {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sync()
+	var members []Member
+	for _, mem := range m.members {
+		if mem.State != Dead {
+			members = append(members, mem.Member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Addr < members[j].Addr })
+	return members
+}
+
+// Peers returns the addresses to replicate with: the metadata of the members that are not known to be dead,
+// or their address when they do not advertise any
+func (m *Memberlist) Peers() []string {
+	var peers []string
+	for _, mem := range m.Members() {
+		if mem.Meta != "" {
+			peers = append(peers, mem.Meta)
+		} else {
+			peers = append(peers, mem.Addr)
+		}
+	}
+	return peers
+}
+
+// Feed keeps the peers of a replicator in line with the members, as long as the memberlist runs
+func (m *Memberlist) Feed(r *replication.Replicator) {
+	update := func() {
+		r.SetPeers(m.Peers())
+	}
+	m.mutex.Lock()
+	m.subscribers = append(m.subscribers, update)
+	m.mutex.Unlock()
+	update()
+}
+
+// errNoMembers is returned by Join when no member to join was given
+var errNoMembers = errors.New("no member to join")
+
+// Join contacts the given members and learns the member list from the first one that answers
+func (m *Memberlist) Join(ctx context.Context, addrs ...string) error {
+	joined := make(chan struct{})
+	var once sync.Once
+	m.mutex.Lock()
+	m.seq++
+	seq := m.seq
+	m.pending[seq] = func() { once.Do(func() { close(joined) }) }
+	m.mutex.Unlock()
+	defer m.forget(seq)
+
+	sent := 0
+	for _, addr := range addrs {
+		if addr == m.self {
+			continue
+		}
+		if err := m.send(addr, message{Type: joinMessage, Seq: seq}); err != nil {
+			return err
+		}
+		sent++
+	}
+	if sent == 0 {
+		return errNoMembers
+	}
+	select {
+	case <-joined:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("cannot join %v: %w", addrs, ctx.Err())
+	}
+}
+
+// Run probes a member every probe interval until the context is done
+func (m *Memberlist) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.tick(ctx)
+		}
+	}
+}
+
+// Close stops the memberlist and its transport, other members eventually declare it dead
+func (m *Memberlist) Close() error {
+	return m.transport.Close()
+}
+
+// tick runs a protocol period: probes a random member, and declares dead the members suspected for too long
+func (m *Memberlist) tick(ctx context.Context) {
+	m.mutex.Lock()
+	m.sync()
+	var candidates []string
+	for addr, mem := range m.members {
+		if mem.State != Dead {
+			candidates = append(candidates, addr)
+		}
+	}
+	sort.Strings(candidates)
+	var target string
+	if len(candidates) > 0 {
+		target = candidates[m.cfg.Rand.Intn(len(candidates))]
+	}
+	now := time.Now()
+	for _, mem := range m.members {
+		if mem.State == Suspect && now.Sub(mem.suspectedAt) >= m.cfg.SuspicionTimeout {
+			m.apply(Member{Addr: mem.Addr, Meta: mem.Meta, State: Dead, Incarnation: mem.Incarnation})
+		}
+	}
+	subscribers := append([]func(){}, m.subscribers...)
+	m.mutex.Unlock()
+
+	if target != "" {
+		m.probe(ctx, target)
+	}
+	for _, notify := range subscribers {
+		notify()
+	}
+}
+
+// probe pings a member directly, then through other members, and suspects it if no ack came back in time
+func (m *Memberlist) probe(ctx context.Context, target string) {
+	acked := make(chan struct{})
+	var once sync.Once
+	m.mutex.Lock()
+	m.seq++
+	seq := m.seq
+	m.pending[seq] = func() { once.Do(func() { close(acked) }) }
+	m.mutex.Unlock()
+	defer m.forget(seq)
+
+	// send failures are handled like lost datagrams, the missing ack tells
+	_ = m.send(target, message{Type: pingMessage, Seq: seq})
+	direct := time.NewTimer(m.cfg.ProbeTimeout)
+	defer direct.Stop()
+	select {
+	case <-acked:
+		return
+	case <-ctx.Done():
+		return
+	case <-direct.C:
+	}
+
+	for _, helper := range m.helpers(target) {
+		_ = m.send(helper, message{Type: pingReqMessage, Seq: seq, Target: target})
+	}
+	indirect := time.NewTimer(m.cfg.ProbeInterval - m.cfg.ProbeTimeout)
+	defer indirect.Stop()
+	select {
+	case <-acked:
+		return
+	case <-ctx.Done():
+		return
+	case <-indirect.C:
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if mem, ok := m.members[target]; ok && mem.State == Alive {
+		m.apply(Member{Addr: target, Meta: mem.Meta, State: Suspect, Incarnation: mem.Incarnation})
+	}
+}
+
+// helpers picks the members asked to ping a member that did not answer
+func (m *Memberlist) helpers(target string) []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var helpers []string
+	for addr, mem := range m.members {
+		if addr != target && mem.State == Alive {
+			helpers = append(helpers, addr)
+		}
+	}
+	sort.Strings(helpers)
+	m.cfg.Rand.Shuffle(len(helpers), func(i, j int) { helpers[i], helpers[j] = helpers[j], helpers[i] })
+	if len(helpers) > m.cfg.IndirectProbes {
+		helpers = helpers[:m.cfg.IndirectProbes]
+	}
+	return helpers
+}
+
+func (m *Memberlist) forget(seq uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.pending, seq)
+}
+
+// receive handles incoming messages until the transport is closed
+func (m *Memberlist) receive() {
+	for p := range m.transport.Packets() {
+		var msg message
+		if err := json.Unmarshal(p.Data, &msg); err != nil {
+			continue
+		}
+		m.handle(msg)
+	}
+}
+
+func (m *Memberlist) handle(msg message) {
+	m.mutex.Lock()
+	for _, u := range msg.Updates {
+		m.apply(u)
+	}
+	m.mutex.Unlock()
+
+	switch msg.Type {
+	case pingMessage:
+		_ = m.send(msg.From, message{Type: ackMessage, Seq: msg.Seq})
+	case joinMessage:
+		// the joining member learns the whole list at once rather than through piggybacked updates
+		m.mutex.Lock()
+		m.sync()
+		updates := []Member{m.alive()}
+		for _, mem := range m.members {
+			updates = append(updates, mem.Member)
+		}
+		m.mutex.Unlock()
+		_ = m.sendRaw(msg.From, message{Type: ackMessage, Seq: msg.Seq, From: m.self, Updates: updates})
+	case pingReqMessage:
+		// ping the target on behalf of the sender, and forward the ack if it comes back in time
+		m.mutex.Lock()
+		m.seq++
+		seq := m.seq
+		m.pending[seq] = func() {
+			_ = m.send(msg.From, message{Type: ackMessage, Seq: msg.Seq})
+		}
+		m.mutex.Unlock()
+		time.AfterFunc(m.cfg.ProbeInterval, func() { m.forget(seq) })
+		_ = m.send(msg.Target, message{Type: pingMessage, Seq: seq})
+	case ackMessage:
+		m.mutex.Lock()
+		acked, ok := m.pending[msg.Seq]
+		delete(m.pending, msg.Seq)
+		m.mutex.Unlock()
+		if ok {
+			acked()
+		}
+	}
+}
+
+// send sends a message with the updates being disseminated piggybacked on it
+func (m *Memberlist) send(addr string, msg message) error {
+	m.mutex.Lock()
+	msg.From = m.self
+	msg.Updates = append([]Member{m.alive()}, m.piggyback()...)
+	m.mutex.Unlock()
+	return m.sendRaw(addr, msg)
+}
+
+func (m *Memberlist) sendRaw(addr string, msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return m.transport.Send(addr, data)
+}
+
+// alive returns the update advertising the local member
+func (m *Memberlist) alive() Member {
+	return Member{Addr: m.self, Meta: m.cfg.Meta, State: Alive, Incarnation: m.incarnation}
+}
+
+// piggyback returns the updates to piggyback on a message, least transmitted first, and drops the updates that
+// were transmitted often enough to have reached every member with high probability
+func (m *Memberlist) piggyback() []Member {
+	all := make([]*broadcast, 0, len(m.broadcasts))
+	for _, b := range m.broadcasts {
+		all = append(all, b)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].transmits != all[j].transmits {
+			return all[i].transmits < all[j].transmits
+		}
+		return all[i].update.Addr < all[j].update.Addr
+	})
+	if len(all) > maxPiggyback {
+		all = all[:maxPiggyback]
+	}
+	limit := 3 * int(math.Ceil(math.Log2(float64(len(m.members)+2))))
+	updates := make([]Member, 0, len(all))
+	for _, b := range all {
+		updates = append(updates, b.update)
+		b.transmits++
+		if b.transmits >= limit {
+			delete(m.broadcasts, b.update.Addr)
+		}
+	}
+	return updates
+}
+
+// apply merges an update into the local view, following the SWIM precedence rules: a higher incarnation wins,
+// at equal incarnations suspect overrides alive, and dead overrides everything
+func (m *Memberlist) apply(u Member) {
+	if u.Addr == "" {
+		return
+	}
+	if u.Addr == m.self {
+		// a member refutes suspicions about itself by advertising a newer incarnation
+		if u.State != Alive && u.Incarnation >= m.incarnation {
+			m.incarnation = u.Incarnation + 1
+			m.disseminate(m.alive())
+		}
+		return
+	}
+
+	cur, known := m.members[u.Addr]
+	if known {
+		switch u.State {
+		case Alive:
+			if u.Incarnation <= cur.Incarnation {
+				if u.Meta != "" && cur.State != Dead {
+					cur.Meta = u.Meta
+				}
+				return
+			}
+		case Suspect:
+			if cur.State == Dead || u.Incarnation < cur.Incarnation || (u.Incarnation == cur.Incarnation && cur.State == Suspect) {
+				return
+			}
+		case Dead:
+			if cur.State == Dead && u.Incarnation <= cur.Incarnation {
+				return
+			}
+		}
+	} else if u.State == Dead {
+		return
+	}
+
+	mem := &member{Member: u}
+	if mem.Meta == "" && known {
+		mem.Meta = cur.Meta
+	}
+	if u.State == Suspect {
+		mem.suspectedAt = time.Now()
+	}
+	m.members[u.Addr] = mem
+	// the list is a plain set held in memory, writing to it cannot fail
+	if u.State == Dead {
+		_ = m.list.Remove(u.Addr, time.Now())
+	} else if !m.list.Exists(u.Addr) {
+		_ = m.list.Add(u.Addr, time.Now())
+	}
+	m.disseminate(u)
+}
+
+// disseminate queues an update for piggybacking, replacing any older update about the same member
+func (m *Memberlist) disseminate(u Member) {
+	m.broadcasts[u.Addr] = &broadcast{update: u}
+}
+
+// sync reconciles the SWIM state with the member list, which may have changed through replication: members added
+// by other replicas are considered alive until probed, and members removed by them are considered dead
+func (m *Memberlist) sync() {
+	addrs, err := m.list.Get()
+	if err != nil {
+		return
+	}
+	listed := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		listed[addr] = true
+		if addr == m.self {
+			continue
+		}
+		if mem, ok := m.members[addr]; !ok {
+			m.members[addr] = &member{Member: Member{Addr: addr, State: Alive}}
+		} else if mem.State == Dead {
+			mem.State = Alive
+		}
+	}
+	if !listed[m.self] {
+		// another replica declared the local member dead, it rejoins with a newer incarnation
+		m.incarnation++
+		_ = m.list.Add(m.self, time.Now())
+		m.disseminate(m.alive())
+	}
+	for addr, mem := range m.members {
+		if !listed[addr] && mem.State != Dead {
+			mem.State = Dead
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package membership_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt/membership"
+	"github.com/bjornaer/crdt/replication"
+)
+
+// options make protocol periods short enough for tests
+var options = []membership.Option{
+	membership.WithProbe(20*time.Millisecond, 5*time.Millisecond),
+	membership.WithSuspicionTimeout(100 * time.Millisecond),
+}
+
+// eventually polls a condition until it holds or a few seconds passed
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// addrs returns the addresses of the given members
+func addrs(members []membership.Member) []string {
+	var addrs []string
+	for _, m := range members {
+		addrs = append(addrs, m.Addr)
+	}
+	return addrs
+}
+
+// checks element is contained within set
+func contains[T comparable](s []T, e T) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// startCluster runs a memberlist per transport, every member joining the first one
+func startCluster(t *testing.T, transports []membership.Transport, opts ...membership.Option) []*membership.Memberlist {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	var members []*membership.Memberlist
+	for i, transport := range transports {
+		m := membership.New(transport, append(append([]membership.Option{}, options...), opts...)...)
+		t.Cleanup(func() { m.Close() })
+		go m.Run(ctx)
+		if i > 0 {
+			joinCtx, done := context.WithTimeout(ctx, time.Second)
+			err := m.Join(joinCtx, transports[0].Addr())
+			done()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		members = append(members, m)
+	}
+	return members
+}
+
+func memoryCluster(t *testing.T, size int) (*membership.Network, []*membership.Memberlist) {
+	network := membership.NewNetwork()
+	var transports []membership.Transport
+	for i := 0; i < size; i++ {
+		transports = append(transports, network.Listen(fmt.Sprint("m", i)))
+	}
+	return network, startCluster(t, transports)
+}
+
+func TestMembership_Join(t *testing.T) {
+	_, members := memoryCluster(t, 5)
+	for i, m := range members {
+		eventually(t, fmt.Sprintf("m%d to see every member", i), func() bool {
+			return len(m.Members()) == 4
+		})
+	}
+}
+
+func TestMembership_FailureDetection(t *testing.T) {
+	network, members := memoryCluster(t, 4)
+	for _, m := range members {
+		eventually(t, "the cluster to form", func() bool { return len(m.Members()) == 3 })
+	}
+
+	network.SetDown("m3", true)
+	for i, m := range members[:3] {
+		eventually(t, fmt.Sprintf("m%d to declare m3 dead", i), func() bool {
+			return !contains(addrs(m.Members()), "m3") && !m.List().Exists("m3")
+		})
+	}
+}
+
+// checks a member unreachable from another one is kept alive through indirect pings
+func TestMembership_IndirectProbe(t *testing.T) {
+	network, members := memoryCluster(t, 3)
+	for _, m := range members {
+		eventually(t, "the cluster to form", func() bool { return len(m.Members()) == 2 })
+	}
+
+	network.Cut("m0", "m1", true)
+	time.Sleep(500 * time.Millisecond)
+	for _, addr := range []string{"m0", "m1"} {
+		for _, m := range members {
+			if !m.List().Exists(addr) {
+				t.Errorf("Member %s declared dead despite being reachable through m2", addr)
+			}
+		}
+	}
+}
+
+// checks a member refutes a suspicion about itself with a newer incarnation
+func TestMembership_Refute(t *testing.T) {
+	network, members := memoryCluster(t, 2)
+	eventually(t, "the cluster to form", func() bool { return len(members[0].Members()) == 1 })
+
+	spy := network.Listen("spy")
+	suspicion, _ := json.Marshal(map[string]interface{}{
+		"type":    0,
+		"seq":     1,
+		"from":    "spy",
+		"updates": []membership.Member{{Addr: "m1", State: membership.Suspect}},
+	})
+	spy.Send("m1", suspicion)
+
+	eventually(t, "m1 to refute the suspicion", func() bool {
+		for _, m := range members[0].Members() {
+			if m.Addr == "m1" && m.State == membership.Alive && m.Incarnation > 0 {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// checks the member list follows its CRDT, which may be changed through replication
+func TestMembership_List(t *testing.T) {
+	_, members := memoryCluster(t, 1)
+	members[0].List().Add("m9", time.Now())
+	if !contains(addrs(members[0].Members()), "m9") {
+		t.Errorf("Expected a member added to the list to be a member")
+	}
+	eventually(t, "m9 to be declared dead", func() bool {
+		return !members[0].List().Exists("m9")
+	})
+}
+
+func TestMembership_Feed(t *testing.T) {
+	network := membership.NewNetwork()
+	members := startCluster(t, []membership.Transport{network.Listen("m0")})
+	m1 := membership.New(network.Listen("m1"), append(options, membership.WithMeta("10.0.0.1:7946"))...)
+	defer m1.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m1.Run(ctx)
+	if err := m1.Join(ctx, "m0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := replication.New("m0", replication.NewNetwork().Transport("m0"))
+	members[0].Feed(r)
+	eventually(t, "the replicator to be fed", func() bool {
+		peers := r.Peers()
+		return len(peers) == 1 && peers[0] == "10.0.0.1:7946"
+	})
+}
+
+func TestMembership_UDP(t *testing.T) {
+	var transports []membership.Transport
+	for i := 0; i < 3; i++ {
+		transport, err := membership.ListenUDP("127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		transports = append(transports, transport)
+	}
+	members := startCluster(t, transports)
+	for _, m := range members {
+		eventually(t, "the cluster to form", func() bool { return len(m.Members()) == 2 })
+	}
+
+	members[2].Close()
+	eventually(t, "the closed member to be declared dead", func() bool {
+		return len(members[0].Members()) == 1 && len(members[1].Members()) == 1
+	})
+}
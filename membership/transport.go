@@ -0,0 +1,190 @@
+package membership
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// Packet is a datagram received from another member
+type Packet struct {
+	From string
+	Data []byte
+}
+
+// Transport carries datagrams between members, delivery is best effort
+type Transport interface {
+	// Addr returns the address other members reach the local member at
+	Addr() string
+	// Send sends a datagram to the member at the given address
+	Send(addr string, data []byte) error
+	// Packets returns the channel received datagrams are delivered on, it is closed along with the transport
+	Packets() <-chan Packet
+	Close() error
+}
+
+// ErrClosed is returned when sending through a closed transport
+var ErrClosed = errors.New("transport closed")
+
+// packetBacklog is the number of received datagrams a transport holds before dropping new ones
+const packetBacklog = 256
+
+// Network connects members living in the same process, mostly for tests
+type Network struct {
+	endpoints map[string]*memoryTransport
+	down      map[string]bool
+	cut       map[[2]string]bool // links between two addresses dropping datagrams
+	mutex     sync.RWMutex
+}
+
+// NewNetwork returns an empty in-memory Network
+func NewNetwork() *Network {
+	return &Network{
+		endpoints: make(map[string]*memoryTransport),
+		down:      make(map[string]bool),
+		cut:       make(map[[2]string]bool),
+	}
+}
+
+// Listen returns the transport of the member at the given address
+func (n *Network) Listen(addr string) Transport {
+	t := &memoryTransport{network: n, addr: addr, packets: make(chan Packet, packetBacklog)}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.endpoints[addr] = t
+	return t
+}
+
+// SetDown drops every datagram from and to an address, or stops doing so
+func (n *Network) SetDown(addr string, down bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.down[addr] = down
+}
+
+// Cut drops every datagram between two addresses, in both directions, or stops doing so
+func (n *Network) Cut(a, b string, cut bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.cut[[2]string{a, b}] = cut
+	n.cut[[2]string{b, a}] = cut
+}
+
+type memoryTransport struct {
+	network *Network
+	addr    string
+	packets chan Packet
+	closed  bool
+	mutex   sync.Mutex
+}
+
+func (t *memoryTransport) Addr() string {
+	return t.addr
+}
+
+func (t *memoryTransport) Send(addr string, data []byte) error {
+	t.mutex.Lock()
+	closed := t.closed
+	t.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	t.network.mutex.RLock()
+	to, ok := t.network.endpoints[addr]
+	lost := t.network.down[addr] || t.network.down[t.addr] || t.network.cut[[2]string{t.addr, addr}]
+	t.network.mutex.RUnlock()
+	if !ok || lost {
+		// datagrams to unknown or unreachable addresses are silently lost, like over UDP
+		return nil
+	}
+	to.deliver(Packet{From: t.addr, Data: append([]byte{}, data...)})
+	return nil
+}
+
+func (t *memoryTransport) deliver(p Packet) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.closed {
+		return
+	}
+	select {
+	case t.packets <- p:
+	default:
+	}
+}
+
+func (t *memoryTransport) Packets() <-chan Packet {
+	return t.packets
+}
+
+func (t *memoryTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.packets)
+	}
+	return nil
+}
+
+// maxPacketSize is the largest datagram read from UDP
+const maxPacketSize = 64 << 10
+
+type udpTransport struct {
+	conn    net.PacketConn
+	packets chan Packet
+}
+
+// ListenUDP returns a transport sending and receiving datagrams on the given UDP address
+func ListenUDP(addr string) (Transport, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	t := &udpTransport{conn: conn, packets: make(chan Packet, packetBacklog)}
+	go t.read()
+	return t, nil
+}
+
+func (t *udpTransport) read() {
+	defer close(t.packets)
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		select {
+		case t.packets <- Packet{From: from.String(), Data: append([]byte{}, buf[:n]...)}:
+		default:
+		}
+	}
+}
+
+func (t *udpTransport) Addr() string {
+	return t.conn.LocalAddr().String()
+}
+
+func (t *udpTransport) Send(addr string, data []byte) error {
+	to, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	_, err = t.conn.WriteTo(data, to)
+	if errors.Is(err, net.ErrClosed) {
+		return ErrClosed
+	}
+	return err
+}
+
+func (t *udpTransport) Packets() <-chan Packet {
+	return t.packets
+}
+
+func (t *udpTransport) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,65 @@
+package merkle
+
+import (
+	"fmt"
+)
+
+// Reader gives access to a tree, possibly held by a remote replica
+type Reader interface {
+	Shape() (fanout int, depth int)
+	Root() (Hash, error)
+	Children(Node) ([]Hash, error)
+}
+
+// Diff walks down the branches where two trees differ, and returns the leaves whose content differs
+//
+// Only the entries of those leaves need to be exchanged for both replicas to converge
+func Diff(local, remote Reader) ([]int, error) {
+	fanout, depth := local.Shape()
+	remoteFanout, remoteDepth := remote.Shape()
+	if fanout != remoteFanout || depth != remoteDepth {
+		return nil, fmt.Errorf("cannot diff trees of different shapes, %dx%d and %dx%d", fanout, depth, remoteFanout, remoteDepth)
+	}
+
+	localRoot, err := local.Root()
+	if err != nil {
+		return nil, err
+	}
+	remoteRoot, err := remote.Root()
+	if err != nil {
+		return nil, err
+	}
+	if localRoot == remoteRoot {
+		return nil, nil
+	}
+
+	differing := []Node{{Level: 0, Index: 0}}
+	for level := 0; level < depth; level++ {
+		var next []Node
+		for _, n := range differing {
+			localChildren, err := local.Children(n)
+			if err != nil {
+				return nil, err
+			}
+			remoteChildren, err := remote.Children(n)
+			if err != nil {
+				return nil, err
+			}
+			if len(localChildren) != len(remoteChildren) {
+				return nil, fmt.Errorf("cannot diff node %d at level %d, children count mismatch", n.Index, n.Level)
+			}
+			for i := range localChildren {
+				if localChildren[i] != remoteChildren[i] {
+					next = append(next, Node{Level: level + 1, Index: n.Index*fanout + i})
+				}
+			}
+		}
+		differing = next
+	}
+
+	leaves := make([]int, 0, len(differing))
+	for _, n := range differing {
+		leaves = append(leaves, n.Index)
+	}
+	return leaves, nil
+}
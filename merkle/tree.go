@@ -0,0 +1,160 @@
+// Package merkle maintains hash trees summarizing the content of replicas, so two replicas can find
+// where they differ without transferring their whole state
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Hash is the digest of a tree node
+type Hash [sha256.Size]byte
+
+// String returns the hexadecimal form of the hash
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// MarshalText encodes the hash in hexadecimal, so it reads as a string in JSON
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText decodes a hash encoded by MarshalText
+func (h *Hash) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != len(h) {
+		return fmt.Errorf("invalid hash length: %d", len(text))
+	}
+	_, err := hex.Decode(h[:], text)
+	return err
+}
+
+// Node identifies a tree node by its level, the root being level 0, and its index within the level
+type Node struct {
+	Level int `json:"level"`
+	Index int `json:"index"`
+}
+
+// Tree is a Merkle tree of fixed fanout and depth: elements are spread over its leaves by hashing their encoding
+//
+// A leaf is the XOR of the hashes of its entries, so adding, replacing or dropping an entry is a constant time update.
+// Inner nodes hash the concatenation of their children and are recomputed lazily, when read
+type Tree struct {
+	fanout int
+	depth  int
+	levels [][]Hash // levels[depth] holds the leaves
+	dirty  [][]bool // inner nodes to recompute before being read
+	mutex  sync.Mutex
+}
+
+// DefaultFanout and DefaultDepth give 4096 leaves, which keeps buckets small for sets of millions of elements
+const (
+	DefaultFanout = 16
+	DefaultDepth  = 3
+)
+
+// NewTree returns an empty tree, fanout^depth being its number of leaves
+func NewTree(fanout, depth int) *Tree {
+	t := &Tree{
+		fanout: fanout,
+		depth:  depth,
+		levels: make([][]Hash, depth+1),
+		dirty:  make([][]bool, depth),
+	}
+	width := 1
+	for level := 0; level <= depth; level++ {
+		t.levels[level] = make([]Hash, width)
+		if level < depth {
+			t.dirty[level] = make([]bool, width)
+			for i := range t.dirty[level] {
+				t.dirty[level][i] = true
+			}
+		}
+		width *= fanout
+	}
+	return t
+}
+
+// Leaves returns the number of leaves of the tree
+func (t *Tree) Leaves() int {
+	return len(t.levels[t.depth])
+}
+
+// Leaf returns the leaf an element belongs to, given its encoding
+func (t *Tree) Leaf(key []byte) int {
+	h := fnv.New64a()
+	h.Write(key)
+	return int(h.Sum64() % uint64(t.Leaves()))
+}
+
+// EntryHash returns the hash of an element/timestamp association, tag tells apart the time sets sharing a tree
+func EntryHash(tag byte, key []byte, timestamp time.Time) Hash {
+	data := make([]byte, 9, len(key)+9)
+	data[0] = tag
+	binary.BigEndian.PutUint64(data[1:], uint64(timestamp.UnixNano()))
+	data = append(data, key...)
+	return sha256.Sum256(data)
+}
+
+// Toggle adds an entry hash to the leaf of its element, or drops it if it was already there
+func (t *Tree) Toggle(key []byte, entry Hash) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	index := t.Leaf(key)
+	leaf := &t.levels[t.depth][index]
+	for i := range leaf {
+		leaf[i] ^= entry[i]
+	}
+	for level := t.depth - 1; level >= 0; level-- {
+		index /= t.fanout
+		t.dirty[level][index] = true
+	}
+}
+
+// Root returns the hash of the root node, equal roots mean equal contents
+func (t *Tree) Root() (Hash, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.hash(0, 0), nil
+}
+
+// Children returns the hashes of the children of an inner node
+func (t *Tree) Children(n Node) ([]Hash, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if n.Level < 0 || n.Level >= t.depth || n.Index < 0 || n.Index >= len(t.levels[n.Level]) {
+		return nil, fmt.Errorf("cannot read children, no inner node %d at level %d", n.Index, n.Level)
+	}
+	children := make([]Hash, t.fanout)
+	for i := range children {
+		children[i] = t.hash(n.Level+1, n.Index*t.fanout+i)
+	}
+	return children, nil
+}
+
+// Shape returns the fanout and depth of the tree
+func (t *Tree) Shape() (int, int) {
+	return t.fanout, t.depth
+}
+
+// hash returns the hash of a node, recomputing it first if needed
+func (t *Tree) hash(level, index int) Hash {
+	if level == t.depth || !t.dirty[level][index] {
+		return t.levels[level][index]
+	}
+	h := sha256.New()
+	for i := 0; i < t.fanout; i++ {
+		child := t.hash(level+1, index*t.fanout+i)
+		h.Write(child[:])
+	}
+	copy(t.levels[level][index][:], h.Sum(nil))
+	t.dirty[level][index] = false
+	return t.levels[level][index]
+}
@@ -0,0 +1,130 @@
+package merkle_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt/merkle"
+)
+
+func TestTree_Toggle(t *testing.T) {
+	tree := merkle.NewTree(4, 2)
+	empty, _ := tree.Root()
+	entry := merkle.EntryHash(0, []byte("item1"), time.Unix(100, 0))
+	tree.Toggle([]byte("item1"), entry)
+	root, _ := tree.Root()
+	if root == empty {
+		t.Errorf("Root unchanged after adding an entry")
+	}
+	tree.Toggle([]byte("item1"), entry)
+	root, _ = tree.Root()
+	if root != empty {
+		t.Errorf("Root mismatch after dropping the only entry, got: %x, expected: %x.", root, empty)
+	}
+}
+
+func TestTree_OrderIndependence(t *testing.T) {
+	t1 := merkle.NewTree(4, 2)
+	t2 := merkle.NewTree(4, 2)
+	keys := []string{"item1", "item2", "item3", "item4"}
+	for i := range keys {
+		k1 := []byte(keys[i])
+		k2 := []byte(keys[len(keys)-1-i])
+		t1.Toggle(k1, merkle.EntryHash(0, k1, time.Unix(100, 0)))
+		t2.Toggle(k2, merkle.EntryHash(0, k2, time.Unix(100, 0)))
+	}
+	r1, _ := t1.Root()
+	r2, _ := t2.Root()
+	if r1 != r2 {
+		t.Errorf("Roots differ for the same entries added in a different order")
+	}
+}
+
+func TestEntryHash(t *testing.T) {
+	base := merkle.EntryHash(0, []byte("item1"), time.Unix(100, 0))
+	if base == merkle.EntryHash(1, []byte("item1"), time.Unix(100, 0)) {
+		t.Errorf("Tag ignored by the entry hash")
+	}
+	if base == merkle.EntryHash(0, []byte("item1"), time.Unix(101, 0)) {
+		t.Errorf("Timestamp ignored by the entry hash")
+	}
+	if base == merkle.EntryHash(0, []byte("item2"), time.Unix(100, 0)) {
+		t.Errorf("Element ignored by the entry hash")
+	}
+}
+
+func TestTree_Children(t *testing.T) {
+	tree := merkle.NewTree(4, 2)
+	children, err := tree.Children(merkle.Node{Level: 1, Index: 3})
+	if err != nil || len(children) != 4 {
+		t.Errorf("Children mismatch, got: %v, err: %v", len(children), err)
+	}
+	_, err = tree.Children(merkle.Node{Level: 2, Index: 0})
+	if err == nil {
+		t.Errorf("Expected error reading the children of a leaf")
+	}
+	_, err = tree.Children(merkle.Node{Level: 1, Index: 4})
+	if err == nil {
+		t.Errorf("Expected error reading the children of a missing node")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t1 := merkle.NewTree(4, 3)
+	t2 := merkle.NewTree(4, 3)
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("item%d", i))
+		entry := merkle.EntryHash(0, key, time.Unix(100, 0))
+		t1.Toggle(key, entry)
+		t2.Toggle(key, entry)
+	}
+	leaves, err := merkle.Diff(t1, t2)
+	if err != nil || len(leaves) != 0 {
+		t.Errorf("Diff of equal trees mismatch, got: %v, err: %v", leaves, err)
+	}
+
+	changed := [][]byte{[]byte("item7"), []byte("item42")}
+	expected := map[int]bool{}
+	for _, key := range changed {
+		t2.Toggle(key, merkle.EntryHash(0, key, time.Unix(200, 0)))
+		expected[t2.Leaf(key)] = true
+	}
+	leaves, err = merkle.Diff(t1, t2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(leaves) != len(expected) {
+		t.Errorf("Diff mismatch, got: %v, expected: %v.", leaves, expected)
+	}
+	for _, leaf := range leaves {
+		if !expected[leaf] {
+			t.Errorf("Unexpected differing leaf: %v", leaf)
+		}
+	}
+}
+
+func TestDiff_ShapeMismatch(t *testing.T) {
+	_, err := merkle.Diff(merkle.NewTree(4, 2), merkle.NewTree(4, 3))
+	if err == nil {
+		t.Errorf("Expected error diffing trees of different shapes")
+	}
+}
+
+func TestHash_Text(t *testing.T) {
+	h := merkle.EntryHash(0, []byte("item1"), time.Unix(100, 0))
+	text, err := h.MarshalText()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded merkle.Hash
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded != h {
+		t.Errorf("Hash changed after a round trip, got: %v, expected: %v.", decoded, h)
+	}
+	if err := decoded.UnmarshalText([]byte("abcd")); err == nil {
+		t.Errorf("Expected an error decoding a truncated hash")
+	}
+}
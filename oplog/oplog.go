@@ -0,0 +1,98 @@
+// Package oplog records the operations applied to CRDT replicas, so they can be replicated and audited
+package oplog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Type identifies the mutation carried by an operation
+type Type uint8
+
+const (
+	// AddOp adds an element to a set, or a vertex to a graph
+	AddOp Type = iota + 1
+	// RemoveOp removes an element from a set, or a vertex from a graph
+	RemoveOp
+	// AddEdgeOp adds an edge between two vertices of a graph
+	AddEdgeOp
+	// RemoveEdgeOp removes an edge between two vertices of a graph
+	RemoveEdgeOp
+)
+
+func (t Type) String() string {
+	switch t {
+	case AddOp:
+		return "add"
+	case RemoveOp:
+		return "remove"
+	case AddEdgeOp:
+		return "add_edge"
+	case RemoveEdgeOp:
+		return "remove_edge"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// Op is a single mutation of a replica
+type Op[T any] struct {
+	Type Type `json:"type"`
+	// Element is the element of set operations, or the vertex of vertex operations, or the first vertex of edge operations
+	Element T `json:"element"`
+	// Target is the second vertex of edge operations
+	Target    T         `json:"target"`
+	Timestamp time.Time `json:"timestamp"`
+	// Origin identifies the replica the operation was first applied to
+	Origin string `json:"origin"`
+}
+
+// Log is an append-only log of the operations of a replica, addressed by offset
+type Log[T any] struct {
+	origin string
+	ops    []Op[T]
+	mutex  sync.RWMutex
+}
+
+// NewLog returns an empty log recording the operations of the given origin replica
+func NewLog[T any](origin string) *Log[T] {
+	return &Log[T]{origin: origin}
+}
+
+// Origin returns the replica the log records operations for
+func (l *Log[T]) Origin() string {
+	return l.origin
+}
+
+// Record appends an operation of the origin replica to the log, and returns its offset
+func (l *Log[T]) Record(t Type, element, target T, timestamp time.Time) uint64 {
+	return l.Append(Op[T]{Type: t, Element: element, Target: target, Timestamp: timestamp, Origin: l.origin})
+}
+
+// Append appends an operation to the log as-is, and returns its offset
+func (l *Log[T]) Append(op Op[T]) uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.ops = append(l.ops, op)
+	return uint64(len(l.ops) - 1)
+}
+
+// Read returns every operation from the given offset on, a lagging replica catches up by applying them
+// and reading again from offset+len(ops)
+func (l *Log[T]) Read(offset uint64) []Op[T] {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if offset >= uint64(len(l.ops)) {
+		return nil
+	}
+	ops := make([]Op[T], len(l.ops)-int(offset))
+	copy(ops, l.ops[offset:])
+	return ops
+}
+
+// Len returns the number of operations in the log, which is also the offset of the next one
+func (l *Log[T]) Len() uint64 {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return uint64(len(l.ops))
+}
@@ -0,0 +1,70 @@
+package oplog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt/oplog"
+)
+
+func TestLog_Record(t *testing.T) {
+	l := oplog.NewLog[string]("replica1")
+	now := time.Now()
+	offset := l.Record(oplog.AddOp, "item1", "", now)
+	if offset != 0 {
+		t.Errorf("Offset mismatch, got: %v, expected: %v.", offset, 0)
+	}
+	offset = l.Record(oplog.AddEdgeOp, "vertex1", "vertex2", now)
+	if offset != 1 || l.Len() != 2 {
+		t.Errorf("Offset mismatch, got: %v, expected: %v.", offset, 1)
+	}
+	ops := l.Read(0)
+	expected := oplog.Op[string]{Type: oplog.AddEdgeOp, Element: "vertex1", Target: "vertex2", Timestamp: now, Origin: "replica1"}
+	if len(ops) != 2 || ops[1] != expected {
+		t.Errorf("Operation mismatch, got: %v, expected: %v.", ops, expected)
+	}
+}
+
+func TestLog_Read(t *testing.T) {
+	l := oplog.NewLog[string]("replica1")
+	for _, item := range []string{"item1", "item2", "item3"} {
+		l.Record(oplog.AddOp, item, "", time.Now())
+	}
+	tests := []struct {
+		offset   uint64
+		expected []string
+	}{
+		{0, []string{"item1", "item2", "item3"}},
+		{2, []string{"item3"}},
+		{3, nil},
+		{10, nil},
+	}
+	for _, tt := range tests {
+		ops := l.Read(tt.offset)
+		if len(ops) != len(tt.expected) {
+			t.Errorf("Read from %v mismatch, got: %v, expected: %v.", tt.offset, ops, tt.expected)
+			continue
+		}
+		for i, op := range ops {
+			if op.Element != tt.expected[i] {
+				t.Errorf("Read from %v mismatch, got: %v, expected: %v.", tt.offset, ops, tt.expected)
+			}
+		}
+	}
+}
+
+func TestLog_ReadIsACopy(t *testing.T) {
+	l := oplog.NewLog[string]("replica1")
+	l.Record(oplog.AddOp, "item1", "", time.Now())
+	ops := l.Read(0)
+	ops[0].Element = "changed"
+	if l.Read(0)[0].Element != "item1" {
+		t.Errorf("Read exposed the log internals")
+	}
+}
+
+func TestType_String(t *testing.T) {
+	if oplog.RemoveEdgeOp.String() != "remove_edge" || oplog.Type(42).String() != "unknown(42)" {
+		t.Errorf("Type names mismatch, got: %v and %v", oplog.RemoveEdgeOp, oplog.Type(42))
+	}
+}
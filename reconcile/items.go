@@ -0,0 +1,131 @@
+package reconcile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/bjornaer/crdt"
+)
+
+// item is the state of a single element in both time sets of a set, keyed by the element encoding
+type item struct {
+	key       []byte
+	added     bool
+	addedAt   int64 // nanoseconds since the Unix epoch
+	removed   bool
+	removedAt int64
+}
+
+// equal checks if two items carry the same timestamps
+func (i item) equal(other item) bool {
+	return i.added == other.added && i.addedAt == other.addedAt && i.removed == other.removed && i.removedAt == other.removedAt
+}
+
+// hash returns the digest of an item, fingerprints XOR them together
+func (i item) hash() [sha256.Size]byte {
+	data := make([]byte, 18, 18+len(i.key))
+	if i.added {
+		data[0] = 1
+		binary.BigEndian.PutUint64(data[1:9], uint64(i.addedAt))
+	}
+	if i.removed {
+		data[9] = 1
+		binary.BigEndian.PutUint64(data[10:18], uint64(i.removedAt))
+	}
+	return sha256.Sum256(append(data, i.key...))
+}
+
+// snapshot returns the items of a set sorted by key
+func snapshot[T comparable](s crdt.LastWriterWinsSet[T]) ([]item, error) {
+	c := s.GetCodec()
+	byKey := make(map[string]*item)
+	get := func(element T) (*item, error) {
+		key, err := c.Encode(element)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := byKey[string(key)]
+		if !ok {
+			i = &item{key: key}
+			byKey[string(key)] = i
+		}
+		return i, nil
+	}
+	err := s.GetAdditions().Each(func(element T, addedAt time.Time) error {
+		i, err := get(element)
+		if err != nil {
+			return err
+		}
+		i.added, i.addedAt = true, addedAt.UnixNano()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.GetRemovals().Each(func(element T, removedAt time.Time) error {
+		i, err := get(element)
+		if err != nil {
+			return err
+		}
+		i.removed, i.removedAt = true, removedAt.UnixNano()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]item, 0, len(byKey))
+	for _, i := range byKey {
+		items = append(items, *i)
+	}
+	sort.Slice(items, func(a, b int) bool {
+		return bytes.Compare(items[a].key, items[b].key) < 0
+	})
+	return items, nil
+}
+
+// bound is a range boundary, the zero value being the infinite upper bound
+type bound struct {
+	key      []byte
+	infinite bool
+}
+
+// below checks if a key sorts before the bound
+func (b bound) below(key []byte) bool {
+	return b.infinite || bytes.Compare(key, b.key) < 0
+}
+
+// span returns the items of a sorted slice within [lower, upper)
+func span(items []item, lower, upper bound) []item {
+	start := sort.Search(len(items), func(i int) bool {
+		return bytes.Compare(items[i].key, lower.key) >= 0
+	})
+	end := sort.Search(len(items), func(i int) bool {
+		return !upper.below(items[i].key)
+	})
+	if end < start {
+		end = start
+	}
+	return items[start:end]
+}
+
+// fingerprint summarizes a list of items
+type fingerprint struct {
+	hash  [sha256.Size]byte
+	count uint64
+}
+
+func fingerprintOf(items []item) fingerprint {
+	var fp fingerprint
+	for _, i := range items {
+		h := i.hash()
+		for b := range fp.hash {
+			fp.hash[b] ^= h[b]
+		}
+	}
+	fp.count = uint64(len(items))
+	return fp
+}
@@ -0,0 +1,190 @@
+package reconcile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// mode tells how a range is described in a message
+type mode byte
+
+const (
+	// fingerprintMode carries a fingerprint, the receiver compares it with its own
+	fingerprintMode mode = iota + 1
+	// itemsMode carries every item of the range, the receiver answers with the items the sender lacks
+	itemsMode
+	// finalItemsMode carries the items the receiver lacks, nothing is expected in return
+	finalItemsMode
+)
+
+// rangeRecord describes the items of a replica within [lower, upper)
+type rangeRecord struct {
+	lower       bound
+	upper       bound
+	mode        mode
+	fingerprint fingerprint
+	items       []item
+}
+
+func writeBytes(buf *bytes.Buffer, data []byte) {
+	var size [binary.MaxVarintLen64]byte
+	buf.Write(size[:binary.PutUvarint(size[:], uint64(len(data)))])
+	buf.Write(data)
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var data [binary.MaxVarintLen64]byte
+	buf.Write(data[:binary.PutUvarint(data[:], v)])
+}
+
+func writeBound(buf *bytes.Buffer, b bound) {
+	if b.infinite {
+		buf.WriteByte(1)
+		return
+	}
+	buf.WriteByte(0)
+	writeBytes(buf, b.key)
+}
+
+func writeItem(buf *bytes.Buffer, i item) {
+	writeBytes(buf, i.key)
+	var flags byte
+	if i.added {
+		flags |= 1
+	}
+	if i.removed {
+		flags |= 2
+	}
+	buf.WriteByte(flags)
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(i.addedAt))
+	buf.Write(ts[:])
+	binary.BigEndian.PutUint64(ts[:], uint64(i.removedAt))
+	buf.Write(ts[:])
+}
+
+// encode serializes a message, an empty message ends the reconciliation
+func encode(records []rangeRecord) []byte {
+	var buf bytes.Buffer
+	for _, r := range records {
+		writeBound(&buf, r.lower)
+		writeBound(&buf, r.upper)
+		buf.WriteByte(byte(r.mode))
+		switch r.mode {
+		case fingerprintMode:
+			buf.Write(r.fingerprint.hash[:])
+			writeUvarint(&buf, r.fingerprint.count)
+		case itemsMode, finalItemsMode:
+			writeUvarint(&buf, uint64(len(r.items)))
+			for _, i := range r.items {
+				writeItem(&buf, i)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+var errMalformed = errors.New("cannot decode reconciliation message, malformed input")
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errMalformed
+	}
+	if size > uint64(r.Len()) {
+		return nil, errMalformed
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		return nil, errMalformed
+	}
+	return data, nil
+}
+
+func readBound(r *bytes.Reader) (bound, error) {
+	flag, err := r.ReadByte()
+	if err != nil {
+		return bound{}, errMalformed
+	}
+	if flag == 1 {
+		return bound{infinite: true}, nil
+	}
+	key, err := readBytes(r)
+	return bound{key: key}, err
+}
+
+func readItem(r *bytes.Reader) (item, error) {
+	var i item
+	var err error
+	i.key, err = readBytes(r)
+	if err != nil {
+		return i, err
+	}
+	flags, err := r.ReadByte()
+	if err != nil {
+		return i, errMalformed
+	}
+	var ts [16]byte
+	_, err = io.ReadFull(r, ts[:])
+	if err != nil {
+		return i, errMalformed
+	}
+	i.added, i.addedAt = flags&1 != 0, int64(binary.BigEndian.Uint64(ts[:8]))
+	i.removed, i.removedAt = flags&2 != 0, int64(binary.BigEndian.Uint64(ts[8:]))
+	return i, nil
+}
+
+// decode parses a message
+func decode(data []byte) ([]rangeRecord, error) {
+	r := bytes.NewReader(data)
+	var records []rangeRecord
+	for r.Len() > 0 {
+		var rec rangeRecord
+		var err error
+		rec.lower, err = readBound(r)
+		if err != nil {
+			return nil, err
+		}
+		rec.upper, err = readBound(r)
+		if err != nil {
+			return nil, err
+		}
+		m, err := r.ReadByte()
+		if err != nil {
+			return nil, errMalformed
+		}
+		rec.mode = mode(m)
+		switch rec.mode {
+		case fingerprintMode:
+			_, err = io.ReadFull(r, rec.fingerprint.hash[:])
+			if err != nil {
+				return nil, errMalformed
+			}
+			rec.fingerprint.count, err = binary.ReadUvarint(r)
+			if err != nil {
+				return nil, errMalformed
+			}
+		case itemsMode, finalItemsMode:
+			count, err := binary.ReadUvarint(r)
+			if err != nil || count > uint64(r.Len()) {
+				return nil, errMalformed
+			}
+			rec.items = make([]item, 0, count)
+			for n := uint64(0); n < count; n++ {
+				i, err := readItem(r)
+				if err != nil {
+					return nil, err
+				}
+				rec.items = append(rec.items, i)
+			}
+		default:
+			return nil, fmt.Errorf("cannot decode reconciliation message, unknown range mode: %d", m)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,162 @@
+// Package reconcile implements range-based set reconciliation between two LastWriterWinsSet replicas
+//
+// Elements are ordered by their codec encoding. Replicas exchange fingerprints of key ranges, ranges with
+// matching fingerprints are settled, differing ones are split recursively until they are small enough to
+// exchange their items outright. The protocol is a state machine over byte messages, decoupled from transport
+package reconcile
+
+import (
+	"time"
+
+	"github.com/bjornaer/crdt"
+)
+
+const (
+	// itemsThreshold is the number of items under which a differing range is sent outright rather than split
+	itemsThreshold = 16
+	// branching is the number of sub-ranges a differing range is split into
+	branching = 16
+)
+
+// Session is one side of a reconciliation
+type Session[T comparable] struct {
+	set   crdt.LastWriterWinsSet[T]
+	items []item // snapshot of the set taken when the session started, sorted by key
+	done  bool
+}
+
+// NewSession snapshots a set to reconcile it with a peer, items received from the peer are merged into the set
+func NewSession[T comparable](s crdt.LastWriterWinsSet[T]) (*Session[T], error) {
+	items, err := snapshot(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Session[T]{set: s, items: items}, nil
+}
+
+// Initiate returns the first message of the reconciliation, sent by the side starting it
+func (s *Session[T]) Initiate() []byte {
+	full := rangeRecord{
+		lower:       bound{key: []byte{}},
+		upper:       bound{infinite: true},
+		mode:        fingerprintMode,
+		fingerprint: fingerprintOf(s.items),
+	}
+	return encode([]rangeRecord{full})
+}
+
+// Done reports whether the reconciliation is over
+func (s *Session[T]) Done() bool {
+	return s.done
+}
+
+// Next processes a message from the peer and returns the reply to send back
+//
+// An empty reply means this side has nothing left to ask for: it still has to be sent, so the peer knows
+// the reconciliation is over. Receiving an empty message ends the session
+func (s *Session[T]) Next(message []byte) ([]byte, error) {
+	if len(message) == 0 {
+		s.done = true
+		return nil, nil
+	}
+	records, err := decode(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply []rangeRecord
+	for _, rec := range records {
+		own := span(s.items, rec.lower, rec.upper)
+		switch rec.mode {
+		case fingerprintMode:
+			if fingerprintOf(own) == rec.fingerprint {
+				continue
+			}
+			if len(own) <= itemsThreshold {
+				reply = append(reply, rangeRecord{lower: rec.lower, upper: rec.upper, mode: itemsMode, items: own})
+				continue
+			}
+			reply = append(reply, split(own, rec.lower, rec.upper)...)
+		case itemsMode:
+			err = s.merge(rec.items)
+			if err != nil {
+				return nil, err
+			}
+			missing := missingFrom(own, rec.items)
+			if len(missing) > 0 {
+				reply = append(reply, rangeRecord{lower: rec.lower, upper: rec.upper, mode: finalItemsMode, items: missing})
+			}
+		case finalItemsMode:
+			err = s.merge(rec.items)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(reply) == 0 {
+		s.done = true
+	}
+	return encode(reply), nil
+}
+
+// split divides a range into sub-ranges holding a similar number of items, and fingerprints each of them
+func split(own []item, lower, upper bound) []rangeRecord {
+	records := make([]rangeRecord, 0, branching)
+	start := 0
+	for k := 1; k <= branching; k++ {
+		end := k * len(own) / branching
+		sub := rangeRecord{lower: lower, mode: fingerprintMode, fingerprint: fingerprintOf(own[start:end])}
+		if k == branching {
+			sub.upper = upper
+		} else {
+			sub.upper = bound{key: own[end].key}
+		}
+		records = append(records, sub)
+		lower, start = sub.upper, end
+	}
+	return records
+}
+
+// missingFrom returns the own items carrying timestamps the peer items lack
+func missingFrom(own, peer []item) []item {
+	byKey := make(map[string]item, len(peer))
+	for _, i := range peer {
+		byKey[string(i.key)] = i
+	}
+	var missing []item
+	for _, i := range own {
+		p, ok := byKey[string(i.key)]
+		if !ok || (i.added && (!p.added || i.addedAt > p.addedAt)) || (i.removed && (!p.removed || i.removedAt > p.removedAt)) {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// merge merges items received from the peer into the set
+func (s *Session[T]) merge(items []item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	c := s.set.GetCodec()
+	delta := crdt.NewLWWSet(crdt.WithCodec[T](c))
+	for _, i := range items {
+		element, err := c.Decode(i.key)
+		if err != nil {
+			return err
+		}
+		if i.added {
+			err = delta.Add(element, time.Unix(0, i.addedAt))
+			if err != nil {
+				return err
+			}
+		}
+		if i.removed {
+			err = delta.Remove(element, time.Unix(0, i.removedAt))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return s.set.Merge(delta)
+}
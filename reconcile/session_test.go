@@ -0,0 +1,178 @@
+package reconcile_test
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/reconcile"
+)
+
+// sameState checks that two sets hold the same timestamps for every element, in both time sets
+func sameState[T comparable](s1, s2 crdt.LastWriterWinsSet[T]) error {
+	compare := func(name string, get1, get2 func() map[T]time.Time) error {
+		m1, m2 := get1(), get2()
+		if len(m1) != len(m2) {
+			return fmt.Errorf("%s size mismatch, %d and %d", name, len(m1), len(m2))
+		}
+		for element, t1 := range m1 {
+			if t2, ok := m2[element]; !ok || !t1.Equal(t2) {
+				return fmt.Errorf("%s mismatch for %v", name, element)
+			}
+		}
+		return nil
+	}
+	entries := func(s crdt.LastWriterWinsSet[T], removals bool) func() map[T]time.Time {
+		return func() map[T]time.Time {
+			ts := s.GetAdditions()
+			if removals {
+				ts = s.GetRemovals()
+			}
+			m := make(map[T]time.Time)
+			ts.Each(func(element T, at time.Time) error {
+				m[element] = at
+				return nil
+			})
+			return m
+		}
+	}
+	err := compare("additions", entries(s1, false), entries(s2, false))
+	if err != nil {
+		return err
+	}
+	return compare("removals", entries(s1, true), entries(s2, true))
+}
+
+// divergentSets returns two sets sharing a common history, each with its own random mutations on top
+func divergentSets(random *rand.Rand, common, divergent int) (crdt.LastWriterWinsSet[int], crdt.LastWriterWinsSet[int]) {
+	s1 := crdt.NewLWWSet[int]()
+	s2 := crdt.NewLWWSet[int]()
+	epoch := time.Unix(1650000000, 0)
+	for i := 0; i < common; i++ {
+		element := random.Intn(common * 2)
+		at := epoch.Add(time.Duration(random.Intn(1000)) * time.Second)
+		s1.Add(element, at)
+		s2.Add(element, at)
+	}
+	for _, s := range []crdt.LastWriterWinsSet[int]{s1, s2} {
+		for i := 0; i < divergent; i++ {
+			element := random.Intn(common*2 + 10)
+			at := epoch.Add(time.Duration(random.Intn(2000)) * time.Second)
+			if random.Intn(3) == 0 {
+				s.Remove(element, at)
+			} else {
+				s.Add(element, at)
+			}
+		}
+	}
+	return s1, s2
+}
+
+// exchange runs the state machines of both sides until the reconciliation is over, and counts the messages
+func exchange[T comparable](t *testing.T, s1, s2 crdt.LastWriterWinsSet[T]) int {
+	initiator, err := reconcile.NewSession(s1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	responder, err := reconcile.NewSession(s2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	message := initiator.Initiate()
+	sides := []*reconcile.Session[T]{responder, initiator}
+	messages := 1
+	for turn := 0; !initiator.Done() || !responder.Done(); turn++ {
+		if turn > 100 {
+			t.Fatalf("Reconciliation did not terminate")
+		}
+		// only non-empty messages get a reply, empty ones end the session of their receiver
+		if len(message) > 0 {
+			messages++
+		}
+		message, err = sides[turn%2].Next(message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	return messages
+}
+
+func TestSession_RandomConvergence(t *testing.T) {
+	tests := []struct {
+		common, divergent int
+	}{
+		{0, 0},
+		{0, 50},
+		{10, 1},
+		{100, 5},
+		{1000, 3},
+		{1000, 300},
+		{5000, 20},
+	}
+	for seed := int64(0); seed < 5; seed++ {
+		random := rand.New(rand.NewSource(seed))
+		for _, tt := range tests {
+			s1, s2 := divergentSets(random, tt.common, tt.divergent)
+			exchange(t, s1, s2)
+			err := sameState(s1, s2)
+			if err != nil {
+				t.Errorf("Replicas diverged with seed %d, %d common and %d divergent: %v", seed, tt.common, tt.divergent, err)
+			}
+		}
+	}
+}
+
+func TestSession_OneSideEmpty(t *testing.T) {
+	s1 := crdt.NewLWWSet[string]()
+	s2 := crdt.NewLWWSet[string]()
+	for i := 0; i < 500; i++ {
+		s2.Add(fmt.Sprintf("item%d", i), time.Now())
+	}
+	exchange(t, s1, s2)
+	if err := sameState(s1, s2); err != nil {
+		t.Errorf("Replicas diverged: %v", err)
+	}
+}
+
+func TestSession_EqualSets(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	s1, s2 := divergentSets(random, 1000, 0)
+	if messages := exchange(t, s1, s2); messages != 2 {
+		t.Errorf("Equal sets should settle in a single round trip, got %d messages", messages)
+	}
+}
+
+func TestSession_MalformedMessage(t *testing.T) {
+	session, _ := reconcile.NewSession(crdt.NewLWWSet[string]())
+	_, err := session.Next([]byte{0, 5, 1})
+	if err == nil {
+		t.Errorf("Expected error processing a malformed message")
+	}
+}
+
+func TestRun_Stream(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+	s1, s2 := divergentSets(random, 2000, 100)
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- reconcile.Run(s2, c2, false)
+	}()
+	err := reconcile.Run(s1, c1, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = <-errs
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sameState(s1, s2); err != nil {
+		t.Errorf("Replicas diverged: %v", err)
+	}
+}
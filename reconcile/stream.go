@@ -0,0 +1,73 @@
+package reconcile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/bjornaer/crdt"
+)
+
+// maxFrameSize bounds the size of a single message read from a stream
+const maxFrameSize = 64 << 20
+
+// WriteFrame writes a message to a stream, prefixed with its length
+func WriteFrame(w io.Writer, message []byte) error {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(message)))
+	_, err := w.Write(append(size[:], message...))
+	return err
+}
+
+// ReadFrame reads a message written by WriteFrame
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	_, err := io.ReadFull(r, size[:])
+	if err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return nil, fmt.Errorf("cannot read frame, %d bytes exceeds the maximum size", n)
+	}
+	message := make([]byte, n)
+	_, err = io.ReadFull(r, message)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// Run reconciles a set with a peer over a byte stream, exactly one of both sides being the initiator
+func Run[T comparable](s crdt.LastWriterWinsSet[T], rw io.ReadWriter, initiator bool) error {
+	session, err := NewSession(s)
+	if err != nil {
+		return err
+	}
+	if initiator {
+		err = WriteFrame(rw, session.Initiate())
+		if err != nil {
+			return err
+		}
+	}
+	for {
+		message, err := ReadFrame(rw)
+		if err != nil {
+			return err
+		}
+		reply, err := session.Next(message)
+		if err != nil {
+			return err
+		}
+		if len(message) == 0 {
+			return nil
+		}
+		err = WriteFrame(rw, reply)
+		if err != nil {
+			return err
+		}
+		if session.Done() {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package replication_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/replication"
+)
+
+// cluster is a set of replicators gossiping over an in-memory network, each holding a set named "items"
+type cluster struct {
+	nodes []*node
+}
+
+func newCluster(size, fanout int, seed int64, opts ...set.Option[string]) *cluster {
+	network := replication.NewNetwork()
+	c := &cluster{}
+	for i := 0; i < size; i++ {
+		var peers []string
+		for j := 0; j < size; j++ {
+			if j != i {
+				peers = append(peers, fmt.Sprint(j))
+			}
+		}
+		self := fmt.Sprint(i)
+		n := &node{
+			set:       crdt.NewLWWSet(opts...),
+			transport: &recorder{Transport: network.Transport(self)},
+		}
+		n.replicator = replication.New(self, n.transport,
+			replication.WithPeers(peers...),
+			replication.WithFanout(fanout),
+			replication.WithRand(rand.New(rand.NewSource(seed+int64(i)))),
+		)
+		n.replicator.Register("items", replication.Set(n.set, time.Minute))
+		network.Register(self, n.replicator)
+		c.nodes = append(c.nodes, n)
+	}
+	return c
+}
+
+// round makes every node gossip once
+func (c *cluster) round(t testing.TB) {
+	for _, n := range c.nodes {
+		if err := n.replicator.SyncOnce(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func (c *cluster) converged(expected int) bool {
+	for _, n := range c.nodes {
+		items, _ := n.set.Get()
+		if len(items) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// simulate seeds every node of a cluster with an element of its own, and returns the number of gossip rounds
+// until every node holds every element
+func simulate(t testing.TB, size, fanout int, seed int64) int {
+	c := newCluster(size, fanout, seed, crdt.WithDigest[string]())
+	for i, n := range c.nodes {
+		n.set.Add(fmt.Sprint("item", i), time.Now())
+	}
+	for rounds := 1; rounds <= size; rounds++ {
+		c.round(t)
+		if c.converged(size) {
+			return rounds
+		}
+	}
+	t.Fatalf("Cluster of %d nodes did not converge in %d rounds", size, size)
+	return 0
+}
+
+// checks gossip converges in a number of rounds logarithmic in the size of the cluster
+func TestGossip_Convergence(t *testing.T) {
+	for _, size := range []int{8, 32, 64} {
+		rounds := simulate(t, size, 2, 42)
+		bound := int(2*math.Log2(float64(size))) + 2
+		t.Logf("%d nodes converged in %d rounds", size, rounds)
+		if rounds > bound {
+			t.Errorf("Slow convergence for %d nodes, got: %d rounds, expected at most: %d.", size, rounds, bound)
+		}
+	}
+}
+
+// checks changes keep spreading epidemically once replicas only exchange deltas, received deltas being relayed
+func TestGossip_Relay(t *testing.T) {
+	size := 16
+	// without digests replicas always exchange deltas, so every node gets to meet every other one
+	c := newCluster(size, 1, 3)
+	c.nodes[0].set.Add("item", time.Now())
+	for i := 0; i < 10*size; i++ {
+		c.round(t)
+	}
+
+	c.nodes[size-1].set.Add("late", time.Now())
+	for _, n := range c.nodes {
+		n.transport.requests = nil
+	}
+	rounds := 0
+	for !c.converged(2) {
+		c.round(t)
+		rounds++
+		if rounds > size {
+			t.Fatalf("Cluster did not converge in %d rounds", size)
+		}
+	}
+	for _, n := range c.nodes {
+		for _, req := range n.transport.requests {
+			if req.Changes.Full {
+				t.Fatalf("Unexpected full state once every node met every other one")
+			}
+		}
+	}
+	if bound := int(2*math.Log2(float64(size))) + 2; rounds > bound {
+		t.Errorf("Slow convergence from deltas, got: %d rounds, expected at most: %d.", rounds, bound)
+	}
+}
+
+// checks a round only involves fanout peers, and converged replicas only compare their digests
+func TestGossip_Fanout(t *testing.T) {
+	c := newCluster(10, 3, 7, crdt.WithDigest[string]())
+	c.nodes[0].set.Add("item", time.Now())
+	if err := c.nodes[0].replicator.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var reached int
+	for _, n := range c.nodes[1:] {
+		if n.set.Exists("item") {
+			reached++
+		}
+	}
+	if reached != 3 {
+		t.Errorf("Unexpected number of peers reached, got: %d, expected: %d.", reached, 3)
+	}
+
+	for !c.converged(1) {
+		c.round(t)
+	}
+	for _, n := range c.nodes {
+		n.transport.requests = nil
+	}
+	c.round(t)
+	for _, n := range c.nodes {
+		for _, req := range n.transport.requests {
+			if req.Kind != replication.Compare {
+				t.Errorf("Unexpected %v request between converged replicas", req.Kind)
+			}
+		}
+	}
+}
+
+func BenchmarkGossip(b *testing.B) {
+	for _, size := range []int{16, 64} {
+		for _, fanout := range []int{1, 2, 4} {
+			b.Run(fmt.Sprintf("nodes=%d/fanout=%d", size, fanout), func(b *testing.B) {
+				total := 0
+				for i := 0; i < b.N; i++ {
+					total += simulate(b, size, fanout, int64(i))
+				}
+				b.ReportMetric(float64(total)/float64(b.N), "rounds")
+			})
+		}
+	}
+}
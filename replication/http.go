@@ -0,0 +1,327 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bjornaer/crdt/merkle"
+)
+
+// DigestSummary describes the digest tree of a replica, as served by the digest endpoint
+type DigestSummary struct {
+	Fanout int         `json:"fanout"`
+	Depth  int         `json:"depth"`
+	Root   merkle.Hash `json:"root"`
+}
+
+// maxBodySize bounds the payloads read by the HTTP handler and transport
+const maxBodySize = 64 << 20
+
+// NewHandler returns an http.Handler serving the sync endpoints of the replicas registered with r:
+//
+//	GET  /{name}/state                      full state of the replica
+//	GET  /{name}/delta?from={id}            changes the peer did not acknowledge yet
+//	POST /{name}/ack?from={id}&since={n}    records the sequence the peer last received, 0 to get the full state next
+//	POST /{name}/delta?from={id}            merges the changes in the body, replies with the acknowledged sequence
+//	GET  /{name}/digest                     shape and root of the digest tree
+//	GET  /{name}/digest?level={l}&index={i} children of a node of the digest tree
+//
+// Changes travel as JSON encoded Changes, the handler is meant to be mounted with http.StripPrefix. GET requests
+// have no effect on what peers acknowledged, so they can be retried or prefetched
+func NewHandler(r *Replicator) http.Handler {
+	return &handler{replicator: r}
+}
+
+type handler struct {
+	replicator *Replicator
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(req.URL.Path, "/")
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		http.NotFound(w, req)
+		return
+	}
+	name, endpoint := path[:i], path[i+1:]
+	query := req.URL.Query()
+
+	var body interface{}
+	var err error
+	switch {
+	case endpoint == "state" && req.Method == http.MethodGet:
+		body, err = h.state(name)
+	case endpoint == "delta" && req.Method == http.MethodGet:
+		body, err = h.changes(name, query)
+	case endpoint == "ack" && req.Method == http.MethodPost:
+		body, err = h.ack(name, query)
+	case endpoint == "delta" && req.Method == http.MethodPost:
+		body, err = h.push(req.Context(), name, query, req.Body)
+	case endpoint == "digest" && req.Method == http.MethodGet:
+		body, err = h.digest(name, query)
+	case endpoint == "state" || endpoint == "delta" || endpoint == "ack" || endpoint == "digest":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, req)
+		return
+	}
+	if err != nil {
+		if code := errorCode(err); code != "" {
+			w.Header().Set(errorHeader, code)
+		}
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	// the status line is already written at this point, an encoding failure can only truncate the body
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// errorHeader carries the code of the error a response of the handler reports, see errorCode
+const errorHeader = "X-Replication-Error"
+
+// errBadRequest marks errors caused by malformed requests
+var errBadRequest = errors.New("bad request")
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownReplica), errors.Is(err, errNoDigest):
+		return http.StatusNotFound
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *handler) state(name string) (Changes, error) {
+	replica, err := h.replicator.replica(name)
+	if err != nil {
+		return Changes{}, err
+	}
+	return replica.State()
+}
+
+func (h *handler) changes(name string, query url.Values) (Changes, error) {
+	replica, err := h.replicator.replica(name)
+	if err != nil {
+		return Changes{}, err
+	}
+	return replica.Changes(query.Get("from"))
+}
+
+func (h *handler) ack(name string, query url.Values) (struct{}, error) {
+	replica, err := h.replicator.replica(name)
+	if err != nil {
+		return struct{}{}, err
+	}
+	since, err := strconv.ParseUint(query.Get("since"), 10, 64)
+	if err != nil {
+		return struct{}{}, fmt.Errorf("%w: invalid since: %v", errBadRequest, err)
+	}
+	acknowledge(replica, query.Get("from"), since)
+	return struct{}{}, nil
+}
+
+func (h *handler) push(ctx context.Context, name string, query url.Values, body io.Reader) (Changes, error) {
+	var changes Changes
+	if err := json.NewDecoder(io.LimitReader(body, maxBodySize)).Decode(&changes); err != nil {
+		return Changes{}, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	resp, err := h.replicator.Handle(ctx, Request{Kind: Push, From: query.Get("from"), Name: name, Changes: changes})
+	return resp.Changes, err
+}
+
+// errNoDigest is returned for replicas that do not maintain a digest
+var errNoDigest = errors.New("replica does not maintain a digest")
+
+func (h *handler) digest(name string, query url.Values) (interface{}, error) {
+	replica, err := h.replicator.replica(name)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := replica.(Digester)
+	if !ok || d.Digest() == nil {
+		return nil, fmt.Errorf("%w: %s", errNoDigest, name)
+	}
+	tree := d.Digest()
+
+	if !query.Has("level") && !query.Has("index") {
+		root, err := tree.Root()
+		if err != nil {
+			return nil, err
+		}
+		fanout, depth := tree.Shape()
+		return DigestSummary{Fanout: fanout, Depth: depth, Root: root}, nil
+	}
+	level, err := strconv.Atoi(query.Get("level"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid level: %v", errBadRequest, err)
+	}
+	index, err := strconv.Atoi(query.Get("index"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid index: %v", errBadRequest, err)
+	}
+	children, err := tree.Children(merkle.Node{Level: level, Index: index})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	return children, nil
+}
+
+// HTTPTransport calls peers served by NewHandler, peers are addressed by the base URL the handler is mounted at
+type HTTPTransport struct {
+	client *http.Client
+}
+
+// NewHTTPTransport returns a transport sending requests with the given client, or http.DefaultClient if nil
+func NewHTTPTransport(client *http.Client) *HTTPTransport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPTransport{client: client}
+}
+
+// Call sends a request to the peer at the given base URL
+func (t *HTTPTransport) Call(ctx context.Context, peer string, req Request) (Response, error) {
+	query := url.Values{"from": {req.From}}
+	var changes Changes
+	var err error
+	switch req.Kind {
+	case Push:
+		var body []byte
+		body, err = json.Marshal(req.Changes)
+		if err != nil {
+			return Response{}, err
+		}
+		err = t.do(ctx, http.MethodPost, endpoint(peer, req.Name, "delta", query), bytes.NewReader(body), &changes)
+	case Pull:
+		// the acknowledgement goes first and apart, so fetching the changes has no side effect
+		ack := url.Values{"from": {req.From}, "since": {strconv.FormatUint(req.Since, 10)}}
+		err = t.do(ctx, http.MethodPost, endpoint(peer, req.Name, "ack", ack), nil, &struct{}{})
+		if err != nil {
+			return Response{}, err
+		}
+		err = t.do(ctx, http.MethodGet, endpoint(peer, req.Name, "delta", query), nil, &changes)
+	case Compare:
+		var summary DigestSummary
+		err = t.do(ctx, http.MethodGet, endpoint(peer, req.Name, "digest", nil), nil, &summary)
+		if errors.Is(err, errNoDigest) {
+			return Response{}, nil
+		}
+		if err != nil {
+			return Response{}, err
+		}
+		return Response{Root: &summary.Root}, nil
+	default:
+		return Response{}, fmt.Errorf("unknown request kind: %v", req.Kind)
+	}
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Changes: changes}, nil
+}
+
+// State fetches the full state of a replica of the peer
+func (t *HTTPTransport) State(ctx context.Context, peer, name string) (Changes, error) {
+	var changes Changes
+	err := t.do(ctx, http.MethodGet, endpoint(peer, name, "state", nil), nil, &changes)
+	return changes, err
+}
+
+// Digest fetches the digest tree of a replica of the peer, the returned reader fetches inner nodes on demand
+// so it can be walked with merkle.Diff
+func (t *HTTPTransport) Digest(ctx context.Context, peer, name string) (merkle.Reader, error) {
+	var summary DigestSummary
+	err := t.do(ctx, http.MethodGet, endpoint(peer, name, "digest", nil), nil, &summary)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteDigest{ctx: ctx, transport: t, peer: peer, name: name, summary: summary}, nil
+}
+
+func (t *HTTPTransport) do(ctx context.Context, method, u string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{
+			method: method,
+			url:    u,
+			code:   resp.StatusCode,
+			msg:    strings.TrimSpace(string(msg)),
+			err:    errorOf(resp.Header.Get(errorHeader)),
+		}
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(out)
+}
+
+// statusError is returned for responses of the handler other than 200 OK
+type statusError struct {
+	method string
+	url    string
+	code   int
+	msg    string
+	err    error // error the code of the response stands for, if any
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.method, e.url, e.code, http.StatusText(e.code), e.msg)
+}
+
+// Unwrap returns the error the handler reported, so errors.Is matches errors such as ErrUnknownReplica
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// endpoint returns the URL of an endpoint of a replica served at base
+func endpoint(base, name, endpoint string, query url.Values) string {
+	u := strings.TrimRight(base, "/") + "/" + url.PathEscape(name) + "/" + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// remoteDigest reads the digest tree of a replica served over HTTP
+type remoteDigest struct {
+	ctx       context.Context
+	transport *HTTPTransport
+	peer      string
+	name      string
+	summary   DigestSummary
+}
+
+func (d *remoteDigest) Shape() (int, int) {
+	return d.summary.Fanout, d.summary.Depth
+}
+
+func (d *remoteDigest) Root() (merkle.Hash, error) {
+	return d.summary.Root, nil
+}
+
+func (d *remoteDigest) Children(n merkle.Node) ([]merkle.Hash, error) {
+	query := url.Values{"level": {strconv.Itoa(n.Level)}, "index": {strconv.Itoa(n.Index)}}
+	var children []merkle.Hash
+	err := d.transport.do(d.ctx, http.MethodGet, endpoint(d.peer, d.name, "digest", query), nil, &children)
+	return children, err
+}
@@ -0,0 +1,194 @@
+package replication_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/crdtpb"
+	"github.com/bjornaer/crdt/merkle"
+	"github.com/bjornaer/crdt/replication"
+	"google.golang.org/protobuf/proto"
+)
+
+// httpNode is a replicator holding a single set named "items", served over HTTP
+type httpNode struct {
+	set        crdt.LastWriterWinsSet[string]
+	replicator *replication.Replicator
+	server     *httptest.Server
+}
+
+func newHTTPNode(t *testing.T, self string) *httpNode {
+	n := &httpNode{set: crdt.NewLWWSet(crdt.WithDigest[string]())}
+	n.replicator = replication.New(self, replication.NewHTTPTransport(nil))
+	n.replicator.Register("items", replication.Set(n.set, time.Minute))
+	n.server = httptest.NewServer(http.StripPrefix("/crdt", replication.NewHandler(n.replicator)))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func (n *httpNode) url() string {
+	return n.server.URL + "/crdt"
+}
+
+func TestHTTP_Converges(t *testing.T) {
+	n1 := newHTTPNode(t, "n1")
+	n2 := newHTTPNode(t, "n2")
+	n1.replicator.SetPeers([]string{n2.url()})
+
+	n1.set.Add("a", time.Now())
+	n2.set.Add("b", time.Now())
+	n2.set.Add("c", time.Now())
+	n2.set.Remove("c", time.Now())
+	if err := n1.replicator.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n1.set.Add("d", time.Now())
+	if err := n1.replicator.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"a", "b", "d"}
+	for _, s := range []crdt.LastWriterWinsSet[string]{n1.set, n2.set} {
+		items, _ := s.Get()
+		if !setsAreEqual(items, expected) {
+			t.Errorf("Replicas diverged, got: %v, expected: %v.", items, expected)
+		}
+	}
+}
+
+func TestHTTP_State(t *testing.T) {
+	n := newHTTPNode(t, "n1")
+	n.set.Add("a", time.Now())
+
+	changes, err := replication.NewHTTPTransport(nil).State(context.Background(), n.url(), "items")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !changes.Full {
+		t.Errorf("Expected the full state")
+	}
+	m := &crdtpb.LWWSet{}
+	if err := proto.Unmarshal(changes.State, m); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := crdt.SetFromProto[string](m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !s.Exists("a") {
+		t.Errorf("Expected the served state to hold the element")
+	}
+}
+
+// checks the served digest can be walked with merkle.Diff to find where replicas differ
+func TestHTTP_Digest(t *testing.T) {
+	n := newHTTPNode(t, "n1")
+	local := crdt.NewLWWSet(crdt.WithDigest[string]())
+	now := time.Now()
+	for _, e := range []string{"a", "b", "c"} {
+		n.set.Add(e, now)
+		local.Add(e, now)
+	}
+	n.set.Add("d", now)
+
+	remote, err := replication.NewHTTPTransport(nil).Digest(context.Background(), n.url(), "items")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	leaves, err := merkle.Diff(local.Digest(), remote)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []int{local.Digest().Leaf([]byte("d"))}
+	if !setsAreEqual(leaves, expected) {
+		t.Errorf("Unexpected differing leaves, got: %v, expected: %v.", leaves, expected)
+	}
+}
+
+// checks fetching changes acknowledges nothing, only the ack endpoint does
+func TestHTTP_DeltaIsReadOnly(t *testing.T) {
+	n := newHTTPNode(t, "n1")
+	n.set.Add("a", time.Now())
+	fetch := func(method, path string) replication.Changes {
+		req, _ := http.NewRequest(method, n.url()+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Unexpected status for %s %s, got: %d, expected: %d.", method, path, resp.StatusCode, http.StatusOK)
+		}
+		var changes replication.Changes
+		_ = json.NewDecoder(resp.Body).Decode(&changes)
+		return changes
+	}
+
+	first := fetch(http.MethodGet, "/items/delta?from=n2")
+	if second := fetch(http.MethodGet, "/items/delta?from=n2"); !first.Full || !second.Full {
+		t.Errorf("Expected the full state until the peer acknowledges it")
+	}
+	fetch(http.MethodPost, fmt.Sprintf("/items/ack?from=n2&since=%d", first.Sequence))
+	if changes := fetch(http.MethodGet, "/items/delta?from=n2"); changes.Full || len(changes.State) != 0 {
+		t.Errorf("Expected no changes once the peer acknowledged the state, got: %+v", changes)
+	}
+}
+
+func TestHTTP_Errors(t *testing.T) {
+	n := newHTTPNode(t, "n1")
+	n.replicator.Register("graph", replication.Graph(crdt.NewLWWGraph[string](), time.Minute))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/crdt/unknown/state", http.StatusNotFound},
+		{http.MethodGet, "/crdt/items/unknown", http.StatusNotFound},
+		{http.MethodPost, "/crdt/items/ack?since=abc", http.StatusBadRequest},
+		{http.MethodGet, "/crdt/items/ack", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/crdt/items/delta", http.StatusBadRequest},
+		{http.MethodDelete, "/crdt/items/state", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/crdt/items/digest?level=9&index=0", http.StatusBadRequest},
+		{http.MethodGet, "/crdt/graph/digest", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, n.server.URL+test.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("Unexpected status for %s %s, got: %d, expected: %d.", test.method, test.path, resp.StatusCode, test.status)
+		}
+	}
+
+	_, err := replication.NewHTTPTransport(nil).State(context.Background(), n.url(), "unknown")
+	if !errors.Is(err, replication.ErrUnknownReplica) {
+		t.Errorf("Unexpected error fetching an unknown replica, got: %v, expected: %v.", err, replication.ErrUnknownReplica)
+	}
+}
+
+// checks replica names are escaped in the URLs of the transport
+func TestHTTP_EscapedName(t *testing.T) {
+	n := newHTTPNode(t, "n1")
+	s := crdt.NewLWWSet[string]()
+	s.Add("a", time.Now())
+	n.replicator.Register("team a/items?", replication.Set(s, time.Minute))
+
+	changes, err := replication.NewHTTPTransport(nil).State(context.Background(), n.url(), "team a/items?")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !changes.Full || len(changes.State) == 0 {
+		t.Errorf("Expected the full state of the replica")
+	}
+}
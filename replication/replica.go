@@ -0,0 +1,177 @@
+package replication
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/crdtpb"
+	"github.com/bjornaer/crdt/delta"
+	set "github.com/bjornaer/crdt/internal/set"
+	"github.com/bjornaer/crdt/merkle"
+	"google.golang.org/protobuf/proto"
+)
+
+// Changes is an encoded payload exchanged between replicas
+type Changes struct {
+	// Full is true when State holds the full state of the replica rather than a join of deltas
+	Full bool `json:"full"`
+	// Sequence is the sequence of the latest change included in State, the receiver acknowledges it once merged
+	Sequence uint64 `json:"sequence"`
+	// State is empty when the changes hold no entry
+	State []byte `json:"state,omitempty"`
+}
+
+// Replica is a CRDT registered with a Replicator, any CRDT can be replicated by implementing it
+type Replica interface {
+	// State returns the full state of the replica
+	State() (Changes, error)
+	// Changes returns what the peer did not acknowledge yet
+	Changes(peer string) (Changes, error)
+	// Ack records that the peer merged every change up to the given sequence
+	Ack(peer string, sequence uint64)
+	// Forget stops tracking what the peer acknowledged, it receives the full state next
+	Forget(peer string)
+	// Merge merges changes received from a peer
+	Merge(Changes) error
+}
+
+// Digester is implemented by replicas maintaining a digest of their state
+type Digester interface {
+	// Digest returns the digest tree of the replica, or nil if it does not maintain one
+	Digest() *merkle.Tree
+}
+
+// Collector is implemented by replicas purging the tombstones every peer observed
+type Collector interface {
+	// Collect purges the tombstones received in changes every given peer acknowledged, and returns how many
+	// entries were purged
+	Collect(peers []string) (int, error)
+	// Behind tells whether the peer did not acknowledge the changes holding tombstones the replica purged,
+	// its changes are refused with crdt.ErrStaleState as they could bring purged elements back
+	Behind(peer string) bool
+}
+
+// mergeFrom merges changes shipped by a peer, unless the peer is behind the tombstones the replica purged
+func mergeFrom(replica Replica, peer string, c Changes) error {
+	if collector, ok := replica.(Collector); ok && collector.Behind(peer) {
+		return fmt.Errorf("%w: %s did not acknowledge the purged tombstones", crdt.ErrStaleState, peer)
+	}
+	return replica.Merge(c)
+}
+
+// bufferedReplica ships the deltas of a CRDT through a delta.Buffer, states are encoded with protobuf
+type bufferedReplica[S any] struct {
+	buffer *delta.Buffer[S]
+	encode func(S) ([]byte, error)
+	decode func([]byte) (S, error)
+	digest func() *merkle.Tree
+}
+
+func (r *bufferedReplica[S]) State() (Changes, error) {
+	p, err := r.buffer.State()
+	if err != nil {
+		return Changes{}, err
+	}
+	return r.encodePayload(p)
+}
+
+func (r *bufferedReplica[S]) Changes(peer string) (Changes, error) {
+	p, err := r.buffer.Prepare(peer)
+	if err != nil {
+		return Changes{}, err
+	}
+	return r.encodePayload(p)
+}
+
+func (r *bufferedReplica[S]) encodePayload(p delta.Payload[S]) (Changes, error) {
+	state, err := r.encode(p.State)
+	if err != nil {
+		return Changes{}, err
+	}
+	return Changes{Full: p.Full, Sequence: p.Sequence, State: state}, nil
+}
+
+func (r *bufferedReplica[S]) Ack(peer string, sequence uint64) {
+	r.buffer.Ack(peer, sequence)
+}
+
+func (r *bufferedReplica[S]) Forget(peer string) {
+	r.buffer.Forget(peer)
+}
+
+func (r *bufferedReplica[S]) Digest() *merkle.Tree {
+	if r.digest == nil {
+		return nil
+	}
+	return r.digest()
+}
+
+func (r *bufferedReplica[S]) Collect(peers []string) (int, error) {
+	return r.buffer.Collect(peers...)
+}
+
+func (r *bufferedReplica[S]) Behind(peer string) bool {
+	return r.buffer.Behind(peer)
+}
+
+func (r *bufferedReplica[S]) Merge(c Changes) error {
+	state, err := r.decode(c.State)
+	if err != nil {
+		return err
+	}
+	return r.buffer.Receive(delta.Payload[S]{Full: c.Full, Sequence: c.Sequence, State: state})
+}
+
+// Set makes a LastWriterWinsSet replicable, deltas are retained for the retention duration
+//
+// The digest of the set is served to peers when it was built WithDigest, and its tombstones are collected
+// by replicators running WithTombstoneGC
+func Set[T comparable](s crdt.LastWriterWinsSet[T], retention time.Duration) Replica {
+	return &bufferedReplica[crdt.LastWriterWinsSet[T]]{
+		buffer: delta.NewBuffer(delta.Set(s), retention),
+		encode: func(state crdt.LastWriterWinsSet[T]) ([]byte, error) {
+			m, err := crdt.SetToProto(state)
+			if err != nil {
+				return nil, err
+			}
+			return proto.Marshal(m)
+		},
+		decode: func(b []byte) (crdt.LastWriterWinsSet[T], error) {
+			m := &crdtpb.LWWSet{}
+			if err := proto.Unmarshal(b, m); err != nil {
+				return nil, err
+			}
+			return crdt.SetFromProto(m, crdt.WithCodec[T](s.GetCodec()))
+		},
+		digest: s.Digest,
+	}
+}
+
+// Graph makes a LastWriterWinsGraph replicable, deltas are retained for the retention duration
+//
+// The given options are used to decode the states received from peers, and must hold the codec of the graph if it has one
+func Graph[T comparable](g crdt.LastWriterWinsGraph[T], retention time.Duration, opts ...set.Option[T]) Replica {
+	return &bufferedReplica[crdt.LastWriterWinsGraph[T]]{
+		buffer: delta.NewBuffer(delta.Graph(g), retention),
+		encode: func(state crdt.LastWriterWinsGraph[T]) ([]byte, error) {
+			m, err := crdt.GraphToProto(state)
+			if err != nil {
+				return nil, err
+			}
+			// an empty set encodes to nothing, an empty graph has to be told apart from its empty vertex set
+			vertices := m.GetVertices()
+			if len(m.GetEdges()) == 0 && len(vertices.GetAdditions()) == 0 && len(vertices.GetRemovals()) == 0 {
+				return nil, nil
+			}
+			return proto.Marshal(m)
+		},
+		decode: func(b []byte) (crdt.LastWriterWinsGraph[T], error) {
+			m := &crdtpb.LWWGraph{}
+			if err := proto.Unmarshal(b, m); err != nil {
+				return nil, err
+			}
+			return crdt.GraphFromProto(m, opts...)
+		},
+	}
+}
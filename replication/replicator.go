@@ -0,0 +1,427 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bjornaer/crdt"
+)
+
+// Config holds the settings of a Replicator
+type Config struct {
+	// Interval is the time between two synchronization rounds of Run
+	Interval time.Duration
+	// MinBackoff is how long a peer is left alone after its first failure, the wait doubles
+	// with every following failure up to MaxBackoff, and resets once a round succeeds
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Kinds are the requests sent to every peer in a round, pushes then pulls by default
+	Kinds []Kind
+	// Peers are the addresses of the peers to synchronize with
+	Peers []string
+	// Fanout is the number of peers picked at random every round, every peer when zero
+	Fanout int
+	// Rand picks the peers of a round when Fanout is set
+	Rand *rand.Rand
+	// OnError is called for every failed exchange, if set
+	OnError func(peer, name string, err error)
+	// Collect purges the tombstones every peer observed after every round, see WithTombstoneGC
+	Collect bool
+}
+
+// Option configures a Replicator
+type Option func(*Config)
+
+// WithInterval sets the time between two synchronization rounds
+func WithInterval(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Interval = d
+	}
+}
+
+// WithBackoff sets the bounds of the wait applied to failing peers
+func WithBackoff(min, max time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.MinBackoff = min
+		cfg.MaxBackoff = max
+	}
+}
+
+// WithKinds sets the requests sent to every peer in a round, Push only or Pull only for instance
+func WithKinds(kinds ...Kind) Option {
+	return func(cfg *Config) {
+		cfg.Kinds = kinds
+	}
+}
+
+// WithPeers sets the initial peers of the replicator
+func WithPeers(peers ...string) Option {
+	return func(cfg *Config) {
+		cfg.Peers = peers
+	}
+}
+
+// WithFanout makes every round gossip with the given number of peers picked at random, rather than with
+// every peer, so the cost of a round does not grow with the size of the cluster
+func WithFanout(fanout int) Option {
+	return func(cfg *Config) {
+		cfg.Fanout = fanout
+	}
+}
+
+// WithRand sets the source picking the peers of a round, for reproducible simulations
+func WithRand(rnd *rand.Rand) Option {
+	return func(cfg *Config) {
+		cfg.Rand = rnd
+	}
+}
+
+// WithErrorHandler sets the function called for every failed exchange
+func WithErrorHandler(f func(peer, name string, err error)) Option {
+	return func(cfg *Config) {
+		cfg.OnError = f
+	}
+}
+
+// WithTombstoneGC purges, after every round, the tombstones of the replicas implementing Collector that every peer
+// observed: the ones received in changes all peers acknowledged, whatever their timestamps. Failures are reported
+// to the error handler, with the replicator as the peer
+//
+// A peer that never acknowledged anything holds the tombstones back, while the changes of a replica that did not
+// acknowledge the purged ones, such as a replica that is not among the peers, are refused with crdt.ErrStaleState
+// until it does
+func WithTombstoneGC() Option {
+	return func(cfg *Config) {
+		cfg.Collect = true
+	}
+}
+
+// ErrUnknownReplica is returned for requests about a name no replica was registered under
+var ErrUnknownReplica = errors.New("unknown replica")
+
+// backoff tracks the failures of a peer
+type backoff struct {
+	failures int
+	retryAt  time.Time
+}
+
+// Replicator synchronizes named CRDT replicas with a set of peers
+//
+// Every round, the changes a peer did not acknowledge are pushed to it, and the changes it made since
+// the previous round are pulled from it. With a fanout, a round only involves a few random peers and changes
+// spread epidemically, replicas maintaining a digest first compare it with the peer and skip the exchange if equal. A Replicator also serves the requests of its peers, it has to be
+// registered as the Handler of its address on the transport
+type Replicator struct {
+	self      string
+	transport Transport
+	cfg       Config
+	replicas  map[string]Replica
+	peers     []string
+	received  map[string]map[string]uint64 // latest sequence pulled from each peer, per replica
+	backoffs  map[string]*backoff
+	mutex     sync.Mutex
+}
+
+// New returns a Replicator for the replica identified by self, calling its peers through the given transport
+func New(self string, transport Transport, opts ...Option) *Replicator {
+	cfg := Config{
+		Interval:   time.Second,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+		Kinds:      []Kind{Push, Pull},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return &Replicator{
+		self:      self,
+		transport: transport,
+		cfg:       cfg,
+		replicas:  make(map[string]Replica),
+		peers:     append([]string{}, cfg.Peers...),
+		received:  make(map[string]map[string]uint64),
+		backoffs:  make(map[string]*backoff),
+	}
+}
+
+// Register adds a replica under the given name, peers replicate it under the same name
+func (r *Replicator) Register(name string, replica Replica) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.replicas[name] = replica
+}
+
+// SetPeers replaces the peers to synchronize with
+func (r *Replicator) SetPeers(peers []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.peers = append([]string{}, peers...)
+}
+
+// Peers returns the peers to synchronize with
+func (r *Replicator) Peers() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.peers...)
+}
+
+func (r *Replicator) replica(name string) (Replica, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	replica, ok := r.replicas[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownReplica, name)
+	}
+	return replica, nil
+}
+
+// Handle serves a request of a peer
+func (r *Replicator) Handle(ctx context.Context, req Request) (Response, error) {
+	replica, err := r.replica(req.Name)
+	if err != nil {
+		return Response{}, err
+	}
+	switch req.Kind {
+	case Push:
+		if err := mergeFrom(replica, req.From, req.Changes); err != nil {
+			return Response{}, err
+		}
+		return Response{Changes: Changes{Sequence: req.Changes.Sequence}}, nil
+	case Compare:
+		if d, ok := replica.(Digester); ok && d.Digest() != nil {
+			root, err := d.Digest().Root()
+			if err != nil {
+				return Response{}, err
+			}
+			return Response{Root: &root}, nil
+		}
+		return Response{}, nil
+	case Pull:
+		acknowledge(replica, req.From, req.Since)
+		changes, err := replica.Changes(req.From)
+		if err != nil {
+			return Response{}, err
+		}
+		return Response{Changes: changes}, nil
+	default:
+		return Response{}, fmt.Errorf("unknown request kind: %v", req.Kind)
+	}
+}
+
+// acknowledge records the sequence a peer pulls from as what it received, a peer that never received anything
+// gets the full state
+func acknowledge(replica Replica, peer string, since uint64) {
+	if since == 0 {
+		replica.Forget(peer)
+	} else {
+		replica.Ack(peer, since)
+	}
+}
+
+// Run synchronizes with the peers every interval until the context is done
+func (r *Replicator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			// failures are reported to the error handler and retried with backoff, they do not stop the loop
+			_ = r.SyncOnce(ctx)
+		}
+	}
+}
+
+// SyncOnce runs a single synchronization round with every peer that is not backing off
+func (r *Replicator) SyncOnce(ctx context.Context) error {
+	now := time.Now()
+	r.mutex.Lock()
+	var peers []string
+	for _, peer := range r.peers {
+		if b, ok := r.backoffs[peer]; !ok || !now.Before(b.retryAt) {
+			peers = append(peers, peer)
+		}
+	}
+	if r.cfg.Fanout > 0 && len(peers) > r.cfg.Fanout {
+		r.cfg.Rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+		peers = peers[:r.cfg.Fanout]
+	}
+	names := make([]string, 0, len(r.replicas))
+	for name := range r.replicas {
+		names = append(names, name)
+	}
+	r.mutex.Unlock()
+	sort.Strings(names)
+
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			errs[i] = r.syncPeer(ctx, peer, names)
+			r.updateBackoff(peer, errs[i])
+		}(i, peer)
+	}
+	wg.Wait()
+	if r.cfg.Collect {
+		r.collect(names)
+	}
+
+	failed := 0
+	var first error
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("synchronization failed with %d of %d peers: %w", failed, len(peers), first)
+	}
+	return nil
+}
+
+// syncPeer exchanges the changes of every named replica with a peer, stopping at the first failure
+func (r *Replicator) syncPeer(ctx context.Context, peer string, names []string) error {
+	for _, name := range names {
+		if err := r.exchange(ctx, peer, name); err != nil {
+			if r.cfg.OnError != nil {
+				r.cfg.OnError(peer, name, err)
+			}
+			return fmt.Errorf("cannot sync %s with %s: %w", name, peer, err)
+		}
+	}
+	return nil
+}
+
+// exchange runs the configured kinds of requests with a peer about a replica, unless their digests match
+//
+// A push the peer refuses with crdt.ErrStaleState does not stop the exchange: the replica holds entries the peer
+// already purged, and it still pulls the changes of the peer before the refusal is returned
+func (r *Replicator) exchange(ctx context.Context, peer, name string) error {
+	replica, err := r.replica(name)
+	if err != nil {
+		return err
+	}
+	same, err := r.compare(ctx, peer, name, replica)
+	if err != nil || same {
+		return err
+	}
+	var stale error
+	for _, kind := range r.cfg.Kinds {
+		switch kind {
+		case Push:
+			changes, err := replica.Changes(peer)
+			if err != nil {
+				return err
+			}
+			resp, err := r.transport.Call(ctx, peer, Request{Kind: Push, From: r.self, Name: name, Changes: changes})
+			if errors.Is(err, crdt.ErrStaleState) {
+				stale = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			replica.Ack(peer, resp.Changes.Sequence)
+		case Pull:
+			resp, err := r.transport.Call(ctx, peer, Request{Kind: Pull, From: r.self, Name: name, Since: r.lastReceived(peer, name)})
+			if err != nil {
+				return err
+			}
+			if err := replica.Merge(resp.Changes); err != nil {
+				return err
+			}
+			r.setReceived(peer, name, resp.Changes.Sequence)
+		default:
+			return fmt.Errorf("unknown request kind: %v", kind)
+		}
+	}
+	return stale
+}
+
+// compare tells whether the replica and its counterpart on the peer hold the same state, according to their digests
+func (r *Replicator) compare(ctx context.Context, peer, name string, replica Replica) (bool, error) {
+	d, ok := replica.(Digester)
+	if !ok || d.Digest() == nil {
+		return false, nil
+	}
+	root, err := d.Digest().Root()
+	if err != nil {
+		return false, err
+	}
+	resp, err := r.transport.Call(ctx, peer, Request{Kind: Compare, From: r.self, Name: name})
+	if err != nil {
+		return false, err
+	}
+	return resp.Root != nil && *resp.Root == root, nil
+}
+
+// collect purges the tombstones every peer observed from the named replicas supporting it
+func (r *Replicator) collect(names []string) {
+	peers := r.Peers()
+	for _, name := range names {
+		replica, err := r.replica(name)
+		if err != nil {
+			continue
+		}
+		c, ok := replica.(Collector)
+		if !ok {
+			continue
+		}
+		if _, err := c.Collect(peers); err != nil && r.cfg.OnError != nil {
+			r.cfg.OnError(r.self, name, err)
+		}
+	}
+}
+
+func (r *Replicator) lastReceived(peer, name string) uint64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.received[peer][name]
+}
+
+func (r *Replicator) setReceived(peer, name string, sequence uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.received[peer] == nil {
+		r.received[peer] = make(map[string]uint64)
+	}
+	r.received[peer][name] = sequence
+}
+
+// updateBackoff resets the backoff of a peer after a success, and extends it after a failure
+func (r *Replicator) updateBackoff(peer string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err == nil {
+		delete(r.backoffs, peer)
+		return
+	}
+	b, ok := r.backoffs[peer]
+	if !ok {
+		b = &backoff{}
+		r.backoffs[peer] = b
+	}
+	b.failures++
+	wait := r.cfg.MinBackoff
+	for i := 1; i < b.failures && wait < r.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > r.cfg.MaxBackoff {
+		wait = r.cfg.MaxBackoff
+	}
+	b.retryAt = time.Now().Add(wait)
+}
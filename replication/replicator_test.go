@@ -0,0 +1,326 @@
+package replication_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/replication"
+)
+
+// checks element is contained within set
+func contains[T comparable](s []T, e T) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// checks for set equality -- independent of order
+func setsAreEqual[T comparable](s1, s2 []T) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for _, v := range s1 {
+		if !contains(s2, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// recorder is a transport keeping the requests it carries
+type recorder struct {
+	replication.Transport
+	requests []replication.Request
+	mutex    sync.Mutex
+}
+
+func (r *recorder) Call(ctx context.Context, peer string, req replication.Request) (replication.Response, error) {
+	r.mutex.Lock()
+	r.requests = append(r.requests, req)
+	r.mutex.Unlock()
+	return r.Transport.Call(ctx, peer, req)
+}
+
+// node is a replicator holding a single set named "items"
+type node struct {
+	set        crdt.LastWriterWinsSet[string]
+	replicator *replication.Replicator
+	transport  *recorder
+}
+
+func newNode(network *replication.Network, self string, opts ...replication.Option) *node {
+	n := &node{
+		set:       crdt.NewLWWSet[string](),
+		transport: &recorder{Transport: network.Transport(self)},
+	}
+	n.replicator = replication.New(self, n.transport, opts...)
+	n.replicator.Register("items", replication.Set(n.set, time.Minute))
+	network.Register(self, n.replicator)
+	return n
+}
+
+func (n *node) items(t *testing.T) []string {
+	items, err := n.set.Get()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return items
+}
+
+func TestReplicator_Converges(t *testing.T) {
+	network := replication.NewNetwork()
+	n1 := newNode(network, "n1", replication.WithPeers("n2", "n3"))
+	n2 := newNode(network, "n2", replication.WithPeers("n1", "n3"))
+	n3 := newNode(network, "n3", replication.WithPeers("n1", "n2"))
+
+	n1.set.Add("a", time.Now())
+	n2.set.Add("b", time.Now())
+	n3.set.Add("c", time.Now())
+	n3.set.Remove("c", time.Now())
+
+	ctx := context.Background()
+	for _, n := range []*node{n1, n2, n3} {
+		if err := n.replicator.SyncOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := []string{"a", "b"}
+	for _, n := range []*node{n1, n2, n3} {
+		if items := n.items(t); !setsAreEqual(items, expected) {
+			t.Errorf("Replicas diverged, got: %v, expected: %v.", items, expected)
+		}
+	}
+}
+
+// checks only the first round ships full states, later ones only ship deltas
+func TestReplicator_ShipsDeltas(t *testing.T) {
+	network := replication.NewNetwork()
+	n1 := newNode(network, "n1", replication.WithPeers("n2"))
+	n2 := newNode(network, "n2", replication.WithPeers("n1"))
+
+	ctx := context.Background()
+	n1.set.Add("a", time.Now())
+	if err := n1.replicator.SyncOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n1.set.Add("b", time.Now())
+	n1.transport.requests = nil
+	if err := n1.replicator.SyncOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, req := range n1.transport.requests {
+		if req.Kind == replication.Push && req.Changes.Full {
+			t.Errorf("Expected a delta to be pushed once the peer acknowledged the full state")
+		}
+		if req.Kind == replication.Pull && req.Since == 0 {
+			t.Errorf("Expected pulls to resume from the latest received sequence")
+		}
+	}
+	if items := n2.items(t); !setsAreEqual(items, []string{"a", "b"}) {
+		t.Errorf("Unexpected items, got: %v, expected: %v.", items, []string{"a", "b"})
+	}
+}
+
+// checks a failing peer is left alone until its backoff expires
+func TestReplicator_Backoff(t *testing.T) {
+	network := replication.NewNetwork()
+	var failures []string
+	n1 := newNode(network, "n1",
+		replication.WithPeers("n2"),
+		replication.WithBackoff(50*time.Millisecond, time.Second),
+		replication.WithErrorHandler(func(peer, name string, err error) {
+			failures = append(failures, peer+"/"+name)
+		}),
+	)
+	n2 := newNode(network, "n2")
+	n1.set.Add("a", time.Now())
+
+	ctx := context.Background()
+	network.SetDown("n2", true)
+	err := n1.replicator.SyncOnce(ctx)
+	if !errors.Is(err, replication.ErrUnreachable) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, replication.ErrUnreachable)
+	}
+	if len(failures) != 1 || failures[0] != "n2/items" {
+		t.Errorf("Unexpected failures, got: %v, expected: %v.", failures, []string{"n2/items"})
+	}
+
+	network.SetDown("n2", false)
+	n1.transport.requests = nil
+	if err := n1.replicator.SyncOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(n1.transport.requests) != 0 {
+		t.Errorf("Expected no request to a peer backing off, got: %v", n1.transport.requests)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := n1.replicator.SyncOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !n2.set.Exists("a") {
+		t.Errorf("Expected the peer to be synced once its backoff expired")
+	}
+}
+
+// checks a replica that lost its state pulls the full state again
+func TestReplicator_Restart(t *testing.T) {
+	network := replication.NewNetwork()
+	n1 := newNode(network, "n1")
+	n2 := newNode(network, "n2", replication.WithPeers("n1"), replication.WithKinds(replication.Pull))
+	n1.set.Add("a", time.Now())
+
+	ctx := context.Background()
+	if err := n2.replicator.SyncOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n2 = newNode(network, "n2", replication.WithPeers("n1"), replication.WithKinds(replication.Pull))
+	if err := n2.replicator.SyncOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !n2.set.Exists("a") {
+		t.Errorf("Expected the restarted replica to receive the full state")
+	}
+}
+
+// checks tombstones every peer observed are purged for good, and a replica that missed them is refused afterwards
+func TestReplicator_TombstoneGC(t *testing.T) {
+	network := replication.NewNetwork()
+	n1 := newNode(network, "n1", replication.WithPeers("n2"), replication.WithTombstoneGC())
+	n2 := newNode(network, "n2", replication.WithPeers("n1"), replication.WithTombstoneGC())
+	addedAt := time.Now()
+	n1.set.Add("a", addedAt)
+	n1.set.Add("b", time.Now())
+	n1.set.Remove("a", time.Now())
+
+	ctx := context.Background()
+	// later rounds exchange the tombstone the replicas purged at different times, it does not come back
+	for round := 0; round < 3; round++ {
+		for _, n := range []*node{n1, n2} {
+			if err := n.replicator.SyncOnce(ctx); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		for _, n := range []*node{n1, n2} {
+			if size := n.set.GetRemovals().Size(); size != 0 {
+				t.Errorf("Unexpected number of removals after round %d, got: %d, expected: %d.", round, size, 0)
+			}
+			if items := n.items(t); !setsAreEqual(items, []string{"b"}) {
+				t.Errorf("Unexpected items, got: %v, expected: %v.", items, []string{"b"})
+			}
+		}
+	}
+
+	// a replica the others do not know about still holds the addition
+	n3 := newNode(network, "n3", replication.WithPeers("n1"), replication.WithKinds(replication.Push))
+	n3.set.Add("a", addedAt)
+	if err := n3.replicator.SyncOnce(ctx); !errors.Is(err, crdt.ErrStaleState) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, crdt.ErrStaleState)
+	}
+	if n1.set.Exists("a") {
+		t.Errorf("Expected the collected element to stay removed")
+	}
+}
+
+// checks a stale replica whose push is refused still pulls, and catches up with the changes of its peer
+func TestReplicator_StaleReplicaPulls(t *testing.T) {
+	network := replication.NewNetwork()
+	n1 := newNode(network, "n1", replication.WithPeers("n2"), replication.WithTombstoneGC())
+	n2 := newNode(network, "n2", replication.WithPeers("n1"), replication.WithTombstoneGC())
+	addedAt := time.Now()
+	n1.set.Add("a", addedAt)
+	n1.set.Remove("a", time.Now())
+
+	ctx := context.Background()
+	for _, n := range []*node{n1, n2} {
+		if err := n.replicator.SyncOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	n1.set.Add("b", time.Now())
+	n1.set.Add("c", time.Now())
+	n1.set.Remove("c", time.Now())
+
+	n3 := newNode(network, "n3", replication.WithPeers("n1"))
+	n3.set.Add("a", addedAt)
+	n3.set.Add("c", time.Now().Add(-time.Millisecond))
+	if err := n3.replicator.SyncOnce(ctx); !errors.Is(err, crdt.ErrStaleState) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, crdt.ErrStaleState)
+	}
+	// only the addition the peers purged stays behind
+	if items := n3.items(t); !setsAreEqual(items, []string{"a", "b"}) {
+		t.Errorf("Unexpected items, got: %v, expected: %v.", items, []string{"a", "b"})
+	}
+	if n1.set.Exists("a") {
+		t.Errorf("Expected the collected element to stay removed")
+	}
+}
+
+func TestReplicator_Graph(t *testing.T) {
+	network := replication.NewNetwork()
+	g1 := crdt.NewLWWGraph[string]()
+	g2 := crdt.NewLWWGraph[string]()
+	r1 := replication.New("n1", network.Transport("n1"), replication.WithPeers("n2"))
+	r2 := replication.New("n2", network.Transport("n2"))
+	r1.Register("graph", replication.Graph(g1, time.Minute))
+	r2.Register("graph", replication.Graph(g2, time.Minute))
+	network.Register("n1", r1)
+	network.Register("n2", r2)
+
+	g1.AddVertex("vertex1")
+	g2.AddVertex("vertex2")
+	g2.AddVertex("vertex3")
+	g2.AddEdge("vertex2", "vertex3")
+	if err := r1.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, g := range []crdt.LastWriterWinsGraph[string]{g1, g2} {
+		vertices, _ := g.GetAllVertices()
+		expected := []string{"vertex1", "vertex2", "vertex3"}
+		if !setsAreEqual(vertices, expected) || !g.EdgeExists("vertex2", "vertex3") {
+			t.Errorf("Graph replicas diverged, got vertices: %v, expected: %v.", vertices, expected)
+		}
+	}
+}
+
+func TestReplicator_UnknownReplica(t *testing.T) {
+	network := replication.NewNetwork()
+	n1 := newNode(network, "n1", replication.WithPeers("n2"))
+	r2 := replication.New("n2", network.Transport("n2"))
+	network.Register("n2", r2)
+	if err := n1.replicator.SyncOnce(context.Background()); err == nil {
+		t.Errorf("Expected an error syncing a replica the peer does not know")
+	}
+}
+
+func TestReplicator_Run(t *testing.T) {
+	network := replication.NewNetwork()
+	n1 := newNode(network, "n1", replication.WithPeers("n2"), replication.WithInterval(10*time.Millisecond))
+	n2 := newNode(network, "n2")
+	n1.set.Add("a", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- n1.replicator.Run(ctx) }()
+	for !n2.set.Exists("a") && ctx.Err() == nil {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, context.Canceled)
+	}
+	if !n2.set.Exists("a") {
+		t.Errorf("Expected Run to sync with peers")
+	}
+}
@@ -0,0 +1,421 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bjornaer/crdt/reconcile"
+)
+
+// TCPConfig holds the settings shared by TCP transports and servers
+type TCPConfig struct {
+	// DialTimeout bounds the time spent connecting to a peer
+	DialTimeout time.Duration
+	// HeartbeatInterval is the time between two pings on an idle connection
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout is how long a connection may stay silent before it is considered dead and closed,
+	// it has to be longer than HeartbeatInterval
+	HeartbeatTimeout time.Duration
+}
+
+// TCPOption configures a TCP transport or server
+type TCPOption func(*TCPConfig)
+
+// WithDialTimeout sets the time spent connecting to a peer
+func WithDialTimeout(d time.Duration) TCPOption {
+	return func(cfg *TCPConfig) {
+		cfg.DialTimeout = d
+	}
+}
+
+// WithHeartbeat sets the interval between pings and the silence after which a connection is closed
+func WithHeartbeat(interval, timeout time.Duration) TCPOption {
+	return func(cfg *TCPConfig) {
+		cfg.HeartbeatInterval = interval
+		cfg.HeartbeatTimeout = timeout
+	}
+}
+
+func newTCPConfig(opts ...TCPOption) TCPConfig {
+	cfg := TCPConfig{
+		DialTimeout:       5 * time.Second,
+		HeartbeatInterval: 5 * time.Second,
+		HeartbeatTimeout:  15 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// frameType tells what a frame carries
+type frameType int
+
+const (
+	requestFrame frameType = iota
+	responseFrame
+	pingFrame
+	pongFrame
+)
+
+// frame is the unit exchanged over TCP connections, length-prefixed on the wire
+//
+// Requests of every replica share the connection to a peer, responses are matched to them by ID
+type frame struct {
+	ID       uint64    `json:"id"`
+	Type     frameType `json:"type"`
+	Request  *Request  `json:"request,omitempty"`
+	Response *Response `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Code     string    `json:"code,omitempty"` // code of the error, see errorCode
+}
+
+// ErrConnectionClosed is returned for calls interrupted by the loss of their connection
+var ErrConnectionClosed = errors.New("connection closed")
+
+// remoteError is returned for errors reported by the peer handling a request
+type remoteError struct {
+	msg string
+	err error // error the code of the frame stands for, if any
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the error the peer reported, so errors.Is matches errors such as ErrUnknownReplica
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
+
+// tcpConn is a connection shared by concurrent callers and responders
+type tcpConn struct {
+	conn    net.Conn
+	cfg     TCPConfig
+	writeMu sync.Mutex
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newTCPConn(conn net.Conn, cfg TCPConfig) *tcpConn {
+	return &tcpConn{conn: conn, cfg: cfg, closed: make(chan struct{})}
+}
+
+func (c *tcpConn) write(f frame) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	err = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.HeartbeatTimeout))
+	if err != nil {
+		return err
+	}
+	return reconcile.WriteFrame(c.conn, data)
+}
+
+// read returns the next frame, failing if the peer stays silent for longer than the heartbeat timeout
+func (c *tcpConn) read() (frame, error) {
+	var f frame
+	err := c.conn.SetReadDeadline(time.Now().Add(c.cfg.HeartbeatTimeout))
+	if err != nil {
+		return f, err
+	}
+	data, err := reconcile.ReadFrame(c.conn)
+	if err != nil {
+		return f, err
+	}
+	err = json.Unmarshal(data, &f)
+	return f, err
+}
+
+func (c *tcpConn) close() {
+	c.once.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// TCPServer serves the requests of TCP transports to a Handler
+type TCPServer struct {
+	handler   Handler
+	cfg       TCPConfig
+	listeners map[net.Listener]bool
+	conns     map[*tcpConn]bool
+	mutex     sync.Mutex
+}
+
+// NewTCPServer returns a server handing the requests it receives to h, a Replicator usually
+func NewTCPServer(h Handler, opts ...TCPOption) *TCPServer {
+	return &TCPServer{
+		handler:   h,
+		cfg:       newTCPConfig(opts...),
+		listeners: make(map[net.Listener]bool),
+		conns:     make(map[*tcpConn]bool),
+	}
+}
+
+// Serve accepts connections on the listener until it is closed
+func (s *TCPServer) Serve(l net.Listener) error {
+	s.mutex.Lock()
+	s.listeners[l] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.listeners, l)
+		s.mutex.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		c := newTCPConn(conn, s.cfg)
+		s.mutex.Lock()
+		s.conns[c] = true
+		s.mutex.Unlock()
+		go s.serveConn(c)
+	}
+}
+
+// Close stops every listener and closes every connection of the server
+func (s *TCPServer) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var first error
+	for l := range s.listeners {
+		if err := l.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	for c := range s.conns {
+		c.close()
+	}
+	return first
+}
+
+func (s *TCPServer) serveConn(c *tcpConn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.close()
+		s.mutex.Lock()
+		delete(s.conns, c)
+		s.mutex.Unlock()
+	}()
+
+	for {
+		f, err := c.read()
+		if err != nil {
+			return
+		}
+		switch f.Type {
+		case pingFrame:
+			if c.write(frame{ID: f.ID, Type: pongFrame}) != nil {
+				return
+			}
+		case requestFrame:
+			if f.Request == nil {
+				return
+			}
+			// requests are served concurrently, so a slow replica does not hold back the others sharing the connection
+			go func(f frame) {
+				reply := frame{ID: f.ID, Type: responseFrame}
+				resp, err := s.handler.Handle(ctx, *f.Request)
+				if err != nil {
+					reply.Error, reply.Code = err.Error(), errorCode(err)
+				} else {
+					reply.Response = &resp
+				}
+				if c.write(reply) != nil {
+					c.close()
+				}
+			}(f)
+		default:
+			return
+		}
+	}
+}
+
+// TCPTransport calls peers served by a TCPServer, peers are addressed by host:port
+//
+// A single connection is kept per peer and shared by the requests of every replica. Idle connections are kept
+// alive with pings, and a connection gone silent is closed, the next call dialing the peer again
+type TCPTransport struct {
+	cfg    TCPConfig
+	conns  map[string]*clientConn
+	mutex  sync.Mutex
+	closed bool
+}
+
+// clientConn is the connection of a transport to a peer, with the calls waiting for a response
+type clientConn struct {
+	*tcpConn
+	pending map[uint64]chan frame
+	nextID  uint64
+	mutex   sync.Mutex
+}
+
+// NewTCPTransport returns a transport with no connection yet, peers are dialed on first call
+func NewTCPTransport(opts ...TCPOption) *TCPTransport {
+	return &TCPTransport{
+		cfg:   newTCPConfig(opts...),
+		conns: make(map[string]*clientConn),
+	}
+}
+
+// Call sends a request to the peer and waits for its response
+func (t *TCPTransport) Call(ctx context.Context, peer string, req Request) (Response, error) {
+	c, reused, err := t.conn(ctx, peer)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, err := c.call(ctx, req)
+	if errors.Is(err, ErrConnectionClosed) && reused {
+		// the connection may have died while idle, the peer restarting for instance, so we dial it again once,
+		// which is safe as pushes and pulls can be replayed
+		t.drop(peer, c)
+		c, _, err = t.conn(ctx, peer)
+		if err != nil {
+			return Response{}, err
+		}
+		resp, err = c.call(ctx, req)
+	}
+	return resp, err
+}
+
+// Close closes every connection of the transport, later calls fail
+func (t *TCPTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closed = true
+	for peer, c := range t.conns {
+		c.close()
+		delete(t.conns, peer)
+	}
+	return nil
+}
+
+// conn returns the connection to a peer, dialing it if needed, and whether it was already open
+func (t *TCPTransport) conn(ctx context.Context, peer string) (*clientConn, bool, error) {
+	t.mutex.Lock()
+	c, ok := t.conns[peer]
+	closed := t.closed
+	t.mutex.Unlock()
+	if closed {
+		return nil, false, fmt.Errorf("cannot call %s: %w", peer, ErrConnectionClosed)
+	}
+	if ok {
+		return c, true, nil
+	}
+
+	// dialing happens outside of the lock, so an unreachable peer does not hold back calls to the others
+	dialer := net.Dialer{Timeout: t.cfg.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", peer)
+	if err != nil {
+		return nil, false, err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.closed {
+		conn.Close()
+		return nil, false, fmt.Errorf("cannot call %s: %w", peer, ErrConnectionClosed)
+	}
+	if c, ok := t.conns[peer]; ok {
+		// a concurrent call dialed the peer first
+		conn.Close()
+		return c, true, nil
+	}
+	c = &clientConn{tcpConn: newTCPConn(conn, t.cfg), pending: make(map[uint64]chan frame)}
+	t.conns[peer] = c
+	go t.readLoop(peer, c)
+	go t.heartbeat(peer, c)
+	return c, false, nil
+}
+
+// drop forgets the connection to a peer, unless it was already replaced
+func (t *TCPTransport) drop(peer string, c *clientConn) {
+	c.close()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.conns[peer] == c {
+		delete(t.conns, peer)
+	}
+}
+
+func (t *TCPTransport) readLoop(peer string, c *clientConn) {
+	defer t.drop(peer, c)
+	for {
+		f, err := c.read()
+		if err != nil {
+			return
+		}
+		if f.Type != responseFrame {
+			// pongs only matter for resetting the read deadline
+			continue
+		}
+		c.mutex.Lock()
+		ch, ok := c.pending[f.ID]
+		delete(c.pending, f.ID)
+		c.mutex.Unlock()
+		if ok {
+			ch <- f
+		}
+	}
+}
+
+func (t *TCPTransport) heartbeat(peer string, c *clientConn) {
+	ticker := time.NewTicker(t.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			if c.write(frame{Type: pingFrame}) != nil {
+				t.drop(peer, c)
+				return
+			}
+		}
+	}
+}
+
+func (c *clientConn) call(ctx context.Context, req Request) (Response, error) {
+	ch := make(chan frame, 1)
+	c.mutex.Lock()
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.write(frame{ID: id, Type: requestFrame, Request: &req}); err != nil {
+		c.close()
+		return Response{}, fmt.Errorf("%w: %v", ErrConnectionClosed, err)
+	}
+	select {
+	case f := <-ch:
+		if f.Error != "" {
+			return Response{}, &remoteError{msg: f.Error, err: errorOf(f.Code)}
+		}
+		if f.Response == nil {
+			return Response{}, errors.New("empty response")
+		}
+		return *f.Response, nil
+	case <-c.closed:
+		return Response{}, ErrConnectionClosed
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+}
@@ -0,0 +1,206 @@
+package replication_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/replication"
+)
+
+// countingListener counts the connections it accepts
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+// serveTCP serves a handler on a loopback address
+func serveTCP(t *testing.T, addr string, h replication.Handler, opts ...replication.TCPOption) (*replication.TCPServer, *countingListener) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	listener := &countingListener{Listener: l}
+	server := replication.NewTCPServer(h, opts...)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return server, listener
+}
+
+// checks several replicas are multiplexed over a single connection per peer
+func TestTCP_Converges(t *testing.T) {
+	s1, s2 := crdt.NewLWWSet[string](), crdt.NewLWWSet[string]()
+	g1, g2 := crdt.NewLWWGraph[string](), crdt.NewLWWGraph[string]()
+
+	r2 := replication.New("n2", replication.NewTCPTransport())
+	r2.Register("items", replication.Set(s2, time.Minute))
+	r2.Register("graph", replication.Graph(g2, time.Minute))
+	_, listener := serveTCP(t, "127.0.0.1:0", r2)
+
+	transport := replication.NewTCPTransport()
+	defer transport.Close()
+	r1 := replication.New("n1", transport, replication.WithPeers(listener.Addr().String()))
+	r1.Register("items", replication.Set(s1, time.Minute))
+	r1.Register("graph", replication.Graph(g1, time.Minute))
+
+	s1.Add("a", time.Now())
+	s2.Add("b", time.Now())
+	g1.AddVertex("vertex1")
+	g2.AddVertex("vertex2")
+	for i := 0; i < 3; i++ {
+		if err := r1.SyncOnce(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	for _, s := range []crdt.LastWriterWinsSet[string]{s1, s2} {
+		items, _ := s.Get()
+		if !setsAreEqual(items, []string{"a", "b"}) {
+			t.Errorf("Replicas diverged, got: %v, expected: %v.", items, []string{"a", "b"})
+		}
+	}
+	for _, g := range []crdt.LastWriterWinsGraph[string]{g1, g2} {
+		vertices, _ := g.GetAllVertices()
+		if !setsAreEqual(vertices, []string{"vertex1", "vertex2"}) {
+			t.Errorf("Graph replicas diverged, got: %v, expected: %v.", vertices, []string{"vertex1", "vertex2"})
+		}
+	}
+	if accepted := atomic.LoadInt32(&listener.accepted); accepted != 1 {
+		t.Errorf("Unexpected number of connections, got: %d, expected: %d.", accepted, 1)
+	}
+}
+
+// echo answers every request with its sequence, after an optional delay
+type echo struct {
+	delay time.Duration
+}
+
+func (e echo) Handle(ctx context.Context, req replication.Request) (replication.Response, error) {
+	time.Sleep(e.delay)
+	if req.Name == "fail" {
+		return replication.Response{}, errors.New("failure")
+	}
+	return replication.Response{Changes: replication.Changes{Sequence: req.Since}}, nil
+}
+
+func TestTCP_ConcurrentCalls(t *testing.T) {
+	_, listener := serveTCP(t, "127.0.0.1:0", echo{delay: 10 * time.Millisecond})
+	transport := replication.NewTCPTransport()
+	defer transport.Close()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(i uint64) {
+			defer wg.Done()
+			resp, err := transport.Call(context.Background(), listener.Addr().String(), replication.Request{Kind: replication.Pull, Name: fmt.Sprint(i), Since: i})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			} else if resp.Changes.Sequence != i {
+				t.Errorf("Mismatched response, got: %d, expected: %d.", resp.Changes.Sequence, i)
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+
+	_, err := transport.Call(context.Background(), listener.Addr().String(), replication.Request{Name: "fail"})
+	if err == nil || err.Error() != "failure" {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, "failure")
+	}
+}
+
+// checks errors reported by the peer can be told apart with errors.Is
+func TestTCP_Errors(t *testing.T) {
+	r := replication.New("n2", replication.NewTCPTransport())
+	_, listener := serveTCP(t, "127.0.0.1:0", r)
+	transport := replication.NewTCPTransport()
+	defer transport.Close()
+
+	_, err := transport.Call(context.Background(), listener.Addr().String(), replication.Request{Kind: replication.Pull, Name: "unknown"})
+	if !errors.Is(err, replication.ErrUnknownReplica) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, replication.ErrUnknownReplica)
+	}
+}
+
+// checks the transport reconnects to a peer that restarted
+func TestTCP_Reconnect(t *testing.T) {
+	server, listener := serveTCP(t, "127.0.0.1:0", echo{})
+	addr := listener.Addr().String()
+	transport := replication.NewTCPTransport()
+	defer transport.Close()
+
+	if _, err := transport.Call(context.Background(), addr, replication.Request{Kind: replication.Pull}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	server.Close()
+	if _, err := transport.Call(context.Background(), addr, replication.Request{Kind: replication.Pull}); err == nil {
+		t.Errorf("Expected an error calling a stopped peer")
+	}
+
+	serveTCP(t, addr, echo{})
+	if _, err := transport.Call(context.Background(), addr, replication.Request{Kind: replication.Pull}); err != nil {
+		t.Errorf("Unexpected error after the peer restarted: %v", err)
+	}
+}
+
+// checks a connection to a peer that stopped answering is closed once the heartbeat timeout expires
+func TestTCP_Heartbeat(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		// accepts connections and never answers
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	transport := replication.NewTCPTransport(replication.WithHeartbeat(10*time.Millisecond, 50*time.Millisecond))
+	defer transport.Close()
+	start := time.Now()
+	_, err = transport.Call(context.Background(), l.Addr().String(), replication.Request{Kind: replication.Pull})
+	if !errors.Is(err, replication.ErrConnectionClosed) {
+		t.Errorf("Unexpected error, got: %v, expected: %v.", err, replication.ErrConnectionClosed)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Dead connection detected too late, after %v", elapsed)
+	}
+}
+
+// checks heartbeats keep an idle connection open
+func TestTCP_IdleConnection(t *testing.T) {
+	opts := []replication.TCPOption{replication.WithHeartbeat(10*time.Millisecond, 50*time.Millisecond)}
+	_, listener := serveTCP(t, "127.0.0.1:0", echo{}, opts...)
+	transport := replication.NewTCPTransport(opts...)
+	defer transport.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := transport.Call(context.Background(), listener.Addr().String(), replication.Request{Kind: replication.Pull}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		time.Sleep(150 * time.Millisecond)
+	}
+	if accepted := atomic.LoadInt32(&listener.accepted); accepted != 1 {
+		t.Errorf("Unexpected number of connections, got: %d, expected: %d.", accepted, 1)
+	}
+}
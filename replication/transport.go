@@ -0,0 +1,165 @@
+// Package replication keeps CRDT replicas in sync with their peers over a pluggable transport
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/merkle"
+)
+
+// Kind tells what a Request asks of the peer receiving it
+type Kind int
+
+const (
+	// Push ships the changes the peer did not acknowledge yet, the peer merges them
+	Push Kind = iota
+	// Pull asks the peer for the changes since the sequence the sender last received from it
+	Pull
+	// Compare asks the peer for the root of its digest, replicas holding the same state skip the exchange
+	Compare
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Push:
+		return "push"
+	case Pull:
+		return "pull"
+	case Compare:
+		return "compare"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Request is sent by a Replicator to one of its peers, about a single named CRDT
+type Request struct {
+	Kind Kind   `json:"kind"`
+	From string `json:"from"`
+	Name string `json:"name"`
+	// Since is the latest sequence the sender received from the peer, for pulls
+	Since uint64 `json:"since,omitempty"`
+	// Changes holds the pushed changes, for pushes
+	Changes Changes `json:"changes"`
+}
+
+// Response is returned by the peer that handled a Request
+type Response struct {
+	// Changes holds the pulled changes for pulls, and only the acknowledged sequence for pushes
+	Changes Changes `json:"changes"`
+	// Root is the root of the digest of the peer for compares, nil if it does not maintain one
+	Root *merkle.Hash `json:"root,omitempty"`
+}
+
+// Handler serves the requests of remote replicators, Replicator implements it
+type Handler interface {
+	Handle(ctx context.Context, req Request) (Response, error)
+}
+
+// Transport carries requests to peers and brings back their responses
+//
+// Peers are identified by addresses whose meaning is up to the transport
+type Transport interface {
+	Call(ctx context.Context, peer string, req Request) (Response, error)
+}
+
+// ErrUnreachable is returned by the in-memory transport for peers that are down or unknown
+var ErrUnreachable = errors.New("peer is unreachable")
+
+// error codes carried by transports along with the messages of the errors they report, so the errors peers
+// return can be told apart without parsing messages
+const (
+	codeUnknownReplica = "unknown-replica"
+	codeStaleState     = "stale-state"
+	codeNoDigest       = "no-digest"
+	codeBadRequest     = "bad-request"
+)
+
+// errorCode returns the code of an error handling a request, the empty string for errors without one
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrUnknownReplica):
+		return codeUnknownReplica
+	case errors.Is(err, crdt.ErrStaleState):
+		return codeStaleState
+	case errors.Is(err, errNoDigest):
+		return codeNoDigest
+	case errors.Is(err, errBadRequest):
+		return codeBadRequest
+	default:
+		return ""
+	}
+}
+
+// errorOf returns the error an error code stands for, nil for unknown codes
+func errorOf(code string) error {
+	switch code {
+	case codeUnknownReplica:
+		return ErrUnknownReplica
+	case codeStaleState:
+		return crdt.ErrStaleState
+	case codeNoDigest:
+		return errNoDigest
+	case codeBadRequest:
+		return errBadRequest
+	default:
+		return nil
+	}
+}
+
+// Network connects replicators living in the same process, mostly for tests
+type Network struct {
+	handlers map[string]Handler
+	down     map[string]bool
+	mutex    sync.RWMutex
+}
+
+// NewNetwork returns an empty in-memory Network
+func NewNetwork() *Network {
+	return &Network{
+		handlers: make(map[string]Handler),
+		down:     make(map[string]bool),
+	}
+}
+
+// Register makes a handler reachable at the given address
+func (n *Network) Register(addr string, h Handler) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.handlers[addr] = h
+}
+
+// SetDown makes an address unreachable, or reachable again, calls from and to it fail while it is down
+func (n *Network) SetDown(addr string, down bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.down[addr] = down
+}
+
+// Transport returns the transport used by the replicator registered at the given address
+func (n *Network) Transport(self string) Transport {
+	return &memoryTransport{network: n, self: self}
+}
+
+type memoryTransport struct {
+	network *Network
+	self    string
+}
+
+func (t *memoryTransport) Call(ctx context.Context, peer string, req Request) (Response, error) {
+	t.network.mutex.RLock()
+	h, ok := t.network.handlers[peer]
+	down := t.network.down[peer] || t.network.down[t.self]
+	t.network.mutex.RUnlock()
+	if !ok || down {
+		return Response{}, fmt.Errorf("cannot call %s: %w", peer, ErrUnreachable)
+	}
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	return h.Handle(ctx, req)
+}
@@ -0,0 +1,376 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// StreamConfig holds the settings of change streams, shared by stream handlers and clients
+type StreamConfig struct {
+	// Interval is the time between two checks for changes to send
+	Interval time.Duration
+	// HeartbeatInterval is the longest time a stream stays quiet, it sends its sequence when there is nothing new
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout is how long a connection may stay silent before it is closed,
+	// it has to be longer than HeartbeatInterval
+	HeartbeatTimeout time.Duration
+	// MinBackoff is how long a client waits before reconnecting after a failed attempt, the wait doubles
+	// with every following failure up to MaxBackoff, and resets once connected
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// CheckOrigin tells whether the handler accepts a connection from the origin of a browser,
+	// only the origin of the handler is accepted when nil
+	CheckOrigin func(*http.Request) bool
+}
+
+// StreamOption configures a stream handler or client
+type StreamOption func(*StreamConfig)
+
+// WithStreamInterval sets the time between two checks for changes to send
+func WithStreamInterval(d time.Duration) StreamOption {
+	return func(cfg *StreamConfig) {
+		cfg.Interval = d
+	}
+}
+
+// WithStreamHeartbeat sets the longest time a stream stays quiet and the silence after which a connection is closed
+func WithStreamHeartbeat(interval, timeout time.Duration) StreamOption {
+	return func(cfg *StreamConfig) {
+		cfg.HeartbeatInterval = interval
+		cfg.HeartbeatTimeout = timeout
+	}
+}
+
+// WithStreamBackoff sets the bounds of the wait before a client reconnects
+func WithStreamBackoff(min, max time.Duration) StreamOption {
+	return func(cfg *StreamConfig) {
+		cfg.MinBackoff = min
+		cfg.MaxBackoff = max
+	}
+}
+
+// WithOriginCheck sets the function deciding which browser origins the handler accepts connections from
+func WithOriginCheck(check func(*http.Request) bool) StreamOption {
+	return func(cfg *StreamConfig) {
+		cfg.CheckOrigin = check
+	}
+}
+
+func newStreamConfig(opts ...StreamOption) StreamConfig {
+	cfg := StreamConfig{
+		Interval:          100 * time.Millisecond,
+		HeartbeatInterval: 10 * time.Second,
+		HeartbeatTimeout:  30 * time.Second,
+		MinBackoff:        100 * time.Millisecond,
+		MaxBackoff:        30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// StreamMessage is what change streams carry in both directions, as JSON text messages
+type StreamMessage struct {
+	// Changes are changes of the sender, the receiver merges them and acknowledges their sequence
+	Changes *Changes `json:"changes,omitempty"`
+	// Ack acknowledges that the sender merged every change of the receiver up to this sequence
+	Ack uint64 `json:"ack,omitempty"`
+	// Error tells the receiver the sender refused the changes it sent last, Code is the code of the error if any,
+	// see errorCode. The changes are sent again later, along with the ones made meanwhile
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// NewStreamHandler returns an http.Handler streaming the changes of the replicas registered with r over WebSocket:
+//
+//	GET /{name}?from={id}&since={n}
+//
+// The replica pushes its changes, local or merged from other peers, to the client as they happen, and merges
+// the changes the client sends. Clients are tracked as peers named after their id, which has to be unique, and
+// resume from since, the sequence of the latest changes they merged, or receive the full state when it is 0
+//
+// The handler is meant to be mounted with http.StripPrefix
+func NewStreamHandler(r *Replicator, opts ...StreamOption) http.Handler {
+	cfg := newStreamConfig(opts...)
+	return &streamHandler{
+		replicator: r,
+		cfg:        cfg,
+		upgrader:   websocket.Upgrader{CheckOrigin: cfg.CheckOrigin},
+	}
+}
+
+type streamHandler struct {
+	replicator *Replicator
+	cfg        StreamConfig
+	upgrader   websocket.Upgrader
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	name := strings.Trim(req.URL.Path, "/")
+	if name == "" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	from, since, err := streamQuery(req.URL.Query())
+	var replica Replica
+	if err == nil {
+		replica, err = h.replicator.replica(name)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// the upgrader already replied with an error
+		return
+	}
+
+	// the client may resume from a sequence older than the one it acknowledged before, if it lost its latest changes
+	replica.Forget(from)
+	if since > 0 {
+		replica.Ack(from, since)
+	}
+	s := &stream{conn: conn, replica: replica, peer: from, cfg: h.cfg}
+	_ = s.run(req.Context())
+}
+
+func streamQuery(query url.Values) (string, uint64, error) {
+	from := query.Get("from")
+	if from == "" {
+		return "", 0, fmt.Errorf("%w: missing from", errBadRequest)
+	}
+	since := uint64(0)
+	if s := query.Get("since"); s != "" {
+		var err error
+		since, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: invalid since: %v", errBadRequest, err)
+		}
+	}
+	return from, since, nil
+}
+
+// stream exchanges the changes of a replica with a peer over a WebSocket connection
+//
+// A single message of changes is in flight at a time, the next one joins every change made meanwhile
+type stream struct {
+	conn     *websocket.Conn
+	replica  Replica
+	peer     string
+	cfg      StreamConfig
+	received func(sequence uint64) // called once changes of the peer were merged
+	inflight uint64                // sequence of the changes sent and not acknowledged yet, 0 if none
+	sentAt   time.Time
+	retryAt  time.Time  // when changes the peer refused are sent again
+	mutex    sync.Mutex // serializes writes, guards inflight, sentAt and retryAt
+}
+
+// run streams changes until the connection fails or the context is done
+func (s *stream) run(ctx context.Context) error {
+	defer s.conn.Close()
+	s.conn.SetReadLimit(maxBodySize)
+	received := make(chan error, 1)
+	go func() { received <- s.receive() }()
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.send(); err != nil {
+			return err
+		}
+		select {
+		case err := <-received:
+			return err
+		case <-ctx.Done():
+			closing := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			_ = s.conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(time.Second))
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// send ships the changes the peer did not acknowledge yet, unless some are already in flight
+//
+// Empty changes are only sent once the stream was quiet for the heartbeat interval, they keep the connection alive
+// and let the peer know the sequence it is up to date with
+func (s *stream) send() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.inflight != 0 || time.Now().Before(s.retryAt) {
+		return nil
+	}
+	changes, err := s.replica.Changes(s.peer)
+	if err != nil {
+		return err
+	}
+	if !changes.Full && len(changes.State) == 0 && time.Since(s.sentAt) < s.cfg.HeartbeatInterval {
+		return nil
+	}
+	if err := s.write(StreamMessage{Changes: &changes}); err != nil {
+		return err
+	}
+	s.inflight = changes.Sequence
+	s.sentAt = time.Now()
+	return nil
+}
+
+// write sends a message, the caller holds the mutex
+func (s *stream) write(msg StreamMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.cfg.HeartbeatTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// receive merges the changes sent by the peer and records its acknowledgements, until the connection fails
+//
+// Changes the replica refuses, such as the ones of a peer left behind by the garbage collection, are reported
+// to the peer rather than closing the connection, it would only open it again and send the same changes
+func (s *stream) receive() error {
+	for {
+		if err := s.conn.SetReadDeadline(time.Now().Add(s.cfg.HeartbeatTimeout)); err != nil {
+			return err
+		}
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var msg StreamMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return err
+		}
+		if msg.Ack > 0 {
+			s.replica.Ack(s.peer, msg.Ack)
+			s.mutex.Lock()
+			if msg.Ack >= s.inflight {
+				s.inflight = 0
+			}
+			s.mutex.Unlock()
+		}
+		if msg.Error != "" {
+			// the refused changes are not acknowledged, they are sent again once the peer had time to catch up
+			s.mutex.Lock()
+			s.inflight = 0
+			s.retryAt = time.Now().Add(s.cfg.HeartbeatInterval)
+			s.mutex.Unlock()
+		}
+		if msg.Changes != nil {
+			reply := StreamMessage{Ack: msg.Changes.Sequence}
+			if err := mergeFrom(s.replica, s.peer, *msg.Changes); err != nil {
+				reply = StreamMessage{Error: err.Error(), Code: errorCode(err)}
+			} else if s.received != nil {
+				s.received(msg.Changes.Sequence)
+			}
+			s.mutex.Lock()
+			err := s.write(reply)
+			s.mutex.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StreamClient keeps a local replica in sync with a replica served by NewStreamHandler, over a WebSocket
+// connection it opens again whenever it is lost
+//
+// Changes made to the local replica while disconnected are sent once the client is connected again
+type StreamClient struct {
+	url     string
+	id      string
+	replica Replica
+	cfg     StreamConfig
+	since   uint64
+	mutex   sync.Mutex
+}
+
+// NewStreamClient returns a client streaming the changes of the replica served at the given ws:// or wss:// URL,
+// the id tells the client apart from the other peers of the server
+func NewStreamClient(url, id string, replica Replica, opts ...StreamOption) *StreamClient {
+	return &StreamClient{url: url, id: id, replica: replica, cfg: newStreamConfig(opts...)}
+}
+
+// Version returns the sequence of the latest changes of the server merged into the local replica,
+// clients persisting their replica store it along, to Resume from it after a restart
+func (c *StreamClient) Version() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.since
+}
+
+// Resume sets the version the next connection resumes from, 0 asks the server for its full state
+func (c *StreamClient) Resume(version uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.since = version
+}
+
+// Run streams changes until the context is done, reconnecting with exponential backoff whenever the connection fails
+func (c *StreamClient) Run(ctx context.Context) error {
+	failures := 0
+	for {
+		connected := c.connect(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			failures = 0
+		}
+		failures++
+
+		wait := c.cfg.MinBackoff
+		for i := 1; i < failures && wait < c.cfg.MaxBackoff; i++ {
+			wait *= 2
+		}
+		if wait > c.cfg.MaxBackoff {
+			wait = c.cfg.MaxBackoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// connect streams changes over a single connection until it fails, it tells whether the connection could be opened
+func (c *StreamClient) connect(ctx context.Context) bool {
+	query := url.Values{"from": {c.id}, "since": {strconv.FormatUint(c.Version(), 10)}}
+	u := c.url
+	if strings.Contains(u, "?") {
+		u += "&" + query.Encode()
+	} else {
+		u += "?" + query.Encode()
+	}
+	dialer := &websocket.Dialer{HandshakeTimeout: c.cfg.HeartbeatTimeout}
+	conn, _, err := dialer.DialContext(ctx, u, nil)
+	if err != nil {
+		return false
+	}
+	// the server is tracked as a single peer, whatever its address
+	s := &stream{conn: conn, replica: c.replica, peer: c.url, cfg: c.cfg, received: c.Resume}
+	_ = s.run(ctx)
+	return true
+}
@@ -0,0 +1,198 @@
+package replication_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bjornaer/crdt"
+	"github.com/bjornaer/crdt/replication"
+)
+
+// streamOptions make streams react fast enough for tests
+var streamOptions = []replication.StreamOption{
+	replication.WithStreamInterval(5 * time.Millisecond),
+	replication.WithStreamBackoff(5*time.Millisecond, 50*time.Millisecond),
+}
+
+// eventually polls a condition until it holds or a few seconds passed
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fullCounter is a replica counting the full states it returns
+type fullCounter struct {
+	replication.Replica
+	full  int
+	mutex sync.Mutex
+}
+
+func (c *fullCounter) Changes(peer string) (replication.Changes, error) {
+	changes, err := c.Replica.Changes(peer)
+	if changes.Full {
+		c.mutex.Lock()
+		c.full++
+		c.mutex.Unlock()
+	}
+	return changes, err
+}
+
+func (c *fullCounter) count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.full
+}
+
+// streamServer serves a set named "items" over WebSocket, and returns the URL to stream it from
+func streamServer(t *testing.T) (crdt.LastWriterWinsSet[string], *fullCounter, *httptest.Server, string) {
+	s := crdt.NewLWWSet[string]()
+	replica := &fullCounter{Replica: replication.Set(s, time.Minute)}
+	r := replication.New("server", replication.NewNetwork().Transport("server"))
+	r.Register("items", replica)
+	server := httptest.NewServer(http.StripPrefix("/stream", replication.NewStreamHandler(r, streamOptions...)))
+	t.Cleanup(server.Close)
+	return s, replica, server, "ws" + strings.TrimPrefix(server.URL, "http") + "/stream/items"
+}
+
+// runClient runs a stream client until the test ends or the returned function is called
+func runClient(t *testing.T, c *replication.StreamClient) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestStream_Live(t *testing.T) {
+	s, _, _, url := streamServer(t)
+	s.Add("a", time.Now())
+	local := crdt.NewLWWSet[string]()
+	runClient(t, replication.NewStreamClient(url, "client", replication.Set(local, time.Minute), streamOptions...))
+
+	eventually(t, "the client to receive the state of the server", func() bool { return local.Exists("a") })
+	s.Add("b", time.Now())
+	s.Remove("a", time.Now())
+	eventually(t, "the client to receive live changes", func() bool { return local.Exists("b") && !local.Exists("a") })
+	local.Add("c", time.Now())
+	eventually(t, "the server to receive the changes of the client", func() bool { return s.Exists("c") })
+}
+
+// checks a restarted client resumes from its version, and local changes made offline are sent once connected
+func TestStream_Resume(t *testing.T) {
+	s, replica, _, url := streamServer(t)
+	s.Add("a", time.Now())
+	local := crdt.NewLWWSet[string]()
+	c := replication.NewStreamClient(url, "client", replication.Set(local, time.Minute), streamOptions...)
+	stop := runClient(t, c)
+	eventually(t, "the client to receive the state of the server", func() bool { return local.Exists("a") })
+	stop()
+
+	s.Add("b", time.Now())
+	local.Add("offline", time.Now())
+	restarted := replication.NewStreamClient(url, "client", replication.Set(local, time.Minute), streamOptions...)
+	restarted.Resume(c.Version())
+	runClient(t, restarted)
+
+	eventually(t, "the client to catch up", func() bool { return local.Exists("b") })
+	eventually(t, "the server to receive offline changes", func() bool { return s.Exists("offline") })
+	if full := replica.count(); full != 1 {
+		t.Errorf("Unexpected number of full states sent, got: %d, expected: %d.", full, 1)
+	}
+	if restarted.Version() <= c.Version() {
+		t.Errorf("Expected the version to move forward, got: %d, previously: %d.", restarted.Version(), c.Version())
+	}
+}
+
+func TestStream_Reconnect(t *testing.T) {
+	s, _, server, url := streamServer(t)
+	local := crdt.NewLWWSet[string]()
+	runClient(t, replication.NewStreamClient(url, "client", replication.Set(local, time.Minute), streamOptions...))
+	s.Add("a", time.Now())
+	eventually(t, "the client to receive changes", func() bool { return local.Exists("a") })
+
+	server.CloseClientConnections()
+	s.Add("b", time.Now())
+	local.Add("c", time.Now())
+	eventually(t, "the client to receive changes after reconnecting", func() bool { return local.Exists("b") })
+	eventually(t, "the server to receive changes after reconnecting", func() bool { return s.Exists("c") })
+}
+
+// checks a client behind the garbage collection horizon of the server keeps its connection, and still receives
+// the changes of the server while its own are refused
+func TestStream_BehindHorizon(t *testing.T) {
+	s := crdt.NewLWWSet[string]()
+	addedAt := time.Now()
+	s.Add("a", addedAt)
+	s.Remove("a", time.Now())
+	if _, err := s.Collect(time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r := replication.New("server", replication.NewNetwork().Transport("server"))
+	r.Register("items", replication.Set(s, time.Minute))
+	var connections int32
+	handler := http.StripPrefix("/stream", replication.NewStreamHandler(r, streamOptions...))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&connections, 1)
+		handler.ServeHTTP(w, req)
+	}))
+	t.Cleanup(server.Close)
+
+	local := crdt.NewLWWSet[string]()
+	local.Add("a", addedAt)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/stream/items"
+	runClient(t, replication.NewStreamClient(url, "client", replication.Set(local, time.Minute), streamOptions...))
+
+	s.Add("b", time.Now())
+	eventually(t, "the client to receive the changes of the server", func() bool { return local.Exists("b") })
+	time.Sleep(50 * time.Millisecond)
+	if s.Exists("a") {
+		t.Errorf("Expected the collected element to stay removed")
+	}
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Errorf("Unexpected number of connections, got: %d, expected: %d.", n, 1)
+	}
+}
+
+func TestStream_Errors(t *testing.T) {
+	_, _, server, _ := streamServer(t)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/stream/items", http.StatusBadRequest},
+		{"/stream/items?from=client&since=x", http.StatusBadRequest},
+		{"/stream/unknown?from=client", http.StatusNotFound},
+		{"/stream/", http.StatusNotFound},
+		// not a WebSocket handshake
+		{"/stream/items?from=client", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		resp, err := http.Get(server.URL + test.path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("Unexpected status for %s, got: %d, expected: %d.", test.path, resp.StatusCode, test.status)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"time"
+)
+
+// Side designates one of the two replicas compared by a diff
+type Side int
+
+// SideA designates the first replica, SideB the second
+const (
+	SideA Side = iota + 1
+	SideB
+)
+
+func (s Side) String() string {
+	switch s {
+	case SideA:
+		return "a"
+	case SideB:
+		return "b"
+	}
+	return "unknown"
+}
+
+// Divergence describes an entry of an element whose timestamps differ between two sets
+type Divergence[T comparable] struct {
+	Element T
+	// Removal tells whether the entry is a removal rather than an addition
+	Removal bool
+	// A and B are the timestamps of the entry in each set, the zero time if the set lacks it
+	A time.Time
+	B time.Time
+	// Winner is the set whose entry a merge keeps, the one with the latest timestamp
+	Winner Side
+}
+
+// Difference describes how two sets diverge
+type Difference[T comparable] struct {
+	// OnlyA holds the elements present in the first set only, OnlyB the ones present in the second set only
+	OnlyA []T
+	OnlyB []T
+	// Entries holds the entries whose timestamps differ, including the ones a single set holds
+	Entries []Divergence[T]
+}
+
+// Equal tells whether both sets hold the same entries
+func (d Difference[T]) Equal() bool {
+	return len(d.OnlyA) == 0 && len(d.OnlyB) == 0 && len(d.Entries) == 0
+}
+
+// GraphDifference describes how two graphs diverge
+type GraphDifference[T comparable] struct {
+	// Vertices describes how the vertices sets diverge
+	Vertices Difference[T]
+	// OnlyA holds the edges present in the first graph only, OnlyB the ones present in the second graph only,
+	// once per edge
+	OnlyA [][2]T
+	OnlyB [][2]T
+	// Edges describes how the edges sets of every vertex diverge, for the vertices whose edges sets do
+	Edges map[T]Difference[T]
+}
+
+// Equal tells whether both graphs hold the same entries
+func (d GraphDifference[T]) Equal() bool {
+	return d.Vertices.Equal() && len(d.OnlyA) == 0 && len(d.OnlyB) == 0 && len(d.Edges) == 0
+}
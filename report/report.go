@@ -0,0 +1,58 @@
+// Package report describes what merges changed in CRDT replicas, and how two replicas diverge
+package report
+
+import (
+	"time"
+)
+
+// Overwrite describes an entry of a set whose timestamp a merge replaced by a later one
+type Overwrite[T comparable] struct {
+	Element T
+	// Removal tells whether the entry is a removal rather than an addition
+	Removal bool
+	// Previous is the timestamp the set held before the merge, Current the one it holds since
+	Previous time.Time
+	Current  time.Time
+}
+
+// Merge describes what a merge changed in a set
+type Merge[T comparable] struct {
+	// Added holds the elements the merge made present, Removed the ones it made absent
+	Added   []T
+	Removed []T
+	// Overwritten holds the entries whose timestamps the merge replaced
+	Overwritten []Overwrite[T]
+	// Additions and Removals count the addition and removal entries taken from the other set, new or overwriting
+	Additions int
+	Removals  int
+}
+
+// Changed tells whether the merge changed the content of the set, rather than its entries only
+func (r Merge[T]) Changed() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0
+}
+
+// GraphMerge describes what a merge changed in a graph
+type GraphMerge[T comparable] struct {
+	// Vertices reports the changes of the vertices set, the vertices made present or absent among them
+	Vertices Merge[T]
+	// AddedEdges holds the edges the merge made present, RemovedEdges the ones it made absent, once per edge
+	AddedEdges   [][2]T
+	RemovedEdges [][2]T
+	// Edges reports the changes of the edges set of every vertex the merge took edge entries for
+	Edges map[T]Merge[T]
+}
+
+// Changed tells whether the merge changed the vertices or edges of the graph, rather than their entries only
+func (r GraphMerge[T]) Changed() bool {
+	return r.Vertices.Changed() || len(r.AddedEdges) > 0 || len(r.RemovedEdges) > 0
+}
+
+// Overwritten counts the vertex and edge entries whose timestamps the merge replaced
+func (r GraphMerge[T]) Overwritten() int {
+	n := len(r.Vertices.Overwritten)
+	for _, edges := range r.Edges {
+		n += len(edges.Overwritten)
+	}
+	return n
+}